@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"syscall"
@@ -19,12 +23,33 @@ import (
 	"order-matching-engine/internal/models"
 
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
+)
+
+// Build information, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=...".
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
 )
 
 // Server wires together DB and matching engine and exposes HTTP handlers.
 type Server struct {
 	db     *sql.DB
 	engine *engine.Engine
+
+	// streamsDone is closed on shutdown to end open event streams, which
+	// would otherwise keep their connections busy until the shutdown timeout.
+	streamsDone chan struct{}
+
+	// accountTokens maps account IDs to the bearer token that authenticates
+	// their account stream. Account streams are disabled when empty.
+	accountTokens map[string]string
+
+	// features lists the optional engine features enabled by the config,
+	// reported by GET /version.
+	features []string
 }
 
 func main() {
@@ -33,7 +58,7 @@ func main() {
 		log.Printf("[INFO] .env not loaded: %v", err)
 	}
 
-	log.Println("[INFO] Starting Order Matching Engine server...")
+	log.Printf("[INFO] Starting Order Matching Engine server %s (commit %s, built %s)...", version, commit, buildTime)
 
 	// Connect to database.
 	database, err := db.Connect()
@@ -46,8 +71,18 @@ func main() {
 	}()
 	log.Println("[INFO] Database connection established")
 
+	// Load optional engine configuration.
+	cfg := engine.DefaultConfig()
+	if path := os.Getenv("ENGINE_CONFIG"); path != "" {
+		cfg, err = engine.LoadConfig(path)
+		if err != nil {
+			log.Fatalf("[ERROR] Failed to load engine config: %v", err)
+		}
+		log.Printf("[INFO] Engine config loaded from %s", path)
+	}
+
 	// Initialize matching engine (prepares statements, etc).
-	matchingEngine, err := engine.NewEngine(database)
+	matchingEngine, err := engine.NewEngine(database, cfg)
 	if err != nil {
 		log.Fatalf("[ERROR] Failed to create matching engine: %v", err)
 	}
@@ -63,23 +98,47 @@ func main() {
 		log.Fatalf("[ERROR] Failed to load open orders: %v", err)
 	}
 
+	accountTokens, err := parseAccountTokens(os.Getenv("ACCOUNT_TOKENS"))
+	if err != nil {
+		log.Fatalf("[ERROR] Invalid ACCOUNT_TOKENS: %v", err)
+	}
+
 	srv := &Server{
-		db:     database,
-		engine: matchingEngine,
+		db:            database,
+		engine:        matchingEngine,
+		streamsDone:   make(chan struct{}),
+		accountTokens: accountTokens,
+		features:      cfg.EnabledFeatures(),
 	}
 
 	// Routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/orders", srv.handleOrders)
 	mux.HandleFunc("/orders/", srv.handleOrderByID)
+	mux.HandleFunc("/orders/status", srv.handleOrderStatuses)
+	mux.HandleFunc("/orders/canceled", srv.handleRecentCancels)
+	mux.HandleFunc("/orders/stale", srv.handleStaleCancel)
+	mux.HandleFunc("/accounts/", srv.handleAccount)
 	mux.HandleFunc("/trades", srv.handleTrades)
 	mux.HandleFunc("/orderbook", srv.handleOrderBook)
+	mux.HandleFunc("/orderbook/simulate", srv.handleSimulate)
+	mux.HandleFunc("/orderbook/delta", srv.handleOrderBookDelta)
+	mux.HandleFunc("/orderbook/liquidity", srv.handleLiquidity)
 	mux.HandleFunc("/health", srv.handleHealth)
+	mux.HandleFunc("/ready", srv.handleReady)
+	mux.HandleFunc("/metrics", srv.handleMetrics)
+	mux.HandleFunc("/version", srv.handleVersion)
+	mux.HandleFunc("/admin/state", srv.handleSymbolState)
+	mux.HandleFunc("/admin/review", srv.handleReview)
+	mux.HandleFunc("/events", srv.handleEvents)
+	mux.HandleFunc("/events/trades/sampled", srv.handleSampledTrades)
+	mux.HandleFunc("/ws/account", srv.handleAccountStream)
 
 	httpServer := &http.Server{
 		Addr:    ":8080",
 		Handler: mux,
 	}
+	httpServer.RegisterOnShutdown(func() { close(srv.streamsDone) })
 
 	// Graceful shutdown setup.
 	stop := make(chan os.Signal, 1)
@@ -111,13 +170,18 @@ func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	places, ok := parseDecimalPlaces(w, r)
+	if !ok {
+		return
+	}
+
 	var req models.CreateOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	if err := validateCreateOrderRequest(&req); err != nil {
+	if err := req.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -125,48 +189,154 @@ func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[INFO] Processing order: symbol=%s, side=%s, type=%s, quantity=%s",
 		req.Symbol, req.Side, req.Type, req.Quantity.String())
 
-	order, trades, err := s.engine.PlaceOrder(&req)
+	placement, err := s.engine.PlaceOrderDetailed(&req)
 	if err != nil {
 		log.Printf("[ERROR] Failed to place order: symbol=%s, error=%v", req.Symbol, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, engine.ErrAutoPaused):
+			http.Error(w, "Order placement temporarily paused", http.StatusServiceUnavailable)
+		case errors.Is(err, engine.ErrCapacityExhausted):
+			http.Error(w, "Order placement paused: database capacity nearly exhausted", http.StatusServiceUnavailable)
+		case errors.Is(err, engine.ErrSymbolUnderReview):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		case errors.Is(err, engine.ErrInvalidTier), errors.Is(err, engine.ErrUnknownSymbol),
+			errors.Is(err, engine.ErrExpired), errors.Is(err, engine.ErrPriceTooFar),
+			errors.Is(err, engine.ErrOutsidePriceCollar), errors.Is(err, engine.ErrPrecisionExceeded),
+			errors.Is(err, engine.ErrInvalidLotSize), errors.Is(err, engine.ErrInvalidQuantityStep),
+			errors.Is(err, engine.ErrNoLiquidity):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	order, trades := placement.Order, placement.Trades
 	log.Printf("[INFO] Order processed: id=%d, status=%s, trades=%d",
 		order.ID, order.Status, len(trades))
 
 	resp := models.CreateOrderResponse{
-		OrderID: order.ID,
-		Status:  string(order.Status),
-		Trades:  trades,
-		Message: "Order processed successfully",
+		OrderID:       order.ID,
+		GlobalOrderID: order.GlobalID,
+		Status:        string(order.Status),
+		Trades:        trades,
+		Message:       "Order processed successfully",
+		BookBefore:    placement.BookBefore,
+		BookAfter:     placement.BookAfter,
+		Trace:         placement.Trace,
+	}
+	if placement.QueuePosition > 0 {
+		resp.QueuePosition = placement.QueuePosition
+		resp.QuantityAhead = &placement.QuantityAhead
 	}
+	writeJSON(w, http.StatusCreated, resp, places)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
+// parseDecimalPlaces reads the optional decimal_places parameter, returning
+// -1 when it is absent. On a bad value it writes a 400 and returns ok=false.
+func parseDecimalPlaces(w http.ResponseWriter, r *http.Request) (places int32, ok bool) {
+	raw := r.URL.Query().Get("decimal_places")
+	if raw == "" {
+		return -1, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 || n > models.MaxDecimalPlaces {
+		http.Error(w, fmt.Sprintf("Invalid decimal_places parameter (must be 0-%d)", models.MaxDecimalPlaces), http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(n), true
 }
 
-// handleOrderByID supports GET /orders/{id} and DELETE /orders/{id}.
-func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// parseInclude reads the optional include parameter, a comma-separated list
+// of names from allowed, into a set. On a bad value it writes a 400 and
+// returns ok=false.
+func parseInclude(w http.ResponseWriter, r *http.Request, allowed ...string) (include map[string]bool, ok bool) {
+	include = map[string]bool{}
+	raw := r.URL.Query().Get("include")
+	if raw == "" {
+		return include, true
+	}
+	for _, name := range strings.Split(raw, ",") {
+		if !slices.Contains(allowed, name) {
+			http.Error(w, fmt.Sprintf("Invalid include parameter (must be a comma-separated list of %s)", strings.Join(allowed, ", ")), http.StatusBadRequest)
+			return nil, false
+		}
+		include[name] = true
+	}
+	return include, true
+}
+
+// writeJSON writes v as a JSON response with the given status. With places
+// from parseDecimalPlaces set, decimals are padded to that many places.
+func writeJSON(w http.ResponseWriter, status int, v any, places int32) {
+	if places < 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
 		return
 	}
 
+	body, err := models.MarshalFixedDecimals(v, places)
+	if err != nil {
+		log.Printf("[ERROR] Failed to encode response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// handleOrderByID supports GET /orders/{id}, DELETE /orders/{id} and the
+// per-order sub-resources under /orders/{id}/.
+func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/orders/")
 	if path == "" {
 		http.Error(w, "Order ID is required", http.StatusBadRequest)
 		return
 	}
 
-	orderID, err := strconv.ParseInt(path, 10, 64)
-	if err != nil {
+	idPart, subresource, _ := strings.Cut(path, "/")
+	orderID, err := s.engine.ParseOrderID(idPart)
+	switch {
+	case errors.Is(err, engine.ErrForeignOrderID):
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, engine.ErrInvalidOrderID):
 		http.Error(w, "Invalid order ID", http.StatusBadRequest)
 		return
+	case err != nil:
+		log.Printf("[ERROR] Failed to resolve order ID %q: %v", idPart, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch subresource {
+	case "":
+	case "queue-history":
+		s.handleQueueHistory(w, r, orderID)
+		return
+	case "cancelable":
+		s.handleCancelable(w, r, orderID)
+		return
+	case "eta":
+		s.handleETA(w, r, orderID)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
 	if r.Method == http.MethodGet {
+		places, ok := parseDecimalPlaces(w, r)
+		if !ok {
+			return
+		}
 		order, err := s.engine.GetOrder(orderID)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
@@ -177,17 +347,28 @@ func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
 			}
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(order)
+		writeJSON(w, http.StatusOK, order, places)
 		return
 	}
 
 	// DELETE: cancel order
+	expect := engine.CancelExpectation{
+		Symbol: r.URL.Query().Get("symbol"),
+		Side:   models.OrderSide(r.URL.Query().Get("side")),
+	}
+	if expect.Side != "" && expect.Side != models.OrderSideBuy && expect.Side != models.OrderSideSell {
+		http.Error(w, "Invalid side parameter (must be buy or sell)", http.StatusBadRequest)
+		return
+	}
 	log.Printf("[INFO] Canceling order: id=%d", orderID)
-	order, err := s.engine.CancelOrder(orderID)
+	order, err := s.engine.CancelOrderExpecting(orderID, expect)
 	if err != nil {
 		log.Printf("[ERROR] Failed to cancel order: id=%d, error=%v", orderID, err)
 		switch {
+		case errors.Is(err, engine.ErrCancelSymbolRequired):
+			http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+		case errors.Is(err, engine.ErrCancelMismatch):
+			http.Error(w, err.Error(), http.StatusConflict)
 		case strings.Contains(err.Error(), "not found"):
 			http.Error(w, "Order not found", http.StatusNotFound)
 		case strings.Contains(err.Error(), "already filled"):
@@ -210,12 +391,182 @@ func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
 		"status":   string(order.Status),
 		"message":  "Order canceled successfully",
 	}
+	if order.GlobalID != "" {
+		resp["global_order_id"] = order.GlobalID
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(resp)
 }
 
-// handleTrades returns recent trades for a symbol: GET /trades?symbol=...&limit=N
+// handleQueueHistory returns an order's queue position timeline: GET /orders/{id}/queue-history
+func (s *Server) handleQueueHistory(w http.ResponseWriter, r *http.Request, orderID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshots, err := s.engine.GetQueuePositionHistory(orderID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "Order not found", http.StatusNotFound)
+		case strings.Contains(err.Error(), "not a limit order"):
+			http.Error(w, "Order is not a limit order", http.StatusBadRequest)
+		default:
+			log.Printf("[ERROR] Failed to get queue history for order %d: %v", orderID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.QueueHistoryResponse{OrderID: orderID, Snapshots: snapshots}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleAccount serves the per-account sub-resources under /accounts/{id}/.
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, subresource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/accounts/"), "/")
+	if accountID == "" {
+		http.Error(w, "Account ID is required", http.StatusBadRequest)
+		return
+	}
+
+	switch subresource {
+	case "position":
+		s.handlePosition(w, r, accountID)
+	case "stp-stats":
+		s.handleSelfTradeStats(w, r, accountID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handlePosition returns an account's net position: GET /accounts/{id}/position?symbol=...
+func (s *Server) handlePosition(w http.ResponseWriter, r *http.Request, accountID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	position, err := s.engine.GetPosition(accountID, symbol)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get position for account %s: %v", accountID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.PositionResponse{AccountID: accountID, Symbol: symbol, Position: position}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleSelfTradeStats returns an account's self-trade prevention cancel
+// counts: GET /accounts/{id}/stp-stats
+func (s *Server) handleSelfTradeStats(w http.ResponseWriter, r *http.Request, accountID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.engine.SelfTradeStats(accountID))
+}
+
+// handleCancelable reports whether an order can be canceled: GET /orders/{id}/cancelable
+func (s *Server) handleCancelable(w http.ResponseWriter, r *http.Request, orderID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cancelable, reason, err := s.engine.IsCancelable(orderID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Order not found", http.StatusNotFound)
+		} else {
+			log.Printf("[ERROR] Failed to check cancelability of order %d: %v", orderID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := models.CancelableResponse{OrderID: orderID, Cancelable: cancelable, Reason: reason}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleETA estimates a resting order's time to fill: GET /orders/{id}/eta
+func (s *Server) handleETA(w http.ResponseWriter, r *http.Request, orderID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	estimate, err := s.engine.EstimateTimeToFill(orderID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, "Order not found", http.StatusNotFound)
+		case strings.Contains(err.Error(), "not resting"):
+			http.Error(w, "Order is not resting on the book", http.StatusConflict)
+		default:
+			log.Printf("[ERROR] Failed to estimate time to fill for order %d: %v", orderID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estimate)
+}
+
+// handleOrderStatuses accepts POST /orders/status to look up many orders at once.
+func (s *Server) handleOrderStatuses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.OrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(req.OrderIDs) == 0 {
+		http.Error(w, "order_ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.OrderIDs) > engine.MaxOrderStatusIDs {
+		http.Error(w, fmt.Sprintf("too many order_ids (max %d)", engine.MaxOrderStatusIDs), http.StatusBadRequest)
+		return
+	}
+
+	found, notFound, err := s.engine.GetOrderStatuses(req.OrderIDs)
+	if errors.Is(err, engine.ErrInvalidOrderID) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to get order statuses: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.OrderStatusResponse{Orders: found, NotFound: notFound}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleTrades returns recent trades for a symbol:
+// GET /trades?symbol=...&limit=N&min_quantity=Q[&include=client_ids,meta]
 func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -238,19 +589,151 @@ func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	trades, err := s.engine.GetTrades(symbol, limit)
+	scale, baseUnits, ok := s.parseAmounts(w, r, symbol)
+	if !ok {
+		return
+	}
+	places, ok := parseDecimalPlaces(w, r)
+	if !ok {
+		return
+	}
+
+	filter := engine.TradeFilter{Symbol: symbol, Limit: limit}
+	if minQtyStr := r.URL.Query().Get("min_quantity"); minQtyStr != "" {
+		minQty, err := decimal.NewFromString(minQtyStr)
+		if err != nil || !minQty.IsPositive() {
+			http.Error(w, "Invalid min_quantity parameter (must be a positive decimal)", http.StatusBadRequest)
+			return
+		}
+		filter.MinQuantity = &minQty
+	}
+	include, ok := parseInclude(w, r, "client_ids", "meta")
+	if !ok {
+		return
+	}
+	filter.IncludeClientIDs = include["client_ids"]
+
+	trades, err := s.engine.QueryTrades(filter)
 	if err != nil {
 		log.Printf("[ERROR] Failed to get trades for symbol %s: %v", symbol, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	response := models.TradeResponse{Trades: trades}
+	var meta *models.SymbolMeta
+	if include["meta"] {
+		m := s.engine.SymbolMeta(symbol)
+		meta = &m
+	}
+
+	if baseUnits {
+		converted, err := models.TradesInBaseUnits(trades, scale)
+		if err != nil {
+			log.Printf("[ERROR] Failed to convert trades for symbol %s to base units: %v", symbol, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.BaseUnitTradeResponse{Scale: scale, Trades: converted, Meta: meta})
+		return
+	}
+	response := models.TradeResponse{Trades: trades, Meta: meta}
+	writeJSON(w, http.StatusOK, response, places)
+}
+
+// parseAmounts reads the optional amounts parameter. With
+// amounts=base_units it returns symbol's base unit scale and true; the
+// default, amounts=decimal, returns false. On a bad value it writes a 400
+// and returns ok=false.
+func (s *Server) parseAmounts(w http.ResponseWriter, r *http.Request, symbol string) (scale models.BaseUnitScale, baseUnits, ok bool) {
+	switch r.URL.Query().Get("amounts") {
+	case "", "decimal":
+		return scale, false, true
+	case models.AmountsBaseUnits:
+		var err error
+		scale, err = s.engine.BaseUnitScale(symbol)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return scale, false, false
+		}
+		return scale, true, true
+	default:
+		http.Error(w, "Invalid amounts parameter (must be decimal or base_units)", http.StatusBadRequest)
+		return scale, false, false
+	}
+}
+
+// handleRecentCancels returns recently canceled orders:
+// GET /orders/canceled?symbol=...&limit=N
+func (s *Server) handleRecentCancels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > engine.MaxRecentCancels {
+			http.Error(w, fmt.Sprintf("Invalid limit parameter (must be 1-%d)", engine.MaxRecentCancels), http.StatusBadRequest)
+			return
+		}
+	}
+
+	places, ok := parseDecimalPlaces(w, r)
+	if !ok {
+		return
+	}
+
+	orders, err := s.engine.GetRecentCancels(symbol, limit)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get canceled orders for symbol %s: %v", symbol, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.CanceledOrdersResponse{Symbol: symbol, Orders: orders}
+	writeJSON(w, http.StatusOK, response, places)
+}
+
+// handleStaleCancel cancels resting orders created before a cutoff on one
+// symbol, or all symbols when omitted: DELETE /orders/stale?symbol=...&before=RFC3339
+func (s *Server) handleStaleCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	before, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("before"))
+	if err != nil {
+		http.Error(w, "before parameter is required (RFC3339 timestamp)", http.StatusBadRequest)
+		return
+	}
+	symbol := r.URL.Query().Get("symbol")
+
+	log.Printf("[INFO] Canceling orders created before %s: symbol=%q", before.Format(time.RFC3339Nano), symbol)
+	canceled, err := s.engine.CancelOrdersBefore(symbol, before)
+	if err != nil {
+		log.Printf("[ERROR] Stale cancel stopped after %d orders: %v", canceled, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[INFO] Canceled %d stale orders", canceled)
+
+	response := models.StaleCancelResponse{Symbol: symbol, Before: before, Canceled: canceled}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleOrderBook returns aggregated top N levels: GET /orderbook?symbol=...&depth=N
+// handleOrderBook returns aggregated top N levels:
+// GET /orderbook?symbol=...&depth=N[&include_counts=true][&include_age=true][&include=meta]
 func (s *Server) handleOrderBook(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -273,17 +756,209 @@ func (s *Server) handleOrderBook(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	bids, asks := s.engine.GetOrderBookWithQuantities(symbol, depth)
+	scale, baseUnits, ok := s.parseAmounts(w, r, symbol)
+	if !ok {
+		return
+	}
+	places, ok := parseDecimalPlaces(w, r)
+	if !ok {
+		return
+	}
+
+	var detail engine.LevelDetail
+	if countsStr := r.URL.Query().Get("include_counts"); countsStr != "" {
+		var err error
+		detail.Counts, err = strconv.ParseBool(countsStr)
+		if err != nil {
+			http.Error(w, "Invalid include_counts parameter (must be true or false)", http.StatusBadRequest)
+			return
+		}
+	}
+	if ageStr := r.URL.Query().Get("include_age"); ageStr != "" {
+		includeAge, err := strconv.ParseBool(ageStr)
+		if err != nil {
+			http.Error(w, "Invalid include_age parameter (must be true or false)", http.StatusBadRequest)
+			return
+		}
+		if includeAge {
+			detail.OldestAgeAt = time.Now()
+		}
+	}
+	include, ok := parseInclude(w, r, "meta")
+	if !ok {
+		return
+	}
+	var meta *models.SymbolMeta
+	if include["meta"] {
+		m := s.engine.SymbolMeta(symbol)
+		meta = &m
+	}
+
+	bids, asks := s.engine.GetOrderBookDetailed(symbol, depth, detail)
+
+	if baseUnits {
+		response, err := orderBookInBaseUnits(symbol, scale, bids, asks)
+		if err != nil {
+			log.Printf("[ERROR] Failed to convert order book for symbol %s to base units: %v", symbol, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		response.Meta = meta
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 	response := models.OrderBookResponse{
 		Symbol: symbol,
 		Bids:   bids,
 		Asks:   asks,
+		Meta:   meta,
+	}
+	writeJSON(w, http.StatusOK, response, places)
+}
+
+// handleOrderBookDelta returns the book changes after a sequence number, or a
+// full snapshot when they are not retained:
+// GET /orderbook/delta?symbol=...[&since=N]
+func (s *Server) handleOrderBookDelta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	var since uint64
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		var err error
+		since, err = strconv.ParseUint(sinceStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid since parameter (must be a sequence number)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	response, err := s.engine.GetBookDelta(symbol, since)
+	if errors.Is(err, engine.ErrBookDeltasDisabled) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to get order book delta for symbol %s: %v", symbol, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleLiquidity returns a symbol's liquidity score:
+// GET /orderbook/liquidity?symbol=...
+func (s *Server) handleLiquidity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.engine.GetLiquidityScore(symbol))
+}
+
+// orderBookInBaseUnits builds an order book response with amounts in base units.
+func orderBookInBaseUnits(symbol string, scale models.BaseUnitScale, bids, asks []models.OrderBookLevel) (*models.BaseUnitOrderBookResponse, error) {
+	b, err := models.LevelsInBaseUnits(bids, scale)
+	if err != nil {
+		return nil, err
+	}
+	a, err := models.LevelsInBaseUnits(asks, scale)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BaseUnitOrderBookResponse{Symbol: symbol, Scale: scale, Bids: b, Asks: a}, nil
+}
+
+// handleSimulate applies a what-if sequence of operations to a copy of the book:
+// POST /orderbook/simulate?depth=N
+func (s *Server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	depth := 10
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		var err error
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil || depth < 1 || depth > 100 {
+			http.Error(w, "Invalid depth parameter (must be 1-100)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var req models.SimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > engine.MaxSimulationOperations {
+		http.Error(w, fmt.Sprintf("too many operations (max %d)", engine.MaxSimulationOperations), http.StatusBadRequest)
+		return
+	}
+
+	response, err := s.engine.SimulateOperations(req.Symbol, req.Operations)
+	if err != nil {
+		if errors.Is(err, engine.ErrUnknownSymbol) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("[ERROR] Failed to simulate operations for symbol %s: %v", req.Symbol, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(response.Bids) > depth {
+		response.Bids = response.Bids[:depth]
+	}
+	if len(response.Asks) > depth {
+		response.Asks = response.Asks[:depth]
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleVersion reports the build and the optional features enabled.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := models.VersionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Features:  s.features,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleHealth is a simple health check that verifies DB connectivity.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -300,24 +975,257 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-// validateCreateOrderRequest performs basic request validation for creating orders.
-func validateCreateOrderRequest(req *models.CreateOrderRequest) error {
-	if req.Symbol == "" {
-		return fmt.Errorf("symbol is required")
+// handleReady reports whether the service should receive order traffic: the DB
+// must be reachable and order placement must not be auto-paused.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	if req.Side != models.OrderSideBuy && req.Side != models.OrderSideSell {
-		return fmt.Errorf("side must be 'buy' or 'sell'")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.db.Ping(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "unavailable", "auto_paused": s.engine.IsAutoPaused()})
+		return
 	}
-	if req.Type != models.OrderTypeLimit && req.Type != models.OrderTypeMarket {
-		return fmt.Errorf("type must be 'limit' or 'market'")
+	if s.engine.IsAutoPaused() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "paused", "auto_paused": true})
+		return
 	}
-	if req.Quantity.IsZero() || req.Quantity.IsNegative() {
-		return fmt.Errorf("quantity must be positive")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ready", "auto_paused": false})
+}
+
+// handleMetrics exposes engine metrics in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.engine.WriteMetrics(w); err != nil {
+		log.Printf("[ERROR] Failed to write metrics: %v", err)
+	}
+}
+
+// handleSymbolState exports a symbol's state bundle with
+// GET /admin/state?symbol=... and imports one with POST /admin/state.
+func (s *Server) handleSymbolState(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		state, err := s.engine.ExportSymbolState(symbol)
+		if err != nil {
+			log.Printf("[ERROR] Failed to export state for symbol %s: %v", symbol, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("[INFO] Exported state: symbol=%s, open_orders=%d, trades=%d",
+			symbol, len(state.OpenOrders), len(state.RecentTrades))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(state)
+
+	case http.MethodPost:
+		var state engine.SymbolState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+
+		imported, err := s.engine.ImportSymbolState(&state)
+		if err != nil {
+			log.Printf("[ERROR] Failed to import state for symbol %s: %v", state.Symbol, err)
+			switch {
+			case errors.Is(err, engine.ErrInvalidSymbolState), errors.Is(err, engine.ErrUnknownSymbol):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, engine.ErrSymbolNotEmpty), errors.Is(err, engine.ErrOrderIDConflict):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		log.Printf("[INFO] Imported state: symbol=%s, open_orders=%d", state.Symbol, imported)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.ImportStateResponse{Symbol: state.Symbol, ImportedOrders: imported})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
-	if req.Type == models.OrderTypeLimit {
-		if req.Price == nil || req.Price.IsZero() || req.Price.IsNegative() {
-			return fmt.Errorf("price is required for limit orders and must be positive")
+}
+
+// handleReview lists the symbols halted because their recovered book was
+// crossed with GET /admin/review, and resumes one with
+// DELETE /admin/review?symbol=...
+func (s *Server) handleReview(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.ReviewResponse{Symbols: s.engine.SymbolsUnderReview()})
+
+	case http.MethodDelete:
+		symbol := r.URL.Query().Get("symbol")
+		if symbol == "" {
+			http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+			return
+		}
+		if err := s.engine.ClearReview(symbol); err != nil {
+			switch {
+			case errors.Is(err, engine.ErrNotUnderReview):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, engine.ErrBookCrossed):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleEvents streams engine events as Server-Sent Events on GET /events.
+// Optional query parameters: symbol restricts to one symbol and types takes a
+// comma-separated list of event types (default: all).
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	var types map[models.EventType]bool
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		types = make(map[models.EventType]bool)
+		for _, t := range strings.Split(raw, ",") {
+			types[models.EventType(strings.TrimSpace(t))] = true
+		}
+	}
+
+	sub := s.engine.Events().Subscribe(0, func(ev models.Event) bool {
+		if symbol != "" && ev.Symbol != symbol {
+			return false
+		}
+		return types == nil || types[ev.Type]
+	})
+	log.Printf("[INFO] Event stream opened: symbol=%q, types=%q", symbol, r.URL.Query().Get("types"))
+	s.streamEvents(w, r, sub)
+}
+
+// handleSampledTrades streams roughly one in trade_sample_every trades per
+// symbol on GET /events/trades/sampled, optionally restricted to symbol.
+func (s *Server) handleSampledTrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	sub := s.engine.SubscribeSampledTrades(symbol)
+	log.Printf("[INFO] Sampled trade stream opened: symbol=%q", symbol)
+	s.streamEvents(w, r, sub)
+}
+
+// handleAccountStream streams every order event of one account (placements,
+// fills and cancels across all symbols) as Server-Sent Events on
+// GET /ws/account?account_id=... The request must carry the account's token
+// as "Authorization: Bearer <token>".
+func (s *Server) handleAccountStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(s.accountTokens) == 0 {
+		http.Error(w, "Account streams are not configured", http.StatusNotFound)
+		return
+	}
+
+	accountID := r.URL.Query().Get("account_id")
+	if accountID == "" {
+		http.Error(w, "account_id is required", http.StatusBadRequest)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	want, known := s.accountTokens[accountID]
+	if !ok || !known || subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+		log.Printf("[WARN] Rejected account stream for account %q", accountID)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sub := s.engine.SubscribeAccount(accountID)
+	log.Printf("[INFO] Account stream opened: account=%q", accountID)
+	s.streamEvents(w, r, sub)
+}
+
+// parseAccountTokens parses ACCOUNT_TOKENS, a comma-separated list of
+// account_id:token pairs.
+func parseAccountTokens(raw string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for i, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		account, token, ok := strings.Cut(pair, ":")
+		if !ok || account == "" || token == "" {
+			// The entry is not echoed: it may hold a token.
+			return nil, fmt.Errorf("entry %d: expected account_id:token", i+1)
+		}
+		tokens[account] = token
+	}
+	return tokens, nil
+}
+
+// streamEvents writes events from sub as Server-Sent Events until the client
+// disconnects or the server shuts down, then closes sub.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, sub *engine.Subscription) {
+	defer func() {
+		sub.Close()
+		if dropped := sub.Dropped(); dropped > 0 {
+			log.Printf("[WARN] Event stream closed after dropping %d events for a slow client", dropped)
+		}
+	}()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case ev := <-sub.C:
+			data, err := json.Marshal(ev)
+			if err != nil {
+				log.Printf("[ERROR] Failed to encode event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.streamsDone:
+			return
 		}
 	}
-	return nil
 }
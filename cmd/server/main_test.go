@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"order-matching-engine/internal/engine"
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHandleVersion checks GET /version reports the stamped build
+// information and the features enabled by the engine config.
+func TestHandleVersion(t *testing.T) {
+	defer func(v, c, b string) { version, commit, buildTime = v, c, b }(version, commit, buildTime)
+	version, commit, buildTime = "v1.2.3", "abc1234", "2024-01-01T00:00:00Z"
+
+	cfg := engine.DefaultConfig()
+	cfg.PreCommitFillEvents = true
+	cfg.IdleBookTTL = engine.Duration{Duration: 1}
+	srv := &Server{features: cfg.EnabledFeatures()}
+
+	rec := httptest.NewRecorder()
+	srv.handleVersion(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp models.VersionResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, models.VersionResponse{
+		Version:   "v1.2.3",
+		Commit:    "abc1234",
+		BuildTime: "2024-01-01T00:00:00Z",
+		GoVersion: runtime.Version(),
+		Features:  []string{"precommit_fill_events", "idle_book_reaper"},
+	}, resp)
+
+	rec = httptest.NewRecorder()
+	srv.handleVersion(rec, httptest.NewRequest(http.MethodPost, "/version", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
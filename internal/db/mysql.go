@@ -74,13 +74,55 @@ func convertURIToDSN(connectionString string) (string, error) {
 	return dsn, nil
 }
 
+// SecretProvider returns the database connection string from an external
+// secret store
+type SecretProvider func() (string, error)
+
+// DSNProvider, when set, supplies the connection string if DB_DSN_FILE is
+// unset. It takes precedence over DB_DSN. Set it before calling Connect
+var DSNProvider SecretProvider
+
+// resolveDSN returns the connection string, looking in order at the file
+// named by DB_DSN_FILE, DSNProvider and the DB_DSN environment variable
+func resolveDSN() (string, error) {
+	if path := os.Getenv("DB_DSN_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read DB_DSN_FILE: %w", err)
+		}
+		connectionString := strings.TrimSpace(string(content))
+		if connectionString == "" {
+			return "", fmt.Errorf("DB_DSN_FILE %s is empty", path)
+		}
+		return connectionString, nil
+	}
+
+	if DSNProvider != nil {
+		connectionString, err := DSNProvider()
+		if err != nil {
+			return "", fmt.Errorf("secret provider failed: %w", err)
+		}
+		connectionString = strings.TrimSpace(connectionString)
+		if connectionString == "" {
+			return "", fmt.Errorf("secret provider returned an empty connection string")
+		}
+		return connectionString, nil
+	}
+
+	connectionString := os.Getenv("DB_DSN")
+	if connectionString == "" {
+		return "", fmt.Errorf("DB_DSN or DB_DSN_FILE environment variable is required")
+	}
+	return connectionString, nil
+}
+
 // Connect establishes a connection to the MySQL/TiDB database
-// using the DB_DSN environment variable
+// using the connection string from resolveDSN
 // Supports both traditional DSN format and TiDB Cloud URI format
 func Connect() (*sql.DB, error) {
-	connectionString := os.Getenv("DB_DSN")
-	if connectionString == "" {
-		return nil, fmt.Errorf("DB_DSN environment variable is required")
+	connectionString, err := resolveDSN()
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert URI to DSN if needed
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"time"
+
+	"order-matching-engine/internal/models"
+)
+
+// SubscribeAccount subscribes to the order events of accountID: its
+// placements, the fills of its orders on either side of a trade, and its
+// cancels, across all symbols. Callers must authenticate the account first.
+func (e *Engine) SubscribeAccount(accountID string) *Subscription {
+	return e.events.SubscribeAccount(accountID, 0)
+}
+
+// publishAccountPlacement publishes the account events of a committed
+// placement: order_placed for order, then order_filled for each side of each
+// trade whose order belongs to an account. Resting orders canceled by
+// self-trade prevention get order_canceled. Orders are taken as they stood
+// after the placement.
+func (e *Engine) publishAccountPlacement(order *models.Order, result *MatchResult) {
+	now := time.Now()
+	if order.AccountID != "" && e.events.HasAccountSubscribers(order.AccountID) {
+		placed := *order
+		e.events.PublishAccount(order.AccountID, models.Event{
+			Type:      models.EventOrderPlaced,
+			Symbol:    order.Symbol,
+			AccountID: order.AccountID,
+			Order:     &placed,
+			Timestamp: now,
+		})
+	}
+	for _, resting := range result.SelfTradeCanceled {
+		e.publishAccountCancel(resting)
+	}
+	if len(result.Trades) == 0 {
+		return
+	}
+
+	byID := make(map[int64]*models.Order, len(result.UpdatedOrders)+1)
+	for _, u := range result.UpdatedOrders {
+		byID[u.ID] = u
+	}
+	byID[order.ID] = order
+	for i := range result.Trades {
+		trade := result.Trades[i]
+		for _, id := range [2]int64{trade.BuyOrderID, trade.SellOrderID} {
+			o := byID[id]
+			if o == nil || o.AccountID == "" || !e.events.HasAccountSubscribers(o.AccountID) {
+				continue
+			}
+			filled := *o
+			e.events.PublishAccount(o.AccountID, models.Event{
+				Type:      models.EventOrderFilled,
+				Symbol:    trade.Symbol,
+				AccountID: o.AccountID,
+				Trade:     &trade,
+				Order:     &filled,
+				Timestamp: now,
+			})
+		}
+	}
+}
+
+// publishAccountCancel publishes order_canceled for a committed cancel of an
+// account's order.
+func (e *Engine) publishAccountCancel(order *models.Order) {
+	if order.AccountID == "" || !e.events.HasAccountSubscribers(order.AccountID) {
+		return
+	}
+	canceled := *order
+	e.events.PublishAccount(order.AccountID, models.Event{
+		Type:      models.EventOrderCanceled,
+		Symbol:    order.Symbol,
+		AccountID: order.AccountID,
+		Order:     &canceled,
+		Timestamp: time.Now(),
+	})
+}
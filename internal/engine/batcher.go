@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"order-matching-engine/internal/models"
+)
+
+// orderBatcher collects orders for one symbol over a batch window and
+// matches them together.
+type orderBatcher struct {
+	engine *Engine
+	symbol string
+	window time.Duration
+
+	mutex   sync.Mutex
+	pending []*batchedOrder // arrival order
+}
+
+// batchedOrder is a queued placement and the channel its result goes to.
+type batchedOrder struct {
+	req       *models.CreateOrderRequest
+	bookDepth int
+	done      chan batchResult
+}
+
+type batchResult struct {
+	placement *Placement
+	err       error
+}
+
+// getBatcher returns the batcher for symbol, creating it if necessary.
+func (e *Engine) getBatcher(symbol string, window time.Duration) *orderBatcher {
+	e.batcherMutex.Lock()
+	defer e.batcherMutex.Unlock()
+
+	b, ok := e.batchers[symbol]
+	if !ok {
+		b = &orderBatcher{engine: e, symbol: symbol, window: window}
+		e.batchers[symbol] = b
+	}
+	return b
+}
+
+// submit queues req and waits for its batch to be matched. The first order
+// of a batch starts the window timer.
+func (b *orderBatcher) submit(req *models.CreateOrderRequest, bookDepth int) (*Placement, error) {
+	item := &batchedOrder{req: req, bookDepth: bookDepth, done: make(chan batchResult, 1)}
+
+	b.mutex.Lock()
+	b.pending = append(b.pending, item)
+	if len(b.pending) == 1 {
+		time.AfterFunc(b.window, b.flush)
+	}
+	b.mutex.Unlock()
+
+	result := <-item.done
+	return result.placement, result.err
+}
+
+// flush matches every queued order in arrival order under one hold of the
+// symbol lock. Each order still gets its own transaction, so one failure
+// does not affect the others in the batch. flush runs on a timer goroutine,
+// so a panic is turned into that order's error rather than crashing the
+// process and stranding the rest of the batch.
+func (b *orderBatcher) flush() {
+	b.mutex.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+
+	symbolMutex := b.engine.getSymbolMutex(b.symbol)
+	symbolMutex.Lock()
+	defer symbolMutex.Unlock()
+
+	for _, item := range batch {
+		item.done <- b.place(item)
+	}
+}
+
+// place runs one queued order. Caller holds the symbol lock.
+func (b *orderBatcher) place(item *batchedOrder) (result batchResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] Batched order placement panicked: symbol=%s, panic=%v", b.symbol, r)
+			result = batchResult{err: fmt.Errorf("order placement failed: %v", r)}
+		}
+	}()
+	placement, err := b.engine.placeLocked(item.req, item.bookDepth)
+	return batchResult{placement: placement, err: err}
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_BatchWindow queues a burst of orders into one batch and checks
+// they are matched in arrival order with the same fills as immediate matching.
+func TestEngine_BatchWindow(t *testing.T) {
+	reqs := []*models.CreateOrderRequest{
+		limitRequest("BTCUSD", models.OrderSideSell, 50100, 1),
+		limitRequest("BTCUSD", models.OrderSideSell, 50000, 1),
+		marketRequest("BTCUSD", models.OrderSideBuy, 1.5),
+		limitRequest("BTCUSD", models.OrderSideBuy, 50100, 1),
+	}
+
+	// Reference: the same sequence matched immediately.
+	immediate, _ := newFakeEngine(t, DefaultConfig())
+	var want []models.Trade
+	for _, req := range reqs {
+		_, trades, err := immediate.PlaceOrder(req)
+		require.NoError(t, err)
+		want = append(want, trades...)
+	}
+	require.Len(t, want, 3)
+
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{"BTCUSD": {BatchWindow: Duration{50 * time.Millisecond}}}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	type outcome struct {
+		trades []models.Trade
+		err    error
+	}
+	results := make([]chan outcome, len(reqs))
+	batcher := eng.getBatcher("BTCUSD", cfg.Symbols["BTCUSD"].BatchWindow.Duration)
+	for i, req := range reqs {
+		results[i] = make(chan outcome, 1)
+		go func(req *models.CreateOrderRequest, out chan outcome) {
+			_, trades, err := eng.PlaceOrder(req)
+			out <- outcome{trades, err}
+		}(req, results[i])
+		// Wait for the order to be queued so arrival order is fixed.
+		require.Eventually(t, func() bool {
+			batcher.mutex.Lock()
+			defer batcher.mutex.Unlock()
+			return len(batcher.pending) == i+1
+		}, time.Second, time.Millisecond)
+	}
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO orders"), "nothing runs before the window closes")
+
+	var got []models.Trade
+	for _, out := range results {
+		o := <-out
+		require.NoError(t, o.err)
+		got = append(got, o.trades...)
+	}
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].BuyOrderID, got[i].BuyOrderID)
+		assert.Equal(t, want[i].SellOrderID, got[i].SellOrderID)
+		assert.True(t, want[i].Price.Equal(got[i].Price))
+		assert.True(t, want[i].Quantity.Equal(got[i].Quantity))
+	}
+
+	bids, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Empty(t, asks)
+	require.Len(t, bids, 1)
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(bids[0].Quantity))
+}
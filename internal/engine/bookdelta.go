@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"math"
+	"sync"
+
+	"order-matching-engine/internal/models"
+)
+
+// bookDeltaLog numbers the changes to one symbol's book and keeps the most
+// recent ones.
+type bookDeltaLog struct {
+	mu  sync.Mutex
+	seq uint64
+	// deltas holds the last deltas in sequence order, at most limit of them.
+	deltas []models.BookDelta
+	limit  int
+}
+
+// append records changes as the next sequence number.
+func (l *bookDeltaLog) append(changes []models.BookLevelChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	if len(l.deltas) == l.limit {
+		copy(l.deltas, l.deltas[1:])
+		l.deltas = l.deltas[:l.limit-1]
+	}
+	l.deltas = append(l.deltas, models.BookDelta{Sequence: l.seq, Changes: changes})
+}
+
+// since returns the current sequence and the deltas after since, or ok false
+// when some of them are no longer retained.
+func (l *bookDeltaLog) since(since uint64) (seq uint64, deltas []models.BookDelta, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if since == 0 || since > l.seq {
+		return l.seq, nil, false
+	}
+	missing := l.seq - since
+	if missing > uint64(len(l.deltas)) {
+		return l.seq, nil, false
+	}
+	deltas = make([]models.BookDelta, missing)
+	copy(deltas, l.deltas[uint64(len(l.deltas))-missing:])
+	return l.seq, deltas, true
+}
+
+// bookDeltaLog returns symbol's delta log, creating it.
+func (e *Engine) bookDeltaLog(symbol string) *bookDeltaLog {
+	e.bookDeltaMutex.Lock()
+	defer e.bookDeltaMutex.Unlock()
+
+	l := e.bookDeltas[symbol]
+	if l == nil {
+		l = &bookDeltaLog{limit: e.config.BookDeltaHistory}
+		e.bookDeltas[symbol] = l
+	}
+	return l
+}
+
+// recordBookChange records the price levels of orders as one delta of
+// symbol's book, each with the quantity now resting there. Market orders are
+// skipped. Callers hold the symbol lock and call it after the change commits.
+func (e *Engine) recordBookChange(symbol string, ob *OrderBook, orders []*models.Order) {
+	if e.config.BookDeltaHistory == 0 {
+		return
+	}
+
+	type levelKey struct {
+		side  models.OrderSide
+		price string
+	}
+	var changes []models.BookLevelChange
+	seen := make(map[levelKey]bool, len(orders))
+	for _, o := range orders {
+		if o.Price == nil {
+			continue
+		}
+		key := levelKey{o.Side, priceKey(*o.Price)}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		changes = append(changes, models.BookLevelChange{
+			Side:     o.Side,
+			Price:    *o.Price,
+			Quantity: ob.levelQuantity(o.Side, *o.Price),
+		})
+	}
+	if len(changes) > 0 {
+		e.bookDeltaLog(symbol).append(changes)
+	}
+}
+
+// recordPlacementChange records the levels a committed placement of order
+// changed: those of the resting orders it traded with or canceled, and its
+// own if it rested.
+func (e *Engine) recordPlacementChange(order *models.Order, ob *OrderBook, result *MatchResult) {
+	if e.config.BookDeltaHistory == 0 {
+		return
+	}
+	touched := make([]*models.Order, 0, len(result.UpdatedOrders)+1)
+	for _, u := range result.UpdatedOrders {
+		if u.ID != order.ID {
+			touched = append(touched, u)
+		}
+	}
+	if result.IncomingOrderLeft != nil {
+		touched = append(touched, result.IncomingOrderLeft)
+	}
+	e.recordBookChange(order.Symbol, ob, touched)
+}
+
+// GetBookDelta returns the changes to symbol's book after sequence since, for
+// clients that poll instead of streaming. Each change sets a price level's
+// total quantity, zero removing the level. When since is 0, ahead of the
+// book or older than the retained history, the full book is returned instead
+// with Snapshot set. Quantities are never rounded for display.
+//
+// Applying a delta twice is harmless, so the snapshot may already include
+// changes numbered after the returned Sequence.
+func (e *Engine) GetBookDelta(symbol string, since uint64) (*models.OrderBookDeltaResponse, error) {
+	if e.config.BookDeltaHistory == 0 {
+		return nil, ErrBookDeltasDisabled
+	}
+
+	seq, deltas, ok := e.bookDeltaLog(symbol).since(since)
+	resp := &models.OrderBookDeltaResponse{Symbol: symbol, Sequence: seq}
+	if ok {
+		resp.Deltas = deltas
+		return resp, nil
+	}
+	resp.Snapshot = true
+	resp.Bids, resp.Asks = e.getOrderBook(symbol).GetAggregatedLevels(math.MaxInt)
+	return resp, nil
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyBookDeltas applies deltas to a book held as price -> quantity per side.
+func applyBookDeltas(book map[models.OrderSide]map[string]decimal.Decimal, deltas []models.BookDelta) {
+	for _, d := range deltas {
+		for _, c := range d.Changes {
+			if c.Quantity.IsZero() {
+				delete(book[c.Side], c.Price.String())
+			} else {
+				book[c.Side][c.Price.String()] = c.Quantity
+			}
+		}
+	}
+}
+
+func bookFromLevels(bids, asks []models.OrderBookLevel) map[models.OrderSide]map[string]decimal.Decimal {
+	book := map[models.OrderSide]map[string]decimal.Decimal{
+		models.OrderSideBuy:  {},
+		models.OrderSideSell: {},
+	}
+	for _, l := range bids {
+		book[models.OrderSideBuy][l.Price.String()] = l.Quantity
+	}
+	for _, l := range asks {
+		book[models.OrderSideSell][l.Price.String()] = l.Quantity
+	}
+	return book
+}
+
+// TestEngine_BookDelta checks that the deltas after a snapshot rebuild the
+// current book, and that a client behind the retained history gets a
+// snapshot.
+func TestEngine_BookDelta(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.BookDeltaHistory = 6
+	eng, _ := newFakeEngine(t, cfg)
+
+	place := func(req *models.CreateOrderRequest) {
+		t.Helper()
+		_, _, err := eng.PlaceOrder(req)
+		require.NoError(t, err)
+	}
+	place(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	place(limitRequest("BTCUSD", models.OrderSideSell, 50010, 2))
+	place(limitRequest("BTCUSD", models.OrderSideBuy, 49990, 1))
+
+	snap, err := eng.GetBookDelta("BTCUSD", 0)
+	require.NoError(t, err)
+	require.True(t, snap.Snapshot)
+	assert.Equal(t, uint64(3), snap.Sequence)
+	book := bookFromLevels(snap.Bids, snap.Asks)
+
+	place(limitRequest("BTCUSD", models.OrderSideSell, 50000, 0.5)) // joins a level
+	place(marketRequest("BTCUSD", models.OrderSideBuy, 2))          // clears 50000, partially fills 50010
+	place(limitRequest("BTCUSD", models.OrderSideBuy, 50020, 1))    // fills 50010 and rests the rest
+	place(marketRequest("ETHUSD", models.OrderSideBuy, 1))          // no book change
+	_, err = eng.CancelOrdersBefore("BTCUSD", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	place(limitRequest("BTCUSD", models.OrderSideBuy, 49980, 3))
+
+	delta, err := eng.GetBookDelta("BTCUSD", snap.Sequence)
+	require.NoError(t, err)
+	require.False(t, delta.Snapshot)
+	assert.Equal(t, uint64(8), delta.Sequence)
+	require.Len(t, delta.Deltas, 5)
+	assert.Equal(t, uint64(4), delta.Deltas[0].Sequence)
+
+	applyBookDeltas(book, delta.Deltas)
+	bids, asks := eng.getOrderBook("BTCUSD").GetAggregatedLevels(math.MaxInt)
+	assert.Equal(t, bookFromLevels(bids, asks), book)
+
+	upToDate, err := eng.GetBookDelta("BTCUSD", delta.Sequence)
+	require.NoError(t, err)
+	assert.False(t, upToDate.Snapshot)
+	assert.Empty(t, upToDate.Deltas)
+
+	for i := 0; i < 6; i++ {
+		place(limitRequest("BTCUSD", models.OrderSideSell, float64(51000+i), 1))
+	}
+	stale, err := eng.GetBookDelta("BTCUSD", snap.Sequence)
+	require.NoError(t, err)
+	assert.True(t, stale.Snapshot, "deltas 4 and up are no longer retained")
+	assert.Equal(t, uint64(14), stale.Sequence)
+	assert.Len(t, stale.Asks, 6)
+}
+
+func TestEngine_BookDeltaDisabled(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+	_, err := eng.GetBookDelta("BTCUSD", 0)
+	assert.ErrorIs(t, err, ErrBookDeltasDisabled)
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"container/list"
+	"sync"
+
+	"order-matching-engine/internal/models"
+)
+
+// orderCache is a bounded LRU of orders that reached a terminal status
+// (filled or canceled). Terminal orders never change again, so cached
+// copies cannot go stale.
+type orderCache struct {
+	capacity int
+	items    map[int64]*list.Element
+	order    *list.List // front = most recently used
+
+	mutex sync.Mutex
+}
+
+// newOrderCache returns a cache holding up to capacity orders.
+func newOrderCache(capacity int) *orderCache {
+	return &orderCache{
+		capacity: capacity,
+		items:    make(map[int64]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get returns a copy of the cached order, if present.
+func (c *orderCache) Get(orderID int64) (*models.Order, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	el, ok := c.items[orderID]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	cp := *el.Value.(*models.Order)
+	return &cp, true
+}
+
+// Put stores a copy of order, evicting the least recently used entry when full.
+func (c *orderCache) Put(order *models.Order) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cp := *order
+	if el, ok := c.items[order.ID]; ok {
+		el.Value = &cp
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[order.ID] = c.order.PushFront(&cp)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*models.Order).ID)
+	}
+}
+
+// Len returns the number of cached orders.
+func (c *orderCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
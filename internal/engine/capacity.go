@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// runCapacityGuard checks database usage now and then every
+// CapacityGuard.CheckInterval until the engine is closed.
+func (e *Engine) runCapacityGuard() {
+	ticker := time.NewTicker(e.config.CapacityGuard.CheckInterval.Duration)
+	defer ticker.Stop()
+
+	for {
+		if err := e.checkCapacity(); err != nil {
+			log.Printf("[ERROR] Capacity check failed: %v", err)
+		}
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkCapacity reads the size and row count of the engine's schema and
+// updates the cached verdict used by PlaceOrder. A failed read leaves the
+// previous verdict in place.
+func (e *Engine) checkCapacity() error {
+	var bytes, rows int64
+	err := e.db.QueryRow(`
+		SELECT COALESCE(SUM(data_length + index_length), 0), COALESCE(SUM(table_rows), 0)
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+	`).Scan(&bytes, &rows)
+	if err != nil {
+		return fmt.Errorf("failed to query database usage: %w", err)
+	}
+
+	g := e.config.CapacityGuard
+	exhausted := (g.MaxBytes > 0 && bytes >= g.MaxBytes) || (g.MaxRows > 0 && rows >= g.MaxRows)
+	if was := e.capacityExhausted.Swap(exhausted); was != exhausted {
+		if exhausted {
+			log.Printf("[WARN] Database usage %d bytes, %d rows reached capacity limit, rejecting new orders", bytes, rows)
+		} else {
+			log.Printf("[INFO] Database usage %d bytes, %d rows below capacity limit, accepting orders", bytes, rows)
+		}
+	}
+	return nil
+}
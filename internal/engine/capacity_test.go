@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_CapacityGuard rejects placements once the database reaches the
+// size limit and accepts them again after usage drops, while cancels are
+// always allowed.
+func TestEngine_CapacityGuard(t *testing.T) {
+	cfg := DefaultConfig()
+	// Left disabled so no background check races the test; thresholds are
+	// applied by calling checkCapacity directly.
+	cfg.CapacityGuard.MaxBytes = 1000
+	eng, fdb := newFakeEngine(t, cfg)
+
+	resting, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+
+	used := int64(1500)
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		switch {
+		case strings.Contains(query, "information_schema.tables"):
+			return &fakeRows{Cols: []string{"bytes", "rows"}, Rows: [][]driver.Value{{used, int64(10)}}}, nil
+		case strings.Contains(query, "WHERE id = ?"):
+			created := time.Now()
+			return &fakeRows{
+				Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+				Rows: [][]driver.Value{{resting.ID, nil, nil, "BTCUSD", "sell", "limit", "50000", "1", "1", "open", int64(0), created, created, nil, nil, nil}},
+			}, nil
+		}
+		return nil, nil
+	}
+	require.NoError(t, eng.checkCapacity())
+
+	inserts := len(fdb.ExecsMatching("INSERT INTO orders"))
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.ErrorIs(t, err, ErrCapacityExhausted)
+	assert.Len(t, fdb.ExecsMatching("INSERT INTO orders"), inserts)
+
+	_, err = eng.CancelOrder(resting.ID)
+	require.NoError(t, err)
+
+	used = 500
+	require.NoError(t, eng.checkCapacity())
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,539 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Config holds optional engine behaviour.
+// The zero value keeps every optional feature disabled.
+type Config struct {
+	// CompletedOrderCacheSize bounds the in-memory LRU of recently filled or
+	// canceled orders consulted by GetOrder before hitting the DB. 0 disables it.
+	CompletedOrderCacheSize int `json:"completed_order_cache_size"`
+
+	// CommitLatencyGuard pauses order placement while DB commits are slow.
+	CommitLatencyGuard CommitLatencyGuardConfig `json:"commit_latency_guard"`
+
+	// CapacityGuard rejects new orders while the database is nearly full.
+	CapacityGuard CapacityGuardConfig `json:"capacity_guard"`
+
+	// Watchdog reports symbols whose placements and cancels stop completing.
+	Watchdog WatchdogConfig `json:"watchdog"`
+
+	// MaxPriorityTier is the highest queue priority tier an order may request.
+	// Higher tiers queue ahead of lower ones at the same price. 0 disables tiers.
+	MaxPriorityTier int `json:"max_priority_tier"`
+
+	// Symbols is the registry of known symbols and their settings.
+	Symbols map[string]SymbolConfig `json:"symbols"`
+	// StrictSymbols rejects orders for symbols missing from Symbols. When
+	// false, a book is created on the first order for any symbol.
+	StrictSymbols bool `json:"strict_symbols"`
+
+	// ClockSkewTolerance is added to client deadlines (valid_until and
+	// sent_at + max_latency_ms) before an order is rejected as expired.
+	ClockSkewTolerance Duration `json:"clock_skew_tolerance"`
+
+	// PreCommitFillEvents publishes provisional fill events as soon as
+	// matching completes, before the DB commit, followed by a confirmation or
+	// retraction per fill once the transaction's outcome is known.
+	PreCommitFillEvents bool `json:"precommit_fill_events"`
+
+	// DuplicateTrades selects how a trade insert that repeats an existing
+	// trade (same orders, execution time and quantity) is handled:
+	// DuplicateTradesIgnore (the default) skips it, DuplicateTradesError
+	// fails the placement.
+	DuplicateTrades string `json:"duplicate_trades"`
+
+	// TradeSampleEvery is N for the sampled trade stream, which carries
+	// roughly one in N trades per symbol.
+	TradeSampleEvery int `json:"trade_sample_every"`
+
+	// IdleBookTTL drops a symbol's in-memory book once it has held no orders
+	// for this long. The book is recreated empty on next use. 0 disables it.
+	IdleBookTTL Duration `json:"idle_book_ttl"`
+
+	// MetricsSymbols, when non-empty, lists the symbols that get their own
+	// label on per-symbol trade metrics. Otherwise the first
+	// MetricsSymbolLimit symbols to trade get one. Trades on any other
+	// symbol are counted under the OtherSymbolLabel label.
+	MetricsSymbols     []string `json:"metrics_symbols"`
+	MetricsSymbolLimit int      `json:"metrics_symbol_limit"`
+
+	// MaxTradesPerOrder caps the trades one incoming order may generate.
+	// Matching stops at the cap and the remainder is handled as described
+	// on MatchOptions.MaxTrades. 0 disables the cap.
+	MaxTradesPerOrder int `json:"max_trades_per_order"`
+
+	// SelfTradePrevention stops orders of the same account from trading
+	// with each other: models.SelfTradeCancelResting or
+	// models.SelfTradeCancelIncoming (see MatchOptions.SelfTradePrevention).
+	// Empty disables it.
+	SelfTradePrevention string `json:"self_trade_prevention"`
+
+	// BookDeltaHistory is how many book changes are retained per symbol for
+	// GET /orderbook/delta. Clients further behind get a full snapshot. 0
+	// disables delta tracking.
+	BookDeltaHistory int `json:"book_delta_history"`
+
+	// CancelRequiresSymbol makes cancels by ID name the order's symbol too,
+	// so a mistyped ID cannot cancel an order on another symbol.
+	CancelRequiresSymbol bool `json:"cancel_requires_symbol"`
+
+	// RejectMarketWithoutLiquidity rejects a market order whose opposing
+	// side is empty with ErrNoLiquidity, writing nothing, rather than
+	// storing it as canceled.
+	RejectMarketWithoutLiquidity bool `json:"reject_market_without_liquidity"`
+
+	// OrderIDNamespace, when set, gives every order a composite ID
+	// "<namespace>-<id>" alongside its numeric one, so IDs from instances
+	// writing to separate databases stay unique once merged. Lookups accept
+	// either form. Letters, digits and underscores, at most 32.
+	OrderIDNamespace string `json:"order_id_namespace"`
+
+	// CrossedBookRecovery selects how LoadOpenOrders handles a recovered
+	// book whose best bid is at or above its best ask: CrossedBookReview
+	// (the default) halts placement on the symbol until ClearReview,
+	// CrossedBookMatch matches the crossing orders against each other.
+	CrossedBookRecovery string `json:"crossed_book_recovery"`
+
+	// TxRetry retries placement and cancel transactions that fail with a
+	// deadlock or lock wait timeout.
+	TxRetry TxRetryConfig `json:"tx_retry"`
+
+	// TradeShards spreads trades over tables trades_0 .. trades_<N-1>,
+	// choosing the table by a hash of the symbol, to reduce insert contention.
+	// 0 or 1 keeps every trade in the single trades table.
+	TradeShards int `json:"trade_shards"`
+
+	// EventLog records every change to the resting books and recovers them
+	// from a periodic snapshot plus the changes logged after it.
+	EventLog EventLogConfig `json:"event_log"`
+}
+
+// Values for Config.DuplicateTrades.
+const (
+	DuplicateTradesIgnore = "ignore"
+	DuplicateTradesError  = "error"
+)
+
+// Values for Config.CrossedBookRecovery.
+const (
+	CrossedBookReview = "review"
+	CrossedBookMatch  = "match"
+)
+
+// SymbolConfig holds per-symbol settings. An entry in Config.Symbols
+// registers the symbol even when all settings are left at their defaults.
+type SymbolConfig struct {
+	// DisplayPrecision, when set, is the number of decimal places aggregated
+	// level quantities are rounded to in book responses. Display only:
+	// matching and stored quantities keep full precision.
+	DisplayPrecision *int32 `json:"display_precision,omitempty"`
+
+	// BaseAsset and QuoteAsset name the traded and pricing assets, and
+	// DisplayCurrency the currency UIs show values in. They are returned as
+	// metadata for clients to format with and don't affect matching.
+	BaseAsset       string `json:"base_asset,omitempty"`
+	QuoteAsset      string `json:"quote_asset,omitempty"`
+	DisplayCurrency string `json:"display_currency,omitempty"`
+
+	// MarketRemainder is the default handling of a market order's unfilled
+	// remainder ("cancel" or "rest"); orders may override it. Empty means cancel.
+	MarketRemainder string `json:"market_remainder,omitempty"`
+
+	// TimeInForce is the default time in force for limit orders that don't
+	// set one. Empty means models.TimeInForceGTC.
+	TimeInForce models.TimeInForce `json:"time_in_force,omitempty"`
+
+	// QuantityPrecision and PricePrecision, when set, are the most decimal
+	// places an order's quantity (the symbol's lot precision) and price may
+	// have; finer orders are rejected. Setting both enables responses in
+	// integer base units, where one unit is 10^-precision.
+	QuantityPrecision *int32 `json:"quantity_precision,omitempty"`
+	PricePrecision    *int32 `json:"price_precision,omitempty"`
+
+	// LotSize, when positive, requires every order quantity to be a
+	// multiple of it.
+	LotSize decimal.Decimal `json:"lot_size"`
+	// QuantityStep, when positive, additionally requires quantities above
+	// QuantityStepThreshold to be multiples of it, so large orders come in
+	// coarser increments than the lot size. Both rules apply independently.
+	QuantityStep          decimal.Decimal `json:"quantity_step"`
+	QuantityStepThreshold decimal.Decimal `json:"quantity_step_threshold"`
+
+	// TickSize is the symbol's minimum price increment.
+	TickSize decimal.Decimal `json:"tick_size"`
+	// MaxTickDistance rejects limit orders that would rest more than this
+	// many ticks from the best opposing price. 0 disables the check.
+	MaxTickDistance int `json:"max_tick_distance,omitempty"`
+
+	// PriceCollarPercent and PriceCollarTicks reject limit orders priced
+	// further than this from the last trade price, on either side. When both
+	// are set the narrower band applies. 0 disables each.
+	PriceCollarPercent decimal.Decimal `json:"price_collar_percent"`
+	PriceCollarTicks   int             `json:"price_collar_ticks,omitempty"`
+
+	// BatchWindow, when positive, queues incoming orders for this long after
+	// the first of a batch arrives and then matches the whole batch in
+	// arrival order under a single hold of the symbol lock. 0 matches each
+	// order immediately.
+	BatchWindow Duration `json:"batch_window"`
+}
+
+// maxDisplayPrecision bounds SymbolConfig.DisplayPrecision.
+const maxDisplayPrecision = 18
+
+// maxAmountPrecision bounds SymbolConfig.QuantityPrecision and PricePrecision.
+const maxAmountPrecision = 18
+
+// maxTradeShards bounds Config.TradeShards.
+const maxTradeShards = 256
+
+// maxBatchWindow bounds SymbolConfig.BatchWindow; callers block for up to
+// this long.
+const maxBatchWindow = time.Second
+
+// CommitLatencyGuardConfig configures the automatic pause of order placement
+// when DB transaction commit latency is sustained above a threshold.
+type CommitLatencyGuardConfig struct {
+	Enabled bool `json:"enabled"`
+	// Window is the number of most recent commits averaged to judge latency.
+	Window int `json:"window"`
+	// PauseThreshold pauses placement when the window average reaches it.
+	PauseThreshold Duration `json:"pause_threshold"`
+	// ResumeThreshold resumes placement once the window average drops to it.
+	ResumeThreshold Duration `json:"resume_threshold"`
+	// ProbeInterval is how often an empty transaction is committed while
+	// paused, so recovery is noticed even when no orders arrive.
+	ProbeInterval Duration `json:"probe_interval"`
+}
+
+// CapacityGuardConfig configures the rejection of new orders when the
+// database's size or row count reaches a limit. Cancels are never rejected.
+type CapacityGuardConfig struct {
+	Enabled bool `json:"enabled"`
+	// MaxBytes is the data plus index size of the engine's schema at which
+	// placement stops. 0 disables the size limit.
+	MaxBytes int64 `json:"max_bytes"`
+	// MaxRows is the total estimated row count of the engine's schema at
+	// which placement stops. 0 disables the row limit.
+	MaxRows int64 `json:"max_rows"`
+	// CheckInterval is how often usage is re-read; placements use the
+	// cached result in between.
+	CheckInterval Duration `json:"check_interval"`
+}
+
+// TxRetryConfig configures the retry of transactions that fail with a
+// retryable error.
+type TxRetryConfig struct {
+	// MaxAttempts is the number of times a transaction is tried, including
+	// the first. 0 or 1 disables retries.
+	MaxAttempts int `json:"max_attempts"`
+	// Backoff is the wait before the first retry; it doubles for each
+	// retry after.
+	Backoff Duration `json:"backoff"`
+}
+
+// EventLogConfig configures the book event log. Each change to a resting
+// order is written to book_events in the transaction that makes it.
+type EventLogConfig struct {
+	Enabled bool `json:"enabled"`
+	// SnapshotEvery is how many of a symbol's events accumulate before its
+	// book is snapshotted and the events the snapshot covers are deleted,
+	// which bounds the events replayed on recovery.
+	SnapshotEvery int `json:"snapshot_every"`
+}
+
+// WatchdogConfig configures stall detection for per-symbol processing.
+type WatchdogConfig struct {
+	// StallTimeout flags a symbol that has had operations in flight but
+	// none completed for this long. 0 disables the watchdog.
+	StallTimeout Duration `json:"stall_timeout"`
+	// ForceRelease gives a stalled symbol a fresh lock so new operations can
+	// proceed. The stuck operation may later resume alongside them; use only
+	// when availability matters more than strict per-symbol serialization.
+	ForceRelease bool `json:"force_release"`
+}
+
+// Duration is a time.Duration read from JSON as a string such as "250ms".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a Go duration string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"250ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON formats the duration as a Go duration string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// DefaultConfig returns the configuration used when none is supplied.
+func DefaultConfig() Config {
+	return Config{
+		DuplicateTrades:     DuplicateTradesIgnore,
+		CrossedBookRecovery: CrossedBookReview,
+		TradeSampleEvery:    10,
+		MetricsSymbolLimit:  100,
+		CommitLatencyGuard: CommitLatencyGuardConfig{
+			Window:          20,
+			PauseThreshold:  Duration{500 * time.Millisecond},
+			ResumeThreshold: Duration{100 * time.Millisecond},
+			ProbeInterval:   Duration{time.Second},
+		},
+		CapacityGuard: CapacityGuardConfig{
+			CheckInterval: Duration{time.Minute},
+		},
+		TxRetry: TxRetryConfig{
+			Backoff: Duration{10 * time.Millisecond},
+		},
+		EventLog: EventLogConfig{
+			SnapshotEvery: 1000,
+		},
+	}
+}
+
+// EnabledFeatures names the optional features and modes this config turns
+// on, in a fixed order. Per-symbol features are listed once if any symbol
+// uses them.
+func (c Config) EnabledFeatures() []string {
+	features := []string{}
+	add := func(on bool, name string) {
+		if on {
+			features = append(features, name)
+		}
+	}
+	add(c.CompletedOrderCacheSize > 0, "completed_order_cache")
+	add(c.CommitLatencyGuard.Enabled, "commit_latency_guard")
+	add(c.CapacityGuard.Enabled, "capacity_guard")
+	add(c.Watchdog.StallTimeout.Duration > 0, "watchdog")
+	add(c.Watchdog.ForceRelease, "watchdog_force_release")
+	add(c.MaxPriorityTier > 0, "priority_tiers")
+	add(c.StrictSymbols, "strict_symbols")
+	add(c.PreCommitFillEvents, "precommit_fill_events")
+	add(c.DuplicateTrades == DuplicateTradesError, "duplicate_trades_error")
+	add(c.IdleBookTTL.Duration > 0, "idle_book_reaper")
+	add(c.TradeShards > 1, "trade_shards")
+	add(c.MaxTradesPerOrder > 0, "max_trades_per_order")
+	add(c.SelfTradePrevention != "", "self_trade_prevention")
+	add(c.BookDeltaHistory > 0, "book_deltas")
+	add(c.CancelRequiresSymbol, "cancel_requires_symbol")
+	add(c.RejectMarketWithoutLiquidity, "reject_market_without_liquidity")
+	add(c.OrderIDNamespace != "", "order_id_namespace")
+	add(c.TxRetry.MaxAttempts > 1, "tx_retry")
+	add(c.CrossedBookRecovery == CrossedBookMatch, "crossed_book_match")
+	add(c.EventLog.Enabled, "event_log")
+
+	var defaultIOC, lotSize, quantityStep, tickDistance, collar, batching, precision, meta bool
+	for _, sc := range c.Symbols {
+		defaultIOC = defaultIOC || sc.TimeInForce == models.TimeInForceIOC
+		lotSize = lotSize || sc.LotSize.IsPositive()
+		quantityStep = quantityStep || sc.QuantityStep.IsPositive()
+		tickDistance = tickDistance || sc.MaxTickDistance > 0
+		collar = collar || sc.PriceCollarPercent.IsPositive() || sc.PriceCollarTicks > 0
+		batching = batching || sc.BatchWindow.Duration > 0
+		precision = precision || sc.QuantityPrecision != nil || sc.PricePrecision != nil
+		meta = meta || sc.BaseAsset != "" || sc.QuoteAsset != "" || sc.DisplayCurrency != ""
+	}
+	add(defaultIOC, "default_ioc")
+	add(lotSize, "lot_size")
+	add(quantityStep, "quantity_step")
+	add(tickDistance, "max_tick_distance")
+	add(collar, "price_collar")
+	add(batching, "batch_window")
+	add(precision, "amount_precision")
+	add(meta, "symbol_meta")
+	return features
+}
+
+// LoadConfig reads a JSON config file, starting from DefaultConfig so
+// omitted fields keep their defaults.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the config for out-of-range values.
+func (c Config) Validate() error {
+	if c.CompletedOrderCacheSize < 0 {
+		return fmt.Errorf("completed_order_cache_size must not be negative")
+	}
+	if c.StrictSymbols && len(c.Symbols) == 0 {
+		return fmt.Errorf("strict_symbols requires at least one entry in symbols")
+	}
+	for symbol, sc := range c.Symbols {
+		if p := sc.DisplayPrecision; p != nil && (*p < 0 || *p > maxDisplayPrecision) {
+			return fmt.Errorf("symbols.%s.display_precision must be between 0 and %d", symbol, maxDisplayPrecision)
+		}
+		for _, code := range [...]struct{ field, value string }{
+			{"base_asset", sc.BaseAsset},
+			{"quote_asset", sc.QuoteAsset},
+			{"display_currency", sc.DisplayCurrency},
+		} {
+			if code.value != "" && !validAssetCode(code.value) {
+				return fmt.Errorf("symbols.%s.%s must be 1-%d letters or digits", symbol, code.field, maxAssetCodeLength)
+			}
+		}
+		if p := sc.QuantityPrecision; p != nil && (*p < 0 || *p > maxAmountPrecision) {
+			return fmt.Errorf("symbols.%s.quantity_precision must be between 0 and %d", symbol, maxAmountPrecision)
+		}
+		if p := sc.PricePrecision; p != nil && (*p < 0 || *p > maxAmountPrecision) {
+			return fmt.Errorf("symbols.%s.price_precision must be between 0 and %d", symbol, maxAmountPrecision)
+		}
+		if sc.LotSize.IsNegative() || sc.QuantityStep.IsNegative() || sc.QuantityStepThreshold.IsNegative() {
+			return fmt.Errorf("symbols.%s lot_size, quantity_step and quantity_step_threshold must not be negative", symbol)
+		}
+		if sc.QuantityStepThreshold.IsPositive() && !sc.QuantityStep.IsPositive() {
+			return fmt.Errorf("symbols.%s.quantity_step_threshold requires a positive quantity_step", symbol)
+		}
+		if sc.TickSize.IsNegative() {
+			return fmt.Errorf("symbols.%s.tick_size must not be negative", symbol)
+		}
+		if sc.MaxTickDistance < 0 {
+			return fmt.Errorf("symbols.%s.max_tick_distance must not be negative", symbol)
+		}
+		if sc.MaxTickDistance > 0 && !sc.TickSize.IsPositive() {
+			return fmt.Errorf("symbols.%s.max_tick_distance requires a positive tick_size", symbol)
+		}
+		if sc.PriceCollarPercent.IsNegative() {
+			return fmt.Errorf("symbols.%s.price_collar_percent must not be negative", symbol)
+		}
+		if sc.PriceCollarTicks < 0 {
+			return fmt.Errorf("symbols.%s.price_collar_ticks must not be negative", symbol)
+		}
+		if sc.PriceCollarTicks > 0 && !sc.TickSize.IsPositive() {
+			return fmt.Errorf("symbols.%s.price_collar_ticks requires a positive tick_size", symbol)
+		}
+		if sc.BatchWindow.Duration < 0 || sc.BatchWindow.Duration > maxBatchWindow {
+			return fmt.Errorf("symbols.%s.batch_window must be between 0 and %v", symbol, maxBatchWindow)
+		}
+		if sc.TimeInForce != "" && !sc.TimeInForce.Valid() {
+			return fmt.Errorf("symbols.%s.time_in_force must be %q or %q", symbol, models.TimeInForceGTC, models.TimeInForceIOC)
+		}
+		switch sc.MarketRemainder {
+		case "", models.MarketRemainderCancel, models.MarketRemainderRest:
+		default:
+			return fmt.Errorf("symbols.%s.market_remainder must be %q or %q", symbol, models.MarketRemainderCancel, models.MarketRemainderRest)
+		}
+	}
+	switch c.DuplicateTrades {
+	case "", DuplicateTradesIgnore, DuplicateTradesError:
+	default:
+		return fmt.Errorf("duplicate_trades must be %q or %q", DuplicateTradesIgnore, DuplicateTradesError)
+	}
+	switch c.CrossedBookRecovery {
+	case "", CrossedBookReview, CrossedBookMatch:
+	default:
+		return fmt.Errorf("crossed_book_recovery must be %q or %q", CrossedBookReview, CrossedBookMatch)
+	}
+	if c.TradeSampleEvery < 0 {
+		return fmt.Errorf("trade_sample_every must not be negative")
+	}
+	if c.TradeShards < 0 || c.TradeShards > maxTradeShards {
+		return fmt.Errorf("trade_shards must be between 0 and %d", maxTradeShards)
+	}
+	if c.MetricsSymbolLimit < 0 {
+		return fmt.Errorf("metrics_symbol_limit must not be negative")
+	}
+	if c.IdleBookTTL.Duration < 0 {
+		return fmt.Errorf("idle_book_ttl must not be negative")
+	}
+	if c.ClockSkewTolerance.Duration < 0 {
+		return fmt.Errorf("clock_skew_tolerance must not be negative")
+	}
+	if c.MaxPriorityTier < 0 {
+		return fmt.Errorf("max_priority_tier must not be negative")
+	}
+	if g := c.CapacityGuard; g.Enabled {
+		if g.MaxBytes < 0 || g.MaxRows < 0 {
+			return fmt.Errorf("capacity_guard limits must not be negative")
+		}
+		if g.MaxBytes == 0 && g.MaxRows == 0 {
+			return fmt.Errorf("capacity_guard requires max_bytes or max_rows")
+		}
+		if g.CheckInterval.Duration <= 0 {
+			return fmt.Errorf("capacity_guard.check_interval must be positive")
+		}
+	}
+	if c.MaxTradesPerOrder < 0 {
+		return fmt.Errorf("max_trades_per_order must not be negative")
+	}
+	if c.TxRetry.MaxAttempts < 0 {
+		return fmt.Errorf("tx_retry.max_attempts must not be negative")
+	}
+	if c.TxRetry.Backoff.Duration < 0 {
+		return fmt.Errorf("tx_retry.backoff must not be negative")
+	}
+	if c.EventLog.Enabled && c.EventLog.SnapshotEvery < 1 {
+		return fmt.Errorf("event_log.snapshot_every must be at least 1")
+	}
+	if c.OrderIDNamespace != "" && !validOrderIDNamespace(c.OrderIDNamespace) {
+		return fmt.Errorf("order_id_namespace must be 1-%d letters, digits or underscores", maxOrderIDNamespaceLength)
+	}
+	if c.BookDeltaHistory < 0 {
+		return fmt.Errorf("book_delta_history must not be negative")
+	}
+	switch c.SelfTradePrevention {
+	case "", models.SelfTradeCancelResting, models.SelfTradeCancelIncoming:
+	default:
+		return fmt.Errorf("self_trade_prevention must be %q or %q, got %q",
+			models.SelfTradeCancelResting, models.SelfTradeCancelIncoming, c.SelfTradePrevention)
+	}
+	if c.Watchdog.StallTimeout.Duration < 0 {
+		return fmt.Errorf("watchdog.stall_timeout must not be negative")
+	}
+	if c.Watchdog.ForceRelease && c.Watchdog.StallTimeout.Duration == 0 {
+		return fmt.Errorf("watchdog.force_release requires stall_timeout")
+	}
+	if g := c.CommitLatencyGuard; g.Enabled {
+		if g.Window < 1 {
+			return fmt.Errorf("commit_latency_guard.window must be at least 1")
+		}
+		if g.PauseThreshold.Duration <= 0 || g.ResumeThreshold.Duration <= 0 {
+			return fmt.Errorf("commit_latency_guard thresholds must be positive")
+		}
+		if g.ResumeThreshold.Duration > g.PauseThreshold.Duration {
+			return fmt.Errorf("commit_latency_guard.resume_threshold must not exceed pause_threshold")
+		}
+		if g.ProbeInterval.Duration <= 0 {
+			return fmt.Errorf("commit_latency_guard.probe_interval must be positive")
+		}
+	}
+	return nil
+}
+
+// symbolConfig returns the settings registered for symbol, if any.
+func (c Config) symbolConfig(symbol string) (SymbolConfig, bool) {
+	sc, ok := c.Symbols[symbol]
+	return sc, ok
+}
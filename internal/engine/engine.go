@@ -3,7 +3,10 @@ package engine
 import (
 	"database/sql"
 	"fmt"
+	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"order-matching-engine/internal/models"
@@ -15,6 +18,7 @@ import (
 // It holds DB connections, prepared statements, matcher and in-memory order books.
 type Engine struct {
 	db            *sql.DB
+	config        Config
 	matcher       *Matcher
 	orderBooks    map[string]*OrderBook
 	symbolMutexes map[string]*sync.Mutex
@@ -22,23 +26,101 @@ type Engine struct {
 
 	// Prepared statements for common DB operations.
 	insertOrderStmt *sql.Stmt
-	insertTradeStmt *sql.Stmt
-	updateOrderStmt *sql.Stmt
-	selectOrderStmt *sql.Stmt
+	// insertTradeStmts holds one insert statement per trades table, indexed
+	// by shard (a single entry when trades are not sharded).
+	insertTradeStmts []*sql.Stmt
+	updateOrderStmt  *sql.Stmt
+	updateRestStmt   *sql.Stmt
+	selectOrderStmt  *sql.Stmt
+
+	// completedOrders caches recently filled/canceled orders (nil when disabled).
+	completedOrders *orderCache
+	// latencyMonitor drives the commit latency guard (nil when disabled).
+	latencyMonitor *commitLatencyMonitor
+	// capacityExhausted caches the capacity guard's latest verdict.
+	capacityExhausted atomic.Bool
+	// watchdog tracks per-symbol progress (nil when disabled).
+	watchdog *symbolWatchdog
+
+	// lastPrices holds the most recent trade price per symbol seen by this
+	// process; GetLastPrice falls back to the DB for other symbols.
+	lastPrices     map[string]decimal.Decimal
+	lastPriceMutex sync.RWMutex
+
+	// events publishes trades and fill notifications to stream subscribers.
+	events *Hub
+	// matchSeq numbers placements that produced trades (Event.MatchID).
+	matchSeq atomic.Uint64
+	// symbolMetrics counts trades and volume per symbol for /metrics.
+	symbolMetrics *symbolTradeMetrics
+	// selfTrades counts self-trade prevention cancels per account.
+	selfTrades *selfTradeStats
+	// bookDeltas numbers and retains book changes per symbol for
+	// GetBookDelta.
+	bookDeltas     map[string]*bookDeltaLog
+	bookDeltaMutex sync.Mutex
+	// batchers queue orders for symbols with a batch_window.
+	batchers     map[string]*orderBatcher
+	batcherMutex sync.Mutex
+	// underReview flags symbols whose recovered book was crossed; placement
+	// on them is halted until ClearReview.
+	underReview map[string]bool
+	reviewMutex sync.RWMutex
+	// eventLog tracks the book event log (nil when disabled).
+	eventLog *bookEventLog
+
+	// done is closed by Close to stop background goroutines.
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewEngine constructs an Engine and prepares SQL statements.
-func NewEngine(db *sql.DB) (*Engine, error) {
+// NewEngine constructs an Engine with the given config and prepares SQL statements.
+func NewEngine(db *sql.DB, cfg Config) (*Engine, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid engine config: %w", err)
+	}
+
 	e := &Engine{
 		db:            db,
+		config:        cfg,
 		matcher:       NewMatcher(),
 		orderBooks:    make(map[string]*OrderBook),
 		symbolMutexes: make(map[string]*sync.Mutex),
+		lastPrices:    make(map[string]decimal.Decimal),
+		events:        NewHub(),
+		symbolMetrics: newSymbolTradeMetrics(cfg.MetricsSymbols, cfg.MetricsSymbolLimit),
+		selfTrades:    newSelfTradeStats(),
+		bookDeltas:    make(map[string]*bookDeltaLog),
+		batchers:      make(map[string]*orderBatcher),
+		underReview:   make(map[string]bool),
+		done:          make(chan struct{}),
+	}
+	if cfg.CompletedOrderCacheSize > 0 {
+		e.completedOrders = newOrderCache(cfg.CompletedOrderCacheSize)
+	}
+	if cfg.CommitLatencyGuard.Enabled {
+		e.latencyMonitor = newCommitLatencyMonitor(cfg.CommitLatencyGuard)
+	}
+	if cfg.EventLog.Enabled {
+		e.eventLog = newBookEventLog(cfg.EventLog)
 	}
 
+	if err := e.ensureTradeShards(); err != nil {
+		return nil, err
+	}
 	if err := e.prepareStatements(); err != nil {
 		return nil, fmt.Errorf("failed to prepare SQL statements: %w", err)
 	}
+	if cfg.IdleBookTTL.Duration > 0 {
+		go e.runBookReaper()
+	}
+	if cfg.CapacityGuard.Enabled {
+		go e.runCapacityGuard()
+	}
+	if cfg.Watchdog.StallTimeout.Duration > 0 {
+		e.watchdog = newSymbolWatchdog()
+		go e.runWatchdog()
+	}
 	return e, nil
 }
 
@@ -48,22 +130,32 @@ func (e *Engine) prepareStatements() error {
 
 	e.insertOrderStmt, err = e.db.Prepare(`
 		INSERT INTO orders (
-			client_order_id, symbol, side, type, price, 
-			initial_quantity, remaining_quantity, status, 
+			id_namespace, client_order_id, account_id, symbol, side, type, price, 
+			initial_quantity, remaining_quantity, status, tier,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare insert order statement: %w", err)
 	}
 
-	e.insertTradeStmt, err = e.db.Prepare(`
-		INSERT INTO trades (
-			symbol, buy_order_id, sell_order_id, price, quantity, executed_at
-		) VALUES (?, ?, ?, ?, ?, ?)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare insert trade statement: %w", err)
+	// With duplicate_trades "ignore", re-inserting a trade that hits
+	// uq_trade_execution is a no-op, so a retried write is safe.
+	for _, table := range e.tradeTables() {
+		insertTrade := `
+		INSERT INTO ` + table + ` (
+			symbol, buy_order_id, sell_order_id, buy_global_order_id, sell_global_order_id,
+			price, quantity, executed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		if e.config.DuplicateTrades != DuplicateTradesError {
+			insertTrade += `
+		ON DUPLICATE KEY UPDATE id = id`
+		}
+		stmt, err := e.db.Prepare(insertTrade)
+		if err != nil {
+			return fmt.Errorf("failed to prepare insert trade statement for %s: %w", table, err)
+		}
+		e.insertTradeStmts = append(e.insertTradeStmts, stmt)
 	}
 
 	e.updateOrderStmt, err = e.db.Prepare(`
@@ -75,9 +167,17 @@ func (e *Engine) prepareStatements() error {
 		return fmt.Errorf("failed to prepare update order statement: %w", err)
 	}
 
+	e.updateRestStmt, err = e.db.Prepare(`
+		UPDATE orders 
+		SET type = ?, price = ?, remaining_quantity = ?, status = ?, updated_at = ? 
+		WHERE id = ?
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare update resting order statement: %w", err)
+	}
+
 	e.selectOrderStmt, err = e.db.Prepare(`
-		SELECT id, client_order_id, symbol, side, type, price, 
-		       initial_quantity, remaining_quantity, status, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders 
 		WHERE id = ?
 	`)
@@ -88,14 +188,17 @@ func (e *Engine) prepareStatements() error {
 	return nil
 }
 
-// Close releases prepared statements held by the engine.
+// Close stops background work and releases prepared statements held by the engine.
 func (e *Engine) Close() error {
+	e.closeOnce.Do(func() { close(e.done) })
+
 	stmts := []*sql.Stmt{
 		e.insertOrderStmt,
-		e.insertTradeStmt,
 		e.updateOrderStmt,
+		e.updateRestStmt,
 		e.selectOrderStmt,
 	}
+	stmts = append(stmts, e.insertTradeStmts...)
 	for _, s := range stmts {
 		if s != nil {
 			s.Close()
@@ -104,6 +207,164 @@ func (e *Engine) Close() error {
 	return nil
 }
 
+// commit commits tx and reports its latency to the commit latency guard.
+func (e *Engine) commit(tx *sql.Tx) error {
+	start := time.Now()
+	err := tx.Commit()
+	e.observeCommit(time.Since(start))
+	return err
+}
+
+// checkSymbol rejects symbols missing from the registry when strict_symbols
+// is enabled. Otherwise any symbol is accepted and its book created on demand.
+func (e *Engine) checkSymbol(symbol string) error {
+	if !e.config.StrictSymbols {
+		return nil
+	}
+	if _, ok := e.config.symbolConfig(symbol); !ok {
+		return fmt.Errorf("%w: %s is not a registered symbol", ErrUnknownSymbol, symbol)
+	}
+	return nil
+}
+
+// checkPrecision rejects an order whose quantity or price is finer than its
+// symbol's configured precision.
+func (e *Engine) checkPrecision(req *models.CreateOrderRequest) error {
+	sc, _ := e.config.symbolConfig(req.Symbol)
+	if p := sc.QuantityPrecision; p != nil && !req.Quantity.Shift(*p).IsInteger() {
+		return fmt.Errorf("%w: quantity %s has more than %d decimal places", ErrPrecisionExceeded, req.Quantity, *p)
+	}
+	if p := sc.PricePrecision; p != nil && req.Price != nil && !req.Price.Shift(*p).IsInteger() {
+		return fmt.Errorf("%w: price %s has more than %d decimal places", ErrPrecisionExceeded, req.Price, *p)
+	}
+	return nil
+}
+
+// checkQuantityIncrements rejects an order whose quantity is not a multiple
+// of its symbol's lot size or, above the step threshold, of its quantity
+// step.
+func (e *Engine) checkQuantityIncrements(req *models.CreateOrderRequest) error {
+	sc, _ := e.config.symbolConfig(req.Symbol)
+	if sc.LotSize.IsPositive() && !req.Quantity.Mod(sc.LotSize).IsZero() {
+		return fmt.Errorf("%w: %s is not a multiple of %s", ErrInvalidLotSize, req.Quantity, sc.LotSize)
+	}
+	if sc.QuantityStep.IsPositive() && req.Quantity.GreaterThan(sc.QuantityStepThreshold) &&
+		!req.Quantity.Mod(sc.QuantityStep).IsZero() {
+		return fmt.Errorf("%w: %s is above %s and not a multiple of %s",
+			ErrInvalidQuantityStep, req.Quantity, sc.QuantityStepThreshold, sc.QuantityStep)
+	}
+	return nil
+}
+
+// BaseUnitScale returns the scale at which symbol's amounts are expressed in
+// integer base units, or ErrBaseUnitsUnavailable.
+func (e *Engine) BaseUnitScale(symbol string) (models.BaseUnitScale, error) {
+	sc, _ := e.config.symbolConfig(symbol)
+	if sc.QuantityPrecision == nil || sc.PricePrecision == nil {
+		return models.BaseUnitScale{}, fmt.Errorf("%w: %s", ErrBaseUnitsUnavailable, symbol)
+	}
+	return models.BaseUnitScale{Price: *sc.PricePrecision, Quantity: *sc.QuantityPrecision}, nil
+}
+
+// checkExpiry rejects an order that reached the engine after its client
+// deadline. Deadlines are extended by the configured clock skew tolerance.
+func (e *Engine) checkExpiry(req *models.CreateOrderRequest, now time.Time) error {
+	skew := e.config.ClockSkewTolerance.Duration
+	if req.ValidUntil != nil && now.After(req.ValidUntil.Add(skew)) {
+		return fmt.Errorf("%w: valid_until %s has passed", ErrExpired, req.ValidUntil.Format(time.RFC3339Nano))
+	}
+	if req.MaxLatencyMs > 0 && req.SentAt != nil {
+		maxLatency := time.Duration(req.MaxLatencyMs) * time.Millisecond
+		if latency := now.Sub(*req.SentAt); latency > maxLatency+skew {
+			return fmt.Errorf("%w: arrived %s after sent_at (max %s)", ErrExpired, latency.Round(time.Millisecond), maxLatency)
+		}
+	}
+	return nil
+}
+
+// checkLiquidity rejects a market order facing an empty opposing side when
+// reject_market_without_liquidity is set, instead of storing it canceled.
+// Caller holds the symbol lock.
+func (e *Engine) checkLiquidity(req *models.CreateOrderRequest, ob *OrderBook) error {
+	if !e.config.RejectMarketWithoutLiquidity || req.Type != models.OrderTypeMarket {
+		return nil
+	}
+	opposing := models.OrderSideSell
+	if req.Side == models.OrderSideSell {
+		opposing = models.OrderSideBuy
+	}
+	if ob.levelCount(opposing) == 0 {
+		return fmt.Errorf("%w: no %s orders in %s", ErrNoLiquidity, opposing, req.Symbol)
+	}
+	return nil
+}
+
+// checkTickDistance rejects a limit order that would rest more than the
+// symbol's max_tick_distance ticks away from the best opposing price.
+// Marketable orders, and orders facing an empty opposing side, always pass.
+// Caller holds the symbol lock.
+func (e *Engine) checkTickDistance(req *models.CreateOrderRequest, ob *OrderBook) error {
+	sc, _ := e.config.symbolConfig(req.Symbol)
+	if sc.MaxTickDistance == 0 || req.Type != models.OrderTypeLimit || req.Price == nil {
+		return nil
+	}
+
+	var best *models.Order
+	var distance decimal.Decimal
+	if req.Side == models.OrderSideBuy {
+		if best = ob.GetBestAsk(); best != nil {
+			distance = best.Price.Sub(*req.Price)
+		}
+	} else {
+		if best = ob.GetBestBid(); best != nil {
+			distance = req.Price.Sub(*best.Price)
+		}
+	}
+	if best == nil || !distance.IsPositive() {
+		return nil
+	}
+
+	ticks := distance.Div(sc.TickSize)
+	if ticks.GreaterThan(decimal.NewFromInt(int64(sc.MaxTickDistance))) {
+		return fmt.Errorf("%w: %s is %s ticks from best opposing price %s (max %d)",
+			ErrPriceTooFar, req.Price, ticks.Round(2), best.Price, sc.MaxTickDistance)
+	}
+	return nil
+}
+
+// checkPriceCollar rejects a limit order priced further from the last trade
+// price than the symbol's collar allows. Symbols that have never traded, and
+// symbols without a collar, always pass. Caller holds the symbol lock.
+func (e *Engine) checkPriceCollar(req *models.CreateOrderRequest) error {
+	sc, _ := e.config.symbolConfig(req.Symbol)
+	if (sc.PriceCollarPercent.IsZero() && sc.PriceCollarTicks == 0) ||
+		req.Type != models.OrderTypeLimit || req.Price == nil {
+		return nil
+	}
+
+	last, ok, err := e.GetLastPrice(req.Symbol)
+	if err != nil || !ok {
+		return err
+	}
+
+	var width decimal.Decimal
+	if sc.PriceCollarPercent.IsPositive() {
+		width = last.Mul(sc.PriceCollarPercent).Div(decimal.NewFromInt(100))
+	}
+	if sc.PriceCollarTicks > 0 {
+		ticks := sc.TickSize.Mul(decimal.NewFromInt(int64(sc.PriceCollarTicks)))
+		if width.IsZero() || ticks.LessThan(width) {
+			width = ticks
+		}
+	}
+
+	if req.Price.Sub(last).Abs().GreaterThan(width) {
+		return fmt.Errorf("%w: %s is more than %s from last trade price %s",
+			ErrOutsidePriceCollar, req.Price, width, last)
+	}
+	return nil
+}
+
 // getSymbolMutex returns a per-symbol mutex, creating it if necessary.
 // This provides coarse-grained serialization per trading symbol.
 func (e *Engine) getSymbolMutex(symbol string) *sync.Mutex {
@@ -139,6 +400,28 @@ func (e *Engine) getOrderBook(symbol string) *OrderBook {
 	return ob
 }
 
+// Book snapshot depth used for include_book requests.
+const (
+	DefaultBookSnapshotDepth = 10
+	MaxBookSnapshotDepth     = 50
+)
+
+// Placement is the outcome of PlaceOrderDetailed.
+type Placement struct {
+	Order  *models.Order
+	Trades []models.Trade
+	// BookBefore and BookAfter are set when the request asked for include_book.
+	BookBefore *models.BookSnapshot
+	BookAfter  *models.BookSnapshot
+	// Trace holds the matching steps when the request asked for trace.
+	Trace []models.MatchTraceStep
+	// QueuePosition (1 = head) and QuantityAhead locate a resting remainder
+	// in its price level's queue as it rested. QueuePosition is 0 when
+	// nothing rested.
+	QueuePosition int
+	QuantityAhead decimal.Decimal
+}
+
 // PlaceOrder processes a new order atomically:
 // - acquires per-symbol lock
 // - inserts the incoming order into DB within a transaction
@@ -146,14 +429,114 @@ func (e *Engine) getOrderBook(symbol string) *OrderBook {
 // - persists trades and order updates
 // - commits the transaction
 func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []models.Trade, error) {
+	p, err := e.PlaceOrderDetailed(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.Order, p.Trades, nil
+}
+
+// PlaceOrderDetailed is PlaceOrder returning a Placement. With req.IncludeBook
+// set, the top levels are captured under the symbol lock immediately before
+// matching and after any remainder rests, so the pair shows exactly this
+// order's effect. The depth defaults to DefaultBookSnapshotDepth and is
+// capped at MaxBookSnapshotDepth. On a symbol with a batch_window the order
+// is queued and matched with the rest of its batch; the call returns once
+// its batch has run.
+func (e *Engine) PlaceOrderDetailed(req *models.CreateOrderRequest) (*Placement, error) {
+	if err := e.checkExpiry(req, time.Now()); err != nil {
+		return nil, err
+	}
+	if e.IsAutoPaused() {
+		return nil, ErrAutoPaused
+	}
+	if e.capacityExhausted.Load() {
+		return nil, ErrCapacityExhausted
+	}
+	if err := e.checkSymbol(req.Symbol); err != nil {
+		return nil, err
+	}
+	if e.UnderReview(req.Symbol) {
+		return nil, ErrSymbolUnderReview
+	}
+	if err := e.checkPrecision(req); err != nil {
+		return nil, err
+	}
+	if err := e.checkQuantityIncrements(req); err != nil {
+		return nil, err
+	}
+	if req.Tier < 0 || req.Tier > e.config.MaxPriorityTier {
+		return nil, fmt.Errorf("%w: %d (max %d)", ErrInvalidTier, req.Tier, e.config.MaxPriorityTier)
+	}
+	bookDepth := 0
+	if req.IncludeBook {
+		bookDepth = req.BookDepth
+		if bookDepth <= 0 {
+			bookDepth = DefaultBookSnapshotDepth
+		}
+		if bookDepth > MaxBookSnapshotDepth {
+			bookDepth = MaxBookSnapshotDepth
+		}
+	}
+
+	defer e.trackSymbolWork(req.Symbol)()
+
+	if sc, _ := e.config.symbolConfig(req.Symbol); sc.BatchWindow.Duration > 0 {
+		return e.getBatcher(req.Symbol, sc.BatchWindow.Duration).submit(req, bookDepth)
+	}
+
 	// Per-symbol serialization to avoid cross-symbol interference.
 	symbolMutex := e.getSymbolMutex(req.Symbol)
 	symbolMutex.Lock()
 	defer symbolMutex.Unlock()
 
+	return e.placeLocked(req, bookDepth)
+}
+
+// placeLocked stores, matches and commits one order. Caller holds the
+// symbol lock and has run the checks that don't need it. Matching updates the
+// live book ahead of the commit; with tx_retry, an attempt that fails with a
+// retryable error has the symbol's book rebuilt from the DB before the next
+// one, so a retried order is never applied twice.
+func (e *Engine) placeLocked(req *models.CreateOrderRequest, bookDepth int) (*Placement, error) {
+	orderBook := e.getOrderBook(req.Symbol)
+	if e.config.TxRetry.MaxAttempts <= 1 {
+		return e.placeAttempt(req, bookDepth, orderBook)
+	}
+
+	var placement *Placement
+	err := e.retryTx(func() error {
+		p, err := e.placeAttempt(req, bookDepth, orderBook)
+		if err != nil {
+			if isRetryableTxError(err) {
+				if rebuildErr := e.rebuildOrderBook(req.Symbol); rebuildErr != nil {
+					return fmt.Errorf("%v; then failed to rebuild the %s book: %w", err, req.Symbol, rebuildErr)
+				}
+			}
+			return err
+		}
+		placement = p
+		return nil
+	})
+	return placement, err
+}
+
+// placeAttempt runs one transaction of placeLocked, matching against
+// orderBook.
+func (e *Engine) placeAttempt(req *models.CreateOrderRequest, bookDepth int, orderBook *OrderBook) (*Placement, error) {
+	if err := e.checkTickDistance(req, orderBook); err != nil {
+		return nil, err
+	}
+	if err := e.checkPriceCollar(req); err != nil {
+		return nil, err
+	}
+	if err := e.checkLiquidity(req, orderBook); err != nil {
+		return nil, err
+	}
+
 	tx, err := e.db.Begin()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	// Protect against panic leaking a transaction.
 	defer func() {
@@ -165,7 +548,9 @@ func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []mo
 
 	now := time.Now()
 	order := &models.Order{
+		IDNamespace:       e.config.OrderIDNamespace,
 		ClientOrderID:     req.ClientOrderID,
+		AccountID:         req.AccountID,
 		Symbol:            req.Symbol,
 		Side:              req.Side,
 		Type:              req.Type,
@@ -173,6 +558,7 @@ func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []mo
 		InitialQuantity:   req.Quantity,
 		RemainingQuantity: req.Quantity,
 		Status:            models.OrderStatusOpen,
+		Tier:              req.Tier,
 		CreatedAt:         now,
 		UpdatedAt:         now,
 	}
@@ -183,7 +569,9 @@ func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []mo
 	}
 
 	res, err := tx.Stmt(e.insertOrderStmt).Exec(
+		nullableString(order.IDNamespace),
 		order.ClientOrderID,
+		nullableAccountID(order.AccountID),
 		order.Symbol,
 		order.Side,
 		order.Type,
@@ -191,38 +579,73 @@ func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []mo
 		order.InitialQuantity,
 		order.RemainingQuantity,
 		order.Status,
+		order.Tier,
 		order.CreatedAt,
 		order.UpdatedAt,
 	)
 	if err != nil {
 		tx.Rollback()
-		return nil, nil, fmt.Errorf("failed to insert order: %w", err)
+		return nil, fmt.Errorf("failed to insert order: %w", err)
 	}
 
 	orderID, err := res.LastInsertId()
 	if err != nil {
 		tx.Rollback()
-		return nil, nil, fmt.Errorf("failed to get order ID: %w", err)
+		return nil, fmt.Errorf("failed to get order ID: %w", err)
 	}
 	order.ID = orderID
+	e.setGlobalID(order)
 
 	// In-memory matching against the book for the symbol.
-	orderBook := e.getOrderBook(req.Symbol)
-	matchResult := e.matcher.Match(order, orderBook)
+	placement := &Placement{Order: order}
+	if bookDepth > 0 {
+		placement.BookBefore = bookSnapshot(orderBook, bookDepth)
+	}
+	var reference *decimal.Decimal
+	if order.Type == models.OrderTypeMarket {
+		reference = referencePrice(orderBook, order.Side)
+	}
+	matchOpts := e.matchOptions(req)
+	matchResult := e.matcher.MatchWithOptions(order, orderBook, matchOpts)
+	setTradeGlobalIDs(matchResult.Trades, order, matchResult.UpdatedOrders)
+	if matchResult.TradeCapReached {
+		log.Printf("[WARN] Trade cap of %d reached: order=%d, symbol=%s", matchOpts.MaxTrades, order.ID, order.Symbol)
+	}
+
+	// With pre-commit fill events, fills are announced now and settled by a
+	// confirmation after commit or a retraction on any failure below,
+	// including a panic.
+	var matchID uint64
+	committed := false
+	if len(matchResult.Trades) > 0 {
+		matchID = e.matchSeq.Add(1)
+		if e.config.PreCommitFillEvents {
+			e.publishTrades(models.EventFillProvisional, matchID, matchResult.Trades)
+			defer func() {
+				if !committed {
+					e.publishTrades(models.EventFillRetracted, matchID, matchResult.Trades)
+				}
+			}()
+		}
+	}
 
 	// Persist trades
 	for _, trade := range matchResult.Trades {
-		_, err = tx.Stmt(e.insertTradeStmt).Exec(
-			trade.Symbol,
-			trade.BuyOrderID,
-			trade.SellOrderID,
-			trade.Price,
-			trade.Quantity,
-			trade.ExecutedAt,
-		)
+		if err = e.insertTrade(tx, trade); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// Persist a market order's realized slippage.
+	if reference != nil && len(matchResult.Trades) > 0 {
+		incoming := incomingAfterMatch(order.ID, matchResult)
+		slippage := realizedSlippage(order.Side, *reference, matchResult.Trades)
+		incoming.ReferencePrice, incoming.Slippage = reference, &slippage
+		_, err = tx.Exec(`UPDATE orders SET reference_price = ?, slippage = ? WHERE id = ?`, *reference, slippage, order.ID)
 		if err != nil {
 			tx.Rollback()
-			return nil, nil, fmt.Errorf("failed to insert trade: %w", err)
+			return nil, fmt.Errorf("failed to record slippage of order %d: %w", order.ID, err)
 		}
 	}
 
@@ -236,13 +659,40 @@ func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []mo
 		)
 		if err != nil {
 			tx.Rollback()
-			return nil, nil, fmt.Errorf("failed to update order %d: %w", updated.ID, err)
+			return nil, fmt.Errorf("failed to update order %d: %w", updated.ID, err)
+		}
+	}
+
+	// A limit order that filled or was canceled during placement never
+	// rested; queue history must not count it as ever being ahead.
+	if matchResult.IncomingOrderLeft == nil && order.Type == models.OrderTypeLimit {
+		_, err = tx.Exec(`UPDATE orders SET rested = FALSE WHERE id = ?`, order.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to mark order %d as never rested: %w", order.ID, err)
 		}
 	}
 
 	// If incoming limit left, add to in-memory book and reflect final state.
-	if matchResult.IncomingOrderLeft != nil {
+	if left := matchResult.IncomingOrderLeft; left != nil {
+		// A remainder that traded (or was converted from a market order)
+		// no longer matches the row inserted above.
+		if left.RemainingQuantity.LessThan(left.InitialQuantity) {
+			_, err = tx.Stmt(e.updateRestStmt).Exec(
+				left.Type,
+				*left.Price,
+				left.RemainingQuantity,
+				left.Status,
+				left.UpdatedAt,
+				left.ID,
+			)
+			if err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to update resting order %d: %w", left.ID, err)
+			}
+		}
 		orderBook.AddOrder(matchResult.IncomingOrderLeft)
+		placement.QueuePosition, placement.QuantityAhead, _, _ = orderBook.QueuePosition(left.ID, left.Side, *left.Price)
 		*order = *matchResult.IncomingOrderLeft
 	} else {
 		// If fully filled/cancelled, update local order object from updated list.
@@ -254,24 +704,211 @@ func (e *Engine) PlaceOrder(req *models.CreateOrderRequest) (*models.Order, []mo
 		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	if bookDepth > 0 {
+		placement.BookAfter = bookSnapshot(orderBook, bookDepth)
+	}
+
+	var lastSeq int64
+	if e.eventLog != nil {
+		events := make([]bookEvent, 0, len(matchResult.UpdatedOrders)+1)
+		for _, u := range matchResult.UpdatedOrders {
+			if u.ID != order.ID {
+				events = append(events, orderEvent(u))
+			}
+		}
+		if left := matchResult.IncomingOrderLeft; left != nil {
+			events = append(events, orderEvent(left))
+		}
+		if lastSeq, err = e.logBookEvents(tx, order.Symbol, events); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err = e.commit(tx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	e.bookEventsCommitted(order.Symbol, lastSeq)
+	if matchOpts.Trace != nil {
+		placement.Trace = matchOpts.Trace.Steps
+	}
+
+	for _, u := range matchResult.UpdatedOrders {
+		e.cacheCompletedOrder(u)
+	}
+	e.recordPlacementChange(order, orderBook, matchResult)
+
+	if len(matchResult.Trades) > 0 {
+		e.setLastPrice(req.Symbol, matchResult.Trades[len(matchResult.Trades)-1].Price)
+		e.symbolMetrics.record(req.Symbol, matchResult.Trades)
+		if e.config.PreCommitFillEvents {
+			e.publishTrades(models.EventFillConfirmed, matchID, matchResult.Trades)
+		}
+		e.publishTrades(models.EventTrade, matchID, matchResult.Trades)
+	}
+	e.selfTrades.record(order, matchResult)
+	e.publishAccountPlacement(order, matchResult)
+
+	placement.Trades = matchResult.Trades
+	return placement, nil
+}
+
+// Events returns the hub on which the engine publishes trade and fill events.
+func (e *Engine) Events() *Hub {
+	return e.events
+}
+
+// SubscribeSampledTrades subscribes to roughly one in trade_sample_every
+// trades per symbol, restricted to symbol unless it is empty.
+func (e *Engine) SubscribeSampledTrades(symbol string) *Subscription {
+	sample := SampleTrades(e.config.TradeSampleEvery)
+	return e.events.Subscribe(0, func(ev models.Event) bool {
+		if symbol != "" && ev.Symbol != symbol {
+			return false
+		}
+		return sample(ev)
+	})
+}
+
+// publishTrades publishes one event of type t per trade.
+func (e *Engine) publishTrades(t models.EventType, matchID uint64, trades []models.Trade) {
+	now := time.Now()
+	for i := range trades {
+		trade := trades[i]
+		e.events.Publish(models.Event{
+			Type:        t,
+			Symbol:      trade.Symbol,
+			MatchID:     matchID,
+			Provisional: t == models.EventFillProvisional,
+			Trade:       &trade,
+			Timestamp:   now,
+		})
+	}
+}
+
+// bookSnapshot captures the top depth levels of ob.
+func bookSnapshot(ob *OrderBook, depth int) *models.BookSnapshot {
+	bids, asks := ob.GetAggregatedLevels(depth)
+	return &models.BookSnapshot{Bids: bids, Asks: asks}
+}
+
+// matchOptions resolves the matching options for req: the order's own
+// market_remainder and time_in_force, else its symbol's settings, and a
+// trace if requested.
+func (e *Engine) matchOptions(req *models.CreateOrderRequest) MatchOptions {
+	sc, _ := e.config.symbolConfig(req.Symbol)
+	mode := req.MarketRemainder
+	if mode == "" {
+		mode = sc.MarketRemainder
+	}
+	opts := MatchOptions{
+		RestMarketRemainder: mode == models.MarketRemainderRest,
+		ImmediateOrCancel:   timeInForce(req, sc) == models.TimeInForceIOC,
+		MaxTrades:           e.config.MaxTradesPerOrder,
+		SelfTradePrevention: e.config.SelfTradePrevention,
 	}
+	if req.Trace {
+		opts.Trace = &MatchTrace{}
+	}
+	return opts
+}
 
-	return order, matchResult.Trades, nil
+// timeInForce resolves req's time in force: the request's own, else the
+// symbol default, else GTC.
+func timeInForce(req *models.CreateOrderRequest, sc SymbolConfig) models.TimeInForce {
+	switch {
+	case req.TimeInForce != "":
+		return req.TimeInForce
+	case sc.TimeInForce != "":
+		return sc.TimeInForce
+	default:
+		return models.TimeInForceGTC
+	}
+}
+
+// insertTrade writes trade within tx. Duplicates are skipped or rejected
+// according to Config.DuplicateTrades.
+func (e *Engine) insertTrade(tx *sql.Tx, trade models.Trade) error {
+	stmt := e.insertTradeStmts[tradeShard(trade.Symbol, len(e.insertTradeStmts))]
+	_, err := tx.Stmt(stmt).Exec(
+		trade.Symbol,
+		trade.BuyOrderID,
+		trade.SellOrderID,
+		nullableString(trade.BuyGlobalOrderID),
+		nullableString(trade.SellGlobalOrderID),
+		trade.Price,
+		trade.Quantity,
+		trade.ExecutedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert trade: %w", err)
+	}
+	return nil
 }
 
-// GetOrder fetches an order by ID using the prepared select statement.
+// cacheCompletedOrder records a filled or canceled order in the completed-order
+// cache, if enabled. Orders in any other status are ignored.
+func (e *Engine) cacheCompletedOrder(order *models.Order) {
+	if e.completedOrders == nil {
+		return
+	}
+	if order.Status == models.OrderStatusFilled || order.Status == models.OrderStatusCanceled {
+		e.completedOrders.Put(order)
+	}
+}
+
+// GetOrder fetches an order by ID, serving recently completed orders from the
+// in-memory cache and falling back to the prepared select statement.
 func (e *Engine) GetOrder(orderID int64) (*models.Order, error) {
-	row := e.selectOrderStmt.QueryRow(orderID)
+	if e.completedOrders != nil {
+		if order, ok := e.completedOrders.Get(orderID); ok {
+			return order, nil
+		}
+	}
 
+	order, err := scanOrder(e.selectOrderStmt.QueryRow(orderID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found")
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// orderColumns lists the orders table columns read by scanOrder, in order.
+const orderColumns = `id, client_order_id, account_id, symbol, side, type, price,
+		       initial_quantity, remaining_quantity, status, tier, created_at, updated_at,
+		       reference_price, slippage, id_namespace`
+
+// nullableAccountID stores orders without an account as NULL.
+func nullableAccountID(accountID string) interface{} {
+	if accountID == "" {
+		return nil
+	}
+	return accountID
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOrder scans a row selected with orderColumns. sql.ErrNoRows is
+// returned unwrapped so callers can detect a missing order.
+func scanOrder(row rowScanner) (*models.Order, error) {
 	var order models.Order
 	var clientOrderID sql.NullString
+	var accountID sql.NullString
 	var price sql.NullString
+	var referencePrice, slippage decimal.NullDecimal
+	var idNamespace sql.NullString
 
 	err := row.Scan(
 		&order.ID,
 		&clientOrderID,
+		&accountID,
 		&order.Symbol,
 		&order.Side,
 		&order.Type,
@@ -279,12 +916,16 @@ func (e *Engine) GetOrder(orderID int64) (*models.Order, error) {
 		&order.InitialQuantity,
 		&order.RemainingQuantity,
 		&order.Status,
+		&order.Tier,
 		&order.CreatedAt,
 		&order.UpdatedAt,
+		&referencePrice,
+		&slippage,
+		&idNamespace,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("order not found")
+			return nil, err
 		}
 		return nil, fmt.Errorf("failed to scan order: %w", err)
 	}
@@ -292,29 +933,161 @@ func (e *Engine) GetOrder(orderID int64) (*models.Order, error) {
 	if clientOrderID.Valid {
 		order.ClientOrderID = &clientOrderID.String
 	}
+	order.AccountID = accountID.String
 	if price.Valid {
 		priceDecimal, err := decimal.NewFromString(price.String)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse price: %w", err)
+			return nil, fmt.Errorf("failed to parse price for order %d: %w", order.ID, err)
 		}
 		order.Price = &priceDecimal
 	}
+	if referencePrice.Valid {
+		order.ReferencePrice = &referencePrice.Decimal
+	}
+	if slippage.Valid {
+		order.Slippage = &slippage.Decimal
+	}
+	order.IDNamespace = idNamespace.String
+	order.GlobalID = formatGlobalOrderID(order.IDNamespace, order.ID)
 	return &order, nil
 }
 
+// MaxOrderStatusIDs caps the number of order IDs accepted by GetOrderStatuses.
+const MaxOrderStatusIDs = 500
+
+// GetOrderStatuses looks up the status and remaining quantity of several orders
+// in a single query. IDs may be numeric or composite; a composite ID matches
+// only an order placed under its namespace. Entries are returned in the order
+// of the requested IDs; IDs with no matching order are reported in notFound.
+// A malformed ID returns ErrInvalidOrderID.
+func (e *Engine) GetOrderStatuses(orderIDs []models.OrderRef) (found []models.OrderStatusEntry, notFound []models.OrderRef, err error) {
+	if len(orderIDs) == 0 {
+		return []models.OrderStatusEntry{}, []models.OrderRef{}, nil
+	}
+	if len(orderIDs) > MaxOrderStatusIDs {
+		return nil, nil, fmt.Errorf("too many order IDs: %d (max %d)", len(orderIDs), MaxOrderStatusIDs)
+	}
+
+	type parsedRef struct {
+		id int64
+		ns string
+	}
+	parsed := make([]parsedRef, len(orderIDs))
+	args := make([]interface{}, len(orderIDs))
+	for i, ref := range orderIDs {
+		id, ns, err := parseOrderRef(string(ref))
+		if err != nil {
+			return nil, nil, err
+		}
+		parsed[i] = parsedRef{id: id, ns: ns}
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(orderIDs)), ",")
+
+	rows, err := e.db.Query(`
+		SELECT id, id_namespace, status, remaining_quantity
+		FROM orders
+		WHERE id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query order statuses: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]models.OrderStatusEntry, len(orderIDs))
+	namespaces := make(map[int64]string, len(orderIDs))
+	for rows.Next() {
+		var entry models.OrderStatusEntry
+		var ns sql.NullString
+		if err := rows.Scan(&entry.OrderID, &ns, &entry.Status, &entry.RemainingQuantity); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan order status: %w", err)
+		}
+		entry.GlobalOrderID = formatGlobalOrderID(ns.String, entry.OrderID)
+		byID[entry.OrderID] = entry
+		namespaces[entry.OrderID] = ns.String
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating order statuses: %w", err)
+	}
+
+	found = make([]models.OrderStatusEntry, 0, len(byID))
+	notFound = make([]models.OrderRef, 0)
+	seen := make(map[int64]bool, len(orderIDs))
+	seenMissing := make(map[models.OrderRef]bool)
+	for i, ref := range orderIDs {
+		p := parsed[i]
+		entry, ok := byID[p.id]
+		if ok && p.ns != "" && p.ns != namespaces[p.id] {
+			ok = false
+		}
+		switch {
+		case ok && !seen[p.id]:
+			seen[p.id] = true
+			found = append(found, entry)
+		case !ok && !seenMissing[ref]:
+			seenMissing[ref] = true
+			notFound = append(notFound, ref)
+		}
+	}
+	return found, notFound, nil
+}
+
+// TradeFilter selects trades for QueryTrades. Zero-valued fields do not filter.
+type TradeFilter struct {
+	Symbol      string
+	MinQuantity *decimal.Decimal // only trades with quantity >= MinQuantity
+	Limit       int              // 0 => no limit
+	// IncludeClientIDs fills in each trade's buy and sell client order IDs.
+	IncludeClientIDs bool
+}
+
 // GetTrades returns recent trades for a symbol (limit 0 => no limit).
 func (e *Engine) GetTrades(symbol string, limit int) ([]models.Trade, error) {
+	return e.QueryTrades(TradeFilter{Symbol: symbol, Limit: limit})
+}
+
+// GetBlockTrades returns recent trades for a symbol with quantity of at least
+// minQty (limit 0 => no limit).
+func (e *Engine) GetBlockTrades(symbol string, minQty decimal.Decimal, limit int) ([]models.Trade, error) {
+	return e.QueryTrades(TradeFilter{Symbol: symbol, MinQuantity: &minQty, Limit: limit})
+}
+
+// QueryTrades returns trades matching filter, most recent first.
+func (e *Engine) QueryTrades(filter TradeFilter) ([]models.Trade, error) {
+	// With client IDs the orders table is joined in, so trade columns are
+	// qualified.
+	qual, from := "", e.tradeTable(filter.Symbol)+" t"
+	if filter.IncludeClientIDs {
+		qual = "t."
+		from += `
+		LEFT JOIN orders bo ON bo.id = t.buy_order_id
+		LEFT JOIN orders so ON so.id = t.sell_order_id`
+	}
+
+	conditions := []string{qual + "symbol = ?"}
+	args := []interface{}{filter.Symbol}
+	if filter.MinQuantity != nil {
+		conditions = append(conditions, qual+"quantity >= ?")
+		args = append(args, *filter.MinQuantity)
+	}
+
+	columns := qual + "id, " + qual + "symbol, " + qual + "buy_order_id, " + qual + "sell_order_id, " +
+		qual + "buy_global_order_id, " + qual + "sell_global_order_id, " +
+		qual + "price, " + qual + "quantity, " + qual + "executed_at"
+	if filter.IncludeClientIDs {
+		columns += ", bo.client_order_id, so.client_order_id"
+	}
 	query := `
-		SELECT id, symbol, buy_order_id, sell_order_id, price, quantity, executed_at 
-		FROM trades 
-		WHERE symbol = ? 
-		ORDER BY executed_at DESC, id DESC
+		SELECT ` + columns + ` 
+		FROM ` + from + ` 
+		WHERE ` + strings.Join(conditions, " AND ") + ` 
+		ORDER BY ` + qual + `executed_at DESC, ` + qual + `id DESC
 	`
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", limit)
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
 	}
 
-	rows, err := e.db.Query(query, symbol)
+	rows, err := e.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query trades: %w", err)
 	}
@@ -323,87 +1096,247 @@ func (e *Engine) GetTrades(symbol string, limit int) ([]models.Trade, error) {
 	var trades []models.Trade
 	for rows.Next() {
 		var t models.Trade
-		if err := rows.Scan(
+		var buyGlobalID, sellGlobalID sql.NullString
+		dest := []interface{}{
 			&t.ID,
 			&t.Symbol,
 			&t.BuyOrderID,
 			&t.SellOrderID,
+			&buyGlobalID,
+			&sellGlobalID,
 			&t.Price,
 			&t.Quantity,
 			&t.ExecutedAt,
-		); err != nil {
+		}
+		var buyClientID, sellClientID sql.NullString
+		if filter.IncludeClientIDs {
+			dest = append(dest, &buyClientID, &sellClientID)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan trade: %w", err)
 		}
+		t.BuyGlobalOrderID, t.SellGlobalOrderID = buyGlobalID.String, sellGlobalID.String
+		if buyClientID.Valid {
+			t.BuyClientOrderID = &buyClientID.String
+		}
+		if sellClientID.Valid {
+			t.SellClientOrderID = &sellClientID.String
+		}
 		trades = append(trades, t)
 	}
 	return trades, nil
 }
 
+// GetLastPrice returns the most recent trade price for symbol. ok is false
+// when the symbol has never traded.
+func (e *Engine) GetLastPrice(symbol string) (price decimal.Decimal, ok bool, err error) {
+	e.lastPriceMutex.RLock()
+	price, ok = e.lastPrices[symbol]
+	e.lastPriceMutex.RUnlock()
+	if ok {
+		return price, true, nil
+	}
+
+	err = e.db.QueryRow(`
+		SELECT price FROM `+e.tradeTable(symbol)+` t
+		WHERE symbol = ?
+		ORDER BY executed_at DESC, id DESC
+		LIMIT 1
+	`, symbol).Scan(&price)
+	if err == sql.ErrNoRows {
+		return decimal.Zero, false, nil
+	}
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("failed to query last price: %w", err)
+	}
+	e.setLastPriceIfUnset(symbol, price)
+	return price, true, nil
+}
+
+// setLastPrice records price as the latest trade price for symbol.
+func (e *Engine) setLastPrice(symbol string, price decimal.Decimal) {
+	e.lastPriceMutex.Lock()
+	e.lastPrices[symbol] = price
+	e.lastPriceMutex.Unlock()
+}
+
+// setLastPriceIfUnset caches a price read from the DB without overwriting a
+// newer one recorded by a placement in the meantime.
+func (e *Engine) setLastPriceIfUnset(symbol string, price decimal.Decimal) {
+	e.lastPriceMutex.Lock()
+	if _, ok := e.lastPrices[symbol]; !ok {
+		e.lastPrices[symbol] = price
+	}
+	e.lastPriceMutex.Unlock()
+}
+
+// MaxRecentCancels caps the number of orders returned by GetRecentCancels.
+const MaxRecentCancels = 500
+
+// GetRecentCancels returns the most recently canceled orders for a symbol,
+// latest first. limit is clamped to 1..MaxRecentCancels, with 0 meaning the max.
+func (e *Engine) GetRecentCancels(symbol string, limit int) ([]models.Order, error) {
+	if limit <= 0 || limit > MaxRecentCancels {
+		limit = MaxRecentCancels
+	}
+
+	rows, err := e.db.Query(`
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE symbol = ? AND status = 'canceled'
+		ORDER BY updated_at DESC, id DESC
+		LIMIT ?
+	`, symbol, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query canceled orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]models.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating canceled orders: %w", err)
+	}
+	return orders, nil
+}
+
 // GetOrderBook returns aggregated top levels for a symbol.
 func (e *Engine) GetOrderBook(symbol string, depth int) (bids []PriceLevel, asks []PriceLevel) {
 	ob := e.getOrderBook(symbol)
 	return ob.GetTopLevels(depth)
 }
 
-// GetOrderBookWithQuantities returns aggregated levels with total quantities.
+// GetOrderBookWithQuantities returns aggregated levels with total quantities,
+// rounded to the symbol's display_precision when one is configured.
 func (e *Engine) GetOrderBookWithQuantities(symbol string, depth int) ([]models.OrderBookLevel, []models.OrderBookLevel) {
-	ob := e.getOrderBook(symbol)
-	bidLevels, askLevels := ob.GetTopLevels(depth)
+	bids, asks := e.getOrderBook(symbol).GetAggregatedLevels(depth)
+	return e.displayLevels(symbol, bids, asks)
+}
 
-	bids := make([]models.OrderBookLevel, len(bidLevels))
-	for i, lvl := range bidLevels {
-		ob.mutex.RLock()
-		pl := ob.Bids[lvl.Price.String()]
-		total := decimal.Zero
-		if pl != nil {
-			total = pl.GetTotalQuantity()
-		}
-		ob.mutex.RUnlock()
-		bids[i] = models.OrderBookLevel{Price: lvl.Price, Quantity: total}
+// GetOrderBookWithCounts is GetOrderBookWithQuantities with the number of
+// resting orders reported on each level.
+func (e *Engine) GetOrderBookWithCounts(symbol string, depth int) ([]models.OrderBookLevel, []models.OrderBookLevel) {
+	bids, asks := e.getOrderBook(symbol).GetAggregatedLevelsWithCounts(depth)
+	return e.displayLevels(symbol, bids, asks)
+}
+
+// GetOrderBookDetailed is GetOrderBookWithQuantities with the level fields
+// selected by detail.
+func (e *Engine) GetOrderBookDetailed(symbol string, depth int, detail LevelDetail) ([]models.OrderBookLevel, []models.OrderBookLevel) {
+	bids, asks := e.getOrderBook(symbol).GetAggregatedLevelsDetailed(depth, detail)
+	return e.displayLevels(symbol, bids, asks)
+}
+
+// displayLevels applies the symbol's display_precision to aggregated levels.
+func (e *Engine) displayLevels(symbol string, bids, asks []models.OrderBookLevel) ([]models.OrderBookLevel, []models.OrderBookLevel) {
+	if sc, ok := e.config.symbolConfig(symbol); ok && sc.DisplayPrecision != nil {
+		roundLevelsForDisplay(bids, *sc.DisplayPrecision)
+		roundLevelsForDisplay(asks, *sc.DisplayPrecision)
 	}
+	return bids, asks
+}
 
-	asks := make([]models.OrderBookLevel, len(askLevels))
-	for i, lvl := range askLevels {
-		ob.mutex.RLock()
-		pl := ob.Asks[lvl.Price.String()]
-		total := decimal.Zero
-		if pl != nil {
-			total = pl.GetTotalQuantity()
+// roundLevelsForDisplay rounds level quantities down to places decimals so a
+// level never shows more than can be filled. A quantity below one display
+// unit rounds up to that unit instead, so a live level is never shown as 0.
+func roundLevelsForDisplay(levels []models.OrderBookLevel, places int32) {
+	for i := range levels {
+		q := levels[i].Quantity
+		rounded := q.RoundDown(places)
+		if rounded.IsZero() && q.IsPositive() {
+			rounded = q.RoundUp(places)
 		}
-		ob.mutex.RUnlock()
-		asks[i] = models.OrderBookLevel{Price: lvl.Price, Quantity: total}
+		levels[i].Quantity = rounded
 	}
+}
 
-	return bids, asks
+// CancelExpectation is what a cancel request expects of the order it names.
+// Empty fields are not checked.
+type CancelExpectation struct {
+	Symbol string
+	Side   models.OrderSide
+}
+
+// check returns ErrCancelMismatch unless order meets x.
+func (x CancelExpectation) check(order *models.Order) error {
+	if (x.Symbol != "" && x.Symbol != order.Symbol) || (x.Side != "" && x.Side != order.Side) {
+		return fmt.Errorf("%w: order %d is %s %s", ErrCancelMismatch, order.ID, order.Side, order.Symbol)
+	}
+	return nil
 }
 
 // CancelOrder cancels an open or partially filled order safely:
 // - re-checks status inside a DB transaction to avoid races
 // - updates DB, removes from in-memory book and commits
 func (e *Engine) CancelOrder(orderID int64) (*models.Order, error) {
+	return e.CancelOrderExpecting(orderID, CancelExpectation{})
+}
+
+// CancelOrderExpecting is CancelOrder that first verifies the order against
+// expect. With cancel_requires_symbol set, expect must name the symbol.
+func (e *Engine) CancelOrderExpecting(orderID int64, expect CancelExpectation) (*models.Order, error) {
+	if e.config.CancelRequiresSymbol && expect.Symbol == "" {
+		return nil, ErrCancelSymbolRequired
+	}
 	order, err := e.GetOrder(orderID)
 	if err != nil {
 		return nil, err
 	}
-
-	if order.Status == models.OrderStatusFilled {
-		return nil, fmt.Errorf("order already filled")
-	}
-	if order.Status == models.OrderStatusCanceled {
-		return nil, fmt.Errorf("order already canceled")
+	if err := expect.check(order); err != nil {
+		return nil, err
 	}
-	if order.RemainingQuantity.IsZero() {
-		return nil, fmt.Errorf("order has no remaining quantity")
+	if err := checkCancelable(order.Status, order.RemainingQuantity); err != nil {
+		return nil, err
 	}
 
+	defer e.trackSymbolWork(order.Symbol)()
+
 	// Per-symbol lock for atomicity.
 	symMtx := e.getSymbolMutex(order.Symbol)
 	symMtx.Lock()
 	defer symMtx.Unlock()
 
+	var now time.Time
+	var lastSeq int64
+	err = e.retryTx(func() error {
+		var err error
+		now, lastSeq, err = e.cancelAttempt(orderID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove from in-memory book if present. The symbol lock keeps the book
+	// in step with the committed cancel.
+	ob := e.getOrderBook(order.Symbol)
+	if order.Price != nil {
+		ob.RemoveOrder(orderID, order.Side, order.Price)
+	}
+	e.bookEventsCommitted(order.Symbol, lastSeq)
+
+	order.RemainingQuantity = decimal.Zero
+	order.Status = models.OrderStatusCanceled
+	order.UpdatedAt = now
+	e.cacheCompletedOrder(order)
+	e.recordBookChange(order.Symbol, ob, []*models.Order{order})
+	e.publishAccountCancel(order)
+	return order, nil
+}
+
+// cancelAttempt runs one transaction of CancelOrderExpecting: it re-checks
+// the order's status and marks it canceled, returning the cancel time and
+// the Seq of its book event (0 without the event log).
+func (e *Engine) cancelAttempt(orderID int64) (time.Time, int64, error) {
 	tx, err := e.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return time.Time{}, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() {
 		if r := recover(); r != nil {
@@ -413,70 +1346,203 @@ func (e *Engine) CancelOrder(orderID int64) (*models.Order, error) {
 	}()
 
 	// Re-check status inside transaction to avoid races.
-	row := tx.Stmt(e.selectOrderStmt).QueryRow(orderID)
-	var clientOrderID sql.NullString
-	var price sql.NullString
-	var temp models.Order
-	var currentStatus string
-	var currentRemainingQty decimal.Decimal
-
-	if err := row.Scan(
-		&temp.ID,
-		&clientOrderID,
-		&temp.Symbol,
-		&temp.Side,
-		&temp.Type,
-		&price,
-		&temp.InitialQuantity,
-		&currentRemainingQty,
-		&currentStatus,
-		&temp.CreatedAt,
-		&temp.UpdatedAt,
-	); err != nil {
+	current, err := scanOrder(tx.Stmt(e.selectOrderStmt).QueryRow(orderID))
+	if err != nil {
 		tx.Rollback()
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("order not found")
+			return time.Time{}, 0, fmt.Errorf("order not found")
 		}
-		return nil, fmt.Errorf("failed to re-check order status: %w", err)
+		return time.Time{}, 0, fmt.Errorf("failed to re-check order status: %w", err)
 	}
 
-	if currentStatus == string(models.OrderStatusFilled) || currentStatus == string(models.OrderStatusCanceled) {
+	if current.Status == models.OrderStatusFilled || current.Status == models.OrderStatusCanceled {
 		tx.Rollback()
-		return nil, fmt.Errorf("order cannot be canceled, current status: %s", currentStatus)
+		return time.Time{}, 0, fmt.Errorf("order cannot be canceled, current status: %s", current.Status)
 	}
-	if currentRemainingQty.IsZero() {
+	if current.RemainingQuantity.IsZero() {
 		tx.Rollback()
-		return nil, fmt.Errorf("order has no remaining quantity")
+		return time.Time{}, 0, fmt.Errorf("order has no remaining quantity")
 	}
 
 	now := time.Now()
 	if _, err := tx.Stmt(e.updateOrderStmt).Exec(decimal.Zero, models.OrderStatusCanceled, now, orderID); err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("failed to update order status: %w", err)
+		return time.Time{}, 0, fmt.Errorf("failed to update order status: %w", err)
+	}
+	lastSeq, err := e.logBookEvents(tx, current.Symbol, []bookEvent{{OrderID: orderID}})
+	if err != nil {
+		tx.Rollback()
+		return time.Time{}, 0, err
 	}
 
-	// Remove from in-memory book if present.
-	ob := e.getOrderBook(order.Symbol)
-	if order.Price != nil {
-		ob.RemoveOrder(orderID, order.Side, order.Price)
+	if err := e.commit(tx); err != nil {
+		return time.Time{}, 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	return now, lastSeq, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+// checkCancelable returns why an order with this status and remaining
+// quantity cannot be canceled, or nil if it can.
+func checkCancelable(status models.OrderStatus, remaining decimal.Decimal) error {
+	switch {
+	case status == models.OrderStatusFilled:
+		return fmt.Errorf("order already filled")
+	case status == models.OrderStatusCanceled:
+		return fmt.Errorf("order already canceled")
+	case remaining.IsZero():
+		return fmt.Errorf("order has no remaining quantity")
 	}
+	return nil
+}
 
-	order.RemainingQuantity = decimal.Zero
-	order.Status = models.OrderStatusCanceled
-	order.UpdatedAt = now
-	return order, nil
+// IsCancelable reports whether CancelOrder would currently accept orderID
+// and, if not, why. It reads only the status and remaining quantity. The
+// answer can go stale as soon as it is returned, e.g. if the order fills.
+func (e *Engine) IsCancelable(orderID int64) (bool, string, error) {
+	if e.completedOrders != nil {
+		if order, ok := e.completedOrders.Get(orderID); ok {
+			if err := checkCancelable(order.Status, order.RemainingQuantity); err != nil {
+				return false, err.Error(), nil
+			}
+		}
+	}
+
+	var status models.OrderStatus
+	var remaining decimal.Decimal
+	err := e.db.QueryRow(`
+		SELECT status, remaining_quantity FROM orders WHERE id = ?
+	`, orderID).Scan(&status, &remaining)
+	if err == sql.ErrNoRows {
+		return false, "", fmt.Errorf("order not found")
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("failed to query order status: %w", err)
+	}
+
+	if err := checkCancelable(status, remaining); err != nil {
+		return false, err.Error(), nil
+	}
+	return true, "", nil
+}
+
+// CancelBatchSize is the number of orders CancelOrdersBefore cancels per
+// transaction.
+const CancelBatchSize = 500
+
+// CancelOrdersBefore cancels every resting order created before cutoff on
+// symbol, or on all symbols when symbol is empty, and returns how many were
+// canceled. Orders are canceled in transactions of up to CancelBatchSize,
+// each under the symbol lock, so placements interleave between batches.
+// Orders canceled by earlier batches stay canceled if a later batch fails.
+func (e *Engine) CancelOrdersBefore(symbol string, cutoff time.Time) (int, error) {
+	symbols := []string{symbol}
+	if symbol == "" {
+		e.globalMutex.RLock()
+		symbols = make([]string, 0, len(e.orderBooks))
+		for s := range e.orderBooks {
+			symbols = append(symbols, s)
+		}
+		e.globalMutex.RUnlock()
+	}
+
+	canceled := 0
+	for _, s := range symbols {
+		for {
+			n, err := e.cancelBatchBefore(s, cutoff)
+			canceled += n
+			if err != nil {
+				return canceled, err
+			}
+			if n < CancelBatchSize {
+				break
+			}
+		}
+	}
+	return canceled, nil
+}
+
+// cancelBatchBefore cancels up to CancelBatchSize resting orders on symbol
+// created before cutoff in one transaction.
+func (e *Engine) cancelBatchBefore(symbol string, cutoff time.Time) (int, error) {
+	defer e.trackSymbolWork(symbol)()
+
+	symMtx := e.getSymbolMutex(symbol)
+	symMtx.Lock()
+	defer symMtx.Unlock()
+
+	ob := e.getOrderBook(symbol)
+	orders := ob.ordersCreatedBefore(cutoff, CancelBatchSize)
+	if len(orders) == 0 {
+		return 0, nil
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	now := time.Now()
+	stmt := tx.Stmt(e.updateOrderStmt)
+	events := make([]bookEvent, len(orders))
+	for i, order := range orders {
+		if _, err := stmt.Exec(decimal.Zero, models.OrderStatusCanceled, now, order.ID); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to cancel order %d: %w", order.ID, err)
+		}
+		events[i] = bookEvent{OrderID: order.ID}
+	}
+	lastSeq, err := e.logBookEvents(tx, symbol, events)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := e.commit(tx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	canceled := make([]*models.Order, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		ob.RemoveOrder(order.ID, order.Side, order.Price)
+		order.RemainingQuantity = decimal.Zero
+		order.Status = models.OrderStatusCanceled
+		order.UpdatedAt = now
+		e.cacheCompletedOrder(order)
+		e.publishAccountCancel(order)
+		canceled[i] = order
+	}
+	e.bookEventsCommitted(symbol, lastSeq)
+	e.recordBookChange(symbol, ob, canceled)
+	return len(orders), nil
 }
 
 // LoadOpenOrders loads open and partially filled orders from DB and restores in-memory book.
+// With the event log enabled, books are replayed from their latest snapshot
+// and the events after it instead; if nothing has been logged yet they are
+// loaded from the orders table and snapshotted to start the log.
+// A restored book that is crossed is then handled per crossed_book_recovery.
 // Call during startup to rebuild state.
 func (e *Engine) LoadOpenOrders() error {
+	if e.eventLog != nil {
+		loaded, ok, err := e.loadBooksFromEventLog()
+		if err != nil {
+			return err
+		}
+		if ok {
+			fmt.Printf("Loaded %d open orders into order books from the event log\n", loaded)
+			e.resolveCrossedBooks()
+			return nil
+		}
+	}
+
 	query := `
-		SELECT id, client_order_id, symbol, side, type, price, 
-		       initial_quantity, remaining_quantity, status, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders 
 		WHERE status IN ('open', 'partially_filled') 
 		ORDER BY created_at ASC, id ASC
@@ -490,41 +1556,15 @@ func (e *Engine) LoadOpenOrders() error {
 
 	loaded := 0
 	for rows.Next() {
-		var order models.Order
-		var clientOrderID sql.NullString
-		var price sql.NullString
-
-		if err := rows.Scan(
-			&order.ID,
-			&clientOrderID,
-			&order.Symbol,
-			&order.Side,
-			&order.Type,
-			&price,
-			&order.InitialQuantity,
-			&order.RemainingQuantity,
-			&order.Status,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-		); err != nil {
-			return fmt.Errorf("failed to scan order: %w", err)
-		}
-
-		if clientOrderID.Valid {
-			order.ClientOrderID = &clientOrderID.String
-		}
-		if price.Valid {
-			pd, err := decimal.NewFromString(price.String)
-			if err != nil {
-				return fmt.Errorf("failed to parse price for order %d: %w", order.ID, err)
-			}
-			order.Price = &pd
+		order, err := scanOrder(rows)
+		if err != nil {
+			return err
 		}
 
 		// Only limit orders are stored in the in-memory book.
 		if order.Type == models.OrderTypeLimit && order.Price != nil {
 			ob := e.getOrderBook(order.Symbol)
-			ob.AddOrder(&order)
+			ob.AddOrder(order)
 			loaded++
 		}
 	}
@@ -534,5 +1574,11 @@ func (e *Engine) LoadOpenOrders() error {
 	}
 
 	fmt.Printf("Loaded %d open orders into order books\n", loaded)
+	if e.eventLog != nil {
+		if err := e.snapshotAllBooks(); err != nil {
+			return err
+		}
+	}
+	e.resolveCrossedBooks()
 	return nil
 }
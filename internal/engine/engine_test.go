@@ -0,0 +1,877 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitRequest builds a limit order request for tests.
+func limitRequest(symbol string, side models.OrderSide, price, quantity float64) *models.CreateOrderRequest {
+	p := decimal.NewFromFloat(price)
+	return &models.CreateOrderRequest{
+		Symbol:   symbol,
+		Side:     side,
+		Type:     models.OrderTypeLimit,
+		Price:    &p,
+		Quantity: decimal.NewFromFloat(quantity),
+	}
+}
+
+// marketRequest builds a market order request for tests.
+func marketRequest(symbol string, side models.OrderSide, quantity float64) *models.CreateOrderRequest {
+	return &models.CreateOrderRequest{
+		Symbol:   symbol,
+		Side:     side,
+		Type:     models.OrderTypeMarket,
+		Quantity: decimal.NewFromFloat(quantity),
+	}
+}
+
+// TestEngine_CompletedOrderServedFromCache verifies a just-filled resting order is
+// returned by GetOrder from the in-memory cache without querying the DB.
+func TestEngine_CompletedOrderServedFromCache(t *testing.T) {
+	eng, fdb := newFakeEngine(t, Config{CompletedOrderCacheSize: 10})
+
+	sell, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1.0))
+	require.NoError(t, err)
+	require.Equal(t, models.OrderStatusOpen, sell.Status)
+
+	_, trades, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1.0))
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+
+	got, err := eng.GetOrder(sell.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sell.ID, got.ID)
+	assert.Equal(t, models.OrderStatusFilled, got.Status)
+	assert.True(t, got.RemainingQuantity.IsZero())
+	assert.Empty(t, fdb.QueriesMatching("FROM orders"), "GetOrder should not hit the DB for a cached order")
+
+	// An order still resting is not cached and falls through to the DB.
+	resting, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 51000, 1.0))
+	require.NoError(t, err)
+	_, err = eng.GetOrder(resting.ID)
+	assert.Error(t, err, "fake DB has no rows for uncached orders")
+	assert.Len(t, fdb.QueriesMatching("FROM orders"), 1)
+}
+
+// TestOrderCache_EvictsLeastRecentlyUsed checks the cache stays within its bound.
+func TestOrderCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newOrderCache(2)
+	cache.Put(&models.Order{ID: 1, Status: models.OrderStatusFilled})
+	cache.Put(&models.Order{ID: 2, Status: models.OrderStatusFilled})
+
+	_, ok := cache.Get(1) // 1 becomes most recently used
+	require.True(t, ok)
+
+	cache.Put(&models.Order{ID: 3, Status: models.OrderStatusCanceled})
+
+	assert.Equal(t, 2, cache.Len())
+	_, ok = cache.Get(2)
+	assert.False(t, ok, "order 2 should have been evicted")
+	_, ok = cache.Get(1)
+	assert.True(t, ok)
+	_, ok = cache.Get(3)
+	assert.True(t, ok)
+}
+
+// TestEngine_LoadOpenOrdersRestoresTierPriority verifies recovery rebuilds the
+// tier-aware queue from rows returned in created_at order.
+func TestEngine_LoadOpenOrdersRestoresTierPriority(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	base := time.Now().Add(-time.Hour)
+	row := func(id int64, tier int64, created time.Time) []driver.Value {
+		return []driver.Value{id, nil, nil, "BTCUSD", "sell", "limit", "50000", "1", "1", "open", tier, created, created, nil, nil, nil}
+	}
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "status IN ('open', 'partially_filled')") {
+			return nil, nil
+		}
+		return &fakeRows{
+			Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+			Rows: [][]driver.Value{
+				row(1, 0, base),
+				row(2, 1, base.Add(time.Minute)),
+				row(3, 0, base.Add(2*time.Minute)),
+			},
+		}, nil
+	}
+
+	require.NoError(t, eng.LoadOpenOrders())
+
+	level := eng.getOrderBook("BTCUSD").Asks["50000"]
+	require.NotNil(t, level)
+	require.Len(t, level.Orders, 3)
+	assert.Equal(t, int64(2), level.Orders[0].ID, "tier 1 order should be restored at the head")
+	assert.Equal(t, int64(1), level.Orders[1].ID)
+	assert.Equal(t, int64(3), level.Orders[2].ID)
+}
+
+// TestEngine_StrictSymbols checks a never-before-seen symbol is rejected with
+// ErrUnknownSymbol in strict mode and auto-created otherwise.
+func TestEngine_StrictSymbols(t *testing.T) {
+	t.Run("strict rejects unknown symbol", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.StrictSymbols = true
+		cfg.Symbols = map[string]SymbolConfig{"BTCUSD": {}}
+		eng, fdb := newFakeEngine(t, cfg)
+
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSDD", models.OrderSideBuy, 50000, 1))
+		assert.ErrorIs(t, err, ErrUnknownSymbol)
+		assert.Empty(t, fdb.ExecsMatching("INSERT INTO orders"), "rejected order must not be written")
+
+		_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1))
+		assert.NoError(t, err, "registered symbol should be accepted")
+	})
+
+	t.Run("non-strict auto-creates book", func(t *testing.T) {
+		eng, _ := newFakeEngine(t, DefaultConfig())
+
+		order, _, err := eng.PlaceOrder(limitRequest("NEWCOIN", models.OrderSideBuy, 1.5, 10))
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderStatusOpen, order.Status)
+
+		bids, _ := eng.GetOrderBookWithQuantities("NEWCOIN", 5)
+		assert.Len(t, bids, 1)
+	})
+}
+
+// TestEngine_PlaceOrderIncludeBook checks the pre-trade snapshot shows the book
+// as the order found it and the post-trade snapshot shows its impact.
+func TestEngine_PlaceOrderIncludeBook(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1.0))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 1.0))
+	require.NoError(t, err)
+
+	// Takes the whole 50000 ask and rests 0.5 at 50050.
+	req := limitRequest("BTCUSD", models.OrderSideBuy, 50050, 1.5)
+	req.IncludeBook = true
+	req.BookDepth = 1
+	p, err := eng.PlaceOrderDetailed(req)
+	require.NoError(t, err)
+	require.NotNil(t, p.BookBefore)
+	require.NotNil(t, p.BookAfter)
+
+	assert.Empty(t, p.BookBefore.Bids)
+	require.Len(t, p.BookBefore.Asks, 1, "depth should be capped at book_depth")
+	assert.True(t, decimal.NewFromInt(50000).Equal(p.BookBefore.Asks[0].Price))
+	assert.True(t, decimal.NewFromFloat(1.0).Equal(p.BookBefore.Asks[0].Quantity))
+
+	require.Len(t, p.BookAfter.Bids, 1)
+	assert.True(t, decimal.NewFromInt(50050).Equal(p.BookAfter.Bids[0].Price))
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(p.BookAfter.Bids[0].Quantity))
+	require.Len(t, p.BookAfter.Asks, 1)
+	assert.True(t, decimal.NewFromInt(50100).Equal(p.BookAfter.Asks[0].Price))
+
+	// Without include_book no snapshots are taken.
+	p, err = eng.PlaceOrderDetailed(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1.0))
+	require.NoError(t, err)
+	assert.Nil(t, p.BookBefore)
+	assert.Nil(t, p.BookAfter)
+}
+
+// TestEngine_RejectsExpiredOrders checks stale orders are rejected before any
+// DB work, and that the clock skew tolerance extends client deadlines.
+func TestEngine_RejectsExpiredOrders(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	past := time.Now().Add(-time.Second)
+	req := limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1.0)
+	req.ValidUntil = &past
+	_, _, err := eng.PlaceOrder(req)
+	require.ErrorIs(t, err, ErrExpired)
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO orders"), "expired order must not be stored")
+
+	sentAt := time.Now().Add(-time.Second)
+	req = limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1.0)
+	req.SentAt = &sentAt
+	req.MaxLatencyMs = 100
+	_, _, err = eng.PlaceOrder(req)
+	require.ErrorIs(t, err, ErrExpired)
+
+	cfg := DefaultConfig()
+	cfg.ClockSkewTolerance = Duration{5 * time.Second}
+	tolerant, _ := newFakeEngine(t, cfg)
+	req = limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1.0)
+	req.ValidUntil = &past
+	_, _, err = tolerant.PlaceOrder(req)
+	require.NoError(t, err, "deadline within the skew tolerance should be accepted")
+}
+
+// TestEngine_DisplayPrecisionRoundsOnlyBookResponse checks display_precision
+// rounds aggregated quantities in the book response while matching and the
+// internal book keep full precision.
+func TestEngine_DisplayPrecisionRoundsOnlyBookResponse(t *testing.T) {
+	precision := int32(2)
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{"BTCUSD": {DisplayPrecision: &precision}}
+	eng, _ := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 0.123456))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 0.5))
+	require.NoError(t, err)
+
+	_, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	require.Len(t, asks, 2)
+	assert.Equal(t, "0.12", asks[0].Quantity.String(), "rounded down, never overstating liquidity")
+
+	_, trades, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 0.123))
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, "0.123", trades[0].Quantity.String(), "matching uses full precision")
+
+	_, internal := eng.getOrderBook("BTCUSD").GetAggregatedLevels(10)
+	assert.Equal(t, "0.000456", internal[0].Quantity.String())
+
+	_, asks = eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	require.Len(t, asks, 2)
+	assert.Equal(t, "0.01", asks[0].Quantity.String(), "a live sub-unit level must not display as 0")
+	assert.Equal(t, "0.5", asks[1].Quantity.String())
+
+	// Symbols without display_precision are unrounded.
+	_, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideSell, 3000, 0.123456))
+	require.NoError(t, err)
+	_, asks = eng.GetOrderBookWithQuantities("ETHUSD", 10)
+	require.Len(t, asks, 1)
+	assert.Equal(t, "0.123456", asks[0].Quantity.String())
+}
+
+// TestEngine_MarketRemainderRestsAsLimit sweeps the book with a market buy
+// under the symbol's "rest" mode and expects the remainder to rest as a
+// limit at the last fill price, both in the book and in the DB.
+func TestEngine_MarketRemainderRestsAsLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{"BTCUSD": {MarketRemainder: models.MarketRemainderRest}}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 0.5))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 0.5))
+	require.NoError(t, err)
+
+	order, trades, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 1.5))
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+
+	assert.Equal(t, models.OrderTypeLimit, order.Type)
+	require.NotNil(t, order.Price)
+	assert.True(t, decimal.NewFromInt(50100).Equal(*order.Price), "rests at the last fill price")
+	assert.Equal(t, models.OrderStatusPartiallyFilled, order.Status)
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(order.RemainingQuantity))
+
+	bids, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Empty(t, asks)
+	require.Len(t, bids, 1)
+	assert.True(t, decimal.NewFromInt(50100).Equal(bids[0].Price))
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(bids[0].Quantity))
+
+	updates := fdb.ExecsMatching("SET type = ?")
+	require.Len(t, updates, 1, "the converted remainder must be persisted")
+	assert.Equal(t, string(models.OrderTypeLimit), updates[0].Args[0])
+	assert.Equal(t, order.ID, updates[0].Args[5])
+
+	// A per-order override restores the default cancel behaviour.
+	req := marketRequest("BTCUSD", models.OrderSideSell, 1.0)
+	req.MarketRemainder = models.MarketRemainderCancel
+	order, _, err = eng.PlaceOrder(req)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	assert.Equal(t, models.OrderTypeMarket, order.Type)
+}
+
+// TestEngine_MaxTickDistance rejects a bid resting too many ticks below the
+// best ask while nearer and marketable orders are accepted.
+func TestEngine_MaxTickDistance(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{
+		"BTCUSD": {TickSize: decimal.NewFromFloat(0.5), MaxTickDistance: 100},
+	}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	// Empty opposing side: nothing to measure against.
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1.0))
+	require.NoError(t, err)
+	inserts := len(fdb.ExecsMatching("INSERT INTO orders"))
+
+	// 50000 - 49900 = 100 = 200 ticks.
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49900, 1.0))
+	require.ErrorIs(t, err, ErrPriceTooFar)
+	assert.Len(t, fdb.ExecsMatching("INSERT INTO orders"), inserts, "rejected order must not be written")
+
+	// Exactly 100 ticks away rests.
+	order, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49950, 1.0))
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusOpen, order.Status)
+
+	// Marketable orders are never rejected.
+	_, trades, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 60000, 0.5))
+	require.NoError(t, err)
+	assert.Len(t, trades, 1)
+
+	// Far asks are measured against the best bid.
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 1.0))
+	require.ErrorIs(t, err, ErrPriceTooFar)
+}
+
+// TestEngine_TraceMarketSweep checks a traced market order sweeping two levels
+// reports a candidate and trade step per fill, in trade order, then the
+// canceled remainder; untraced orders carry no trace.
+func TestEngine_TraceMarketSweep(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+
+	for _, price := range []float64{50000, 50100} {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, price, 0.5))
+		require.NoError(t, err)
+	}
+
+	req := marketRequest("BTCUSD", models.OrderSideBuy, 1.5)
+	req.Trace = true
+	p, err := eng.PlaceOrderDetailed(req)
+	require.NoError(t, err)
+	require.Len(t, p.Trades, 2)
+	require.Len(t, p.Trace, 6)
+
+	for i, trade := range p.Trades {
+		candidate, fill := p.Trace[2*i], p.Trace[2*i+1]
+		assert.Equal(t, models.TraceCandidate, candidate.Action)
+		assert.Equal(t, models.TraceTrade, fill.Action)
+		for _, step := range []models.MatchTraceStep{candidate, fill} {
+			assert.Equal(t, trade.SellOrderID, step.RestingOrderID)
+			require.NotNil(t, step.Price)
+			assert.True(t, trade.Price.Equal(*step.Price))
+		}
+		require.NotNil(t, fill.Quantity)
+		assert.True(t, trade.Quantity.Equal(*fill.Quantity))
+	}
+	assert.Equal(t, models.TraceStop, p.Trace[4].Action)
+	assert.Equal(t, "opposite side empty", p.Trace[4].Reason)
+	assert.Equal(t, models.TraceCancel, p.Trace[5].Action)
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(*p.Trace[5].Quantity))
+	for i, step := range p.Trace {
+		assert.Equal(t, i+1, step.Step)
+	}
+
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 0.5))
+	require.NoError(t, err)
+	p, err = eng.PlaceOrderDetailed(marketRequest("BTCUSD", models.OrderSideBuy, 0.5))
+	require.NoError(t, err)
+	assert.Nil(t, p.Trace)
+}
+
+// TestEngine_OrderBookCounts checks each level reports the number of orders
+// resting there only when counts are requested.
+func TestEngine_OrderBookCounts(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+
+	for _, o := range []struct {
+		side  models.OrderSide
+		price float64
+	}{
+		{models.OrderSideBuy, 49900}, {models.OrderSideBuy, 49900}, {models.OrderSideBuy, 49800},
+		{models.OrderSideSell, 50100}, {models.OrderSideSell, 50100}, {models.OrderSideSell, 50100},
+	} {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", o.side, o.price, 1))
+		require.NoError(t, err)
+	}
+	// A partial fill leaves the order, and so the count, in place.
+	_, _, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 0.5))
+	require.NoError(t, err)
+
+	bids, asks := eng.GetOrderBookWithCounts("BTCUSD", 10)
+	require.Len(t, bids, 2)
+	require.Len(t, asks, 1)
+	assert.Equal(t, 2, bids[0].OrderCount)
+	assert.Equal(t, 1, bids[1].OrderCount)
+	assert.Equal(t, 3, asks[0].OrderCount)
+	assert.True(t, decimal.NewFromFloat(2.5).Equal(asks[0].Quantity))
+
+	bids, asks = eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Zero(t, bids[0].OrderCount)
+	assert.Zero(t, asks[0].OrderCount)
+}
+
+// TestEngine_BaseUnitAmounts checks a book level serializes to integer base
+// units at the symbol's precision and converts back to the same decimal, and
+// that an order finer than the precision is rejected.
+func TestEngine_BaseUnitAmounts(t *testing.T) {
+	cfg := DefaultConfig()
+	quantityPrecision, pricePrecision := int32(8), int32(2)
+	cfg.Symbols = map[string]SymbolConfig{
+		"BTCUSD": {QuantityPrecision: &quantityPrecision, PricePrecision: &pricePrecision},
+	}
+	eng, _ := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000.25, 1.23456789))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000.25, 0.000000001))
+	assert.ErrorIs(t, err, ErrPrecisionExceeded)
+
+	scale, err := eng.BaseUnitScale("BTCUSD")
+	require.NoError(t, err)
+	_, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	levels, err := models.LevelsInBaseUnits(asks, scale)
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(levels)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"price":5000025,"quantity":123456789}]`, string(encoded))
+
+	var decoded []models.BaseUnitOrderBookLevel
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	quantity, err := models.FromBaseUnits(decoded[0].Quantity, scale.Quantity)
+	require.NoError(t, err)
+	assert.True(t, asks[0].Quantity.Equal(quantity), "round trip gave %s", quantity)
+
+	_, err = eng.BaseUnitScale("ETHUSD")
+	assert.ErrorIs(t, err, ErrBaseUnitsUnavailable)
+}
+
+// TestEngine_QuantityStep checks lot size and quantity step are enforced
+// independently: the step only above its threshold, the lot size always.
+func TestEngine_QuantityStep(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{
+		"BTCUSD": {
+			LotSize:               decimal.RequireFromString("0.01"),
+			QuantityStep:          decimal.RequireFromString("0.5"),
+			QuantityStepThreshold: decimal.NewFromInt(1),
+		},
+	}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	for _, tc := range []struct {
+		quantity float64
+		err      error
+	}{
+		{0.37, nil},                    // below the threshold only lots apply
+		{1, nil},                       // the threshold itself is not above it
+		{2.5, nil},                     // a multiple of both
+		{1.25, ErrInvalidQuantityStep}, // whole lots but not a whole step
+		{0.375, ErrInvalidLotSize},     // below the threshold, not a whole lot
+		{1.505, ErrInvalidLotSize},     // above the threshold lots still apply
+	} {
+		inserts := len(fdb.ExecsMatching("INSERT INTO orders"))
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, tc.quantity))
+		if tc.err == nil {
+			assert.NoError(t, err, "quantity %v", tc.quantity)
+			continue
+		}
+		assert.ErrorIs(t, err, tc.err, "quantity %v", tc.quantity)
+		assert.Len(t, fdb.ExecsMatching("INSERT INTO orders"), inserts)
+	}
+}
+
+// TestEngine_SymbolDefaultTimeInForce checks a limit order without a time in
+// force takes its symbol's IOC default, canceling the unfilled remainder,
+// while an explicit GTC still rests and other symbols keep the GTC default.
+// Only the canceled IOC order is marked as never having rested.
+func TestEngine_SymbolDefaultTimeInForce(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{"BTCUSD": {TimeInForce: models.TimeInForceIOC}}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	ask := limitRequest("BTCUSD", models.OrderSideSell, 50000, 1)
+	ask.TimeInForce = models.TimeInForceGTC
+	_, _, err := eng.PlaceOrder(ask)
+	require.NoError(t, err)
+
+	order, trades, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 3))
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	assert.True(t, order.RemainingQuantity.IsZero())
+	iocID := order.ID
+	bids, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Empty(t, bids)
+	assert.Empty(t, asks)
+
+	gtc := limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1)
+	gtc.TimeInForce = models.TimeInForceGTC
+	order, _, err = eng.PlaceOrder(gtc)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusOpen, order.Status)
+
+	order, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideBuy, 3000, 1))
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusOpen, order.Status)
+
+	marks := fdb.ExecsMatching("SET rested = FALSE")
+	require.Len(t, marks, 1)
+	assert.Equal(t, iocID, marks[0].Args[0])
+}
+
+// TestEngine_MaxTradesPerOrder sweeps a book of many tiny asks and checks
+// each order stops at the trade cap: a market remainder is canceled, a limit
+// remainder that would cross is canceled, and one that would not rests.
+func TestEngine_MaxTradesPerOrder(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxTradesPerOrder = 3
+	eng, _ := newFakeEngine(t, cfg)
+
+	for i := 0; i < 12; i++ {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, float64(50000+i), 0.1))
+		require.NoError(t, err)
+	}
+
+	order, trades, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 1))
+	require.NoError(t, err)
+	assert.Len(t, trades, 3)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+
+	// Still marketable at the cap: resting would cross the book.
+	order, trades, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50011, 1))
+	require.NoError(t, err)
+	assert.Len(t, trades, 3)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+
+	// The asks at 50006..50008 are taken; 50009 is beyond the limit.
+	order, trades, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50008, 1))
+	require.NoError(t, err)
+	assert.Len(t, trades, 3)
+	assert.Equal(t, models.OrderStatusPartiallyFilled, order.Status)
+	assert.True(t, decimal.NewFromFloat(0.7).Equal(order.RemainingQuantity))
+
+	bids, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	require.Len(t, bids, 1)
+	assert.True(t, decimal.NewFromInt(50008).Equal(bids[0].Price))
+	require.Len(t, asks, 3)
+	assert.True(t, decimal.NewFromInt(50009).Equal(asks[0].Price))
+}
+
+// TestEngine_SelfTradePrevention counts self-trade cancels per account in
+// both modes: resting orders of the incoming order's account are canceled
+// and skipped, or the incoming order's remainder is canceled.
+func TestEngine_SelfTradePrevention(t *testing.T) {
+	withAccount := func(req *models.CreateOrderRequest, account string) *models.CreateOrderRequest {
+		req.AccountID = account
+		return req
+	}
+
+	cfg := DefaultConfig()
+	cfg.SelfTradePrevention = models.SelfTradeCancelResting
+	eng, _ := newFakeEngine(t, cfg)
+
+	for _, ask := range []*models.CreateOrderRequest{
+		withAccount(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1), "acct-a"),
+		withAccount(limitRequest("BTCUSD", models.OrderSideSell, 50001, 1), "acct-a"),
+		withAccount(limitRequest("BTCUSD", models.OrderSideSell, 50002, 1), "acct-b"),
+		limitRequest("BTCUSD", models.OrderSideSell, 50003, 1),
+	} {
+		_, _, err := eng.PlaceOrder(ask)
+		require.NoError(t, err)
+	}
+
+	order, trades, err := eng.PlaceOrder(withAccount(limitRequest("BTCUSD", models.OrderSideBuy, 50003, 1.5), "acct-a"))
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+	assert.True(t, decimal.NewFromInt(50002).Equal(trades[0].Price), "own asks are skipped")
+	assert.Equal(t, models.OrderStatusFilled, order.Status)
+
+	// Orders without an account never self-trade.
+	_, trades, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 0.5))
+	require.NoError(t, err)
+	assert.Len(t, trades, 1)
+
+	_, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Empty(t, asks)
+	assert.Equal(t, models.SelfTradeStatsResponse{AccountID: "acct-a", RestingCanceled: 2}, eng.SelfTradeStats("acct-a"))
+	assert.Equal(t, models.SelfTradeStatsResponse{AccountID: "acct-b"}, eng.SelfTradeStats("acct-b"))
+
+	cfg.SelfTradePrevention = models.SelfTradeCancelIncoming
+	eng, _ = newFakeEngine(t, cfg)
+
+	_, _, err = eng.PlaceOrder(withAccount(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1), "acct-b"))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(withAccount(limitRequest("BTCUSD", models.OrderSideBuy, 49999, 1), "acct-a"))
+	require.NoError(t, err)
+
+	// Fills against acct-b, then stops at its own bid.
+	order, trades, err = eng.PlaceOrder(withAccount(limitRequest("BTCUSD", models.OrderSideSell, 49000, 2), "acct-a"))
+	require.NoError(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	assert.True(t, order.RemainingQuantity.IsZero())
+
+	for i := 0; i < 2; i++ {
+		order, trades, err = eng.PlaceOrder(withAccount(marketRequest("BTCUSD", models.OrderSideSell, 1), "acct-a"))
+		require.NoError(t, err)
+		assert.Empty(t, trades)
+		assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	}
+
+	bids, _ := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	require.Len(t, bids, 1, "the resting order is kept")
+	assert.True(t, decimal.NewFromInt(49999).Equal(bids[0].Price))
+	assert.Equal(t, models.SelfTradeStatsResponse{AccountID: "acct-a", IncomingCanceled: 3}, eng.SelfTradeStats("acct-a"))
+}
+
+// TestEngine_ReapIdleBooks checks an emptied book is dropped once idle past
+// the TTL, a book with resting orders is kept, and the reaped symbol is
+// recreated on its next order.
+func TestEngine_ReapIdleBooks(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.IdleBookTTL = Duration{time.Hour}
+	eng, _ := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideSell, 1))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideSell, 3000, 1))
+	require.NoError(t, err)
+
+	assert.Zero(t, eng.reapIdleBooks(time.Now()), "not idle for the TTL yet")
+	assert.Equal(t, 1, eng.reapIdleBooks(time.Now().Add(2*time.Hour)))
+
+	eng.globalMutex.RLock()
+	_, btc := eng.orderBooks["BTCUSD"]
+	_, eth := eng.orderBooks["ETHUSD"]
+	eng.globalMutex.RUnlock()
+	assert.False(t, btc, "empty idle book must be reaped")
+	assert.True(t, eth, "book with resting orders must be kept")
+
+	order, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 2))
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusOpen, order.Status)
+	_, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	require.Len(t, asks, 1)
+	assert.True(t, decimal.NewFromInt(2).Equal(asks[0].Quantity))
+
+	price, ok, err := eng.GetLastPrice("BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, decimal.NewFromInt(50000).Equal(price), "last price survives reaping")
+}
+
+// TestEngine_PriceCollar rejects limit orders priced beyond the collar from
+// the last trade price, on either side, and skips the check before the
+// first trade.
+func TestEngine_PriceCollar(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{
+		"BTCUSD": {PriceCollarPercent: decimal.NewFromInt(10)},
+		"ETHUSD": {PriceCollarPercent: decimal.NewFromInt(10), TickSize: decimal.NewFromInt(1), PriceCollarTicks: 50},
+	}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 90000, 1))
+	require.NoError(t, err, "no last price yet, so no collar")
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 1))
+	require.NoError(t, err)
+
+	inserts := len(fdb.ExecsMatching("INSERT INTO orders"))
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 56000, 1))
+	assert.ErrorIs(t, err, ErrOutsidePriceCollar)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 44000, 1))
+	assert.ErrorIs(t, err, ErrOutsidePriceCollar)
+	assert.Len(t, fdb.ExecsMatching("INSERT INTO orders"), inserts, "rejected orders must not be written")
+
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 55000, 1))
+	assert.NoError(t, err, "exactly on the collar passes")
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 46000, 1))
+	assert.NoError(t, err)
+
+	// With both set the narrower band applies: 50 ticks of 1 is under 10%.
+	_, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideSell, 3000, 1))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(marketRequest("ETHUSD", models.OrderSideBuy, 1))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideBuy, 3060, 1))
+	assert.ErrorIs(t, err, ErrOutsidePriceCollar)
+	_, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideBuy, 3050, 1))
+	assert.NoError(t, err)
+}
+
+// TestEngine_CancelOrdersBefore cancels only orders created before the cutoff,
+// on one symbol or on all symbols.
+func TestEngine_CancelOrdersBefore(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	place := func(symbol string, side models.OrderSide, price float64) *models.Order {
+		order, _, err := eng.PlaceOrder(limitRequest(symbol, side, price, 1))
+		require.NoError(t, err)
+		return order
+	}
+	oldBid := place("BTCUSD", models.OrderSideBuy, 49000)
+	oldAsk := place("BTCUSD", models.OrderSideSell, 51000)
+	oldEth := place("ETHUSD", models.OrderSideBuy, 3000)
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	time.Sleep(time.Millisecond)
+	newBid := place("BTCUSD", models.OrderSideBuy, 49000)
+	place("ETHUSD", models.OrderSideSell, 3100)
+
+	canceled, err := eng.CancelOrdersBefore("BTCUSD", cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 2, canceled)
+
+	ob := eng.getOrderBook("BTCUSD")
+	assert.Nil(t, ob.FindOrder(oldBid.ID))
+	assert.Nil(t, ob.FindOrder(oldAsk.ID))
+	assert.NotNil(t, ob.FindOrder(newBid.ID), "newer order must stay")
+	assert.NotNil(t, eng.getOrderBook("ETHUSD").FindOrder(oldEth.ID), "other symbols untouched")
+
+	updates := fdb.ExecsMatching("SET remaining_quantity = ?")
+	require.Len(t, updates, 2)
+	for _, u := range updates {
+		assert.Equal(t, string(models.OrderStatusCanceled), u.Args[1])
+		assert.Contains(t, []int64{oldBid.ID, oldAsk.ID}, u.Args[3])
+	}
+
+	// Empty symbol sweeps every book; already-canceled orders are not redone.
+	canceled, err = eng.CancelOrdersBefore("", cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, canceled)
+	assert.Nil(t, eng.getOrderBook("ETHUSD").FindOrder(oldEth.ID))
+	bids, asks := eng.GetOrderBookWithQuantities("ETHUSD", 10)
+	assert.Empty(t, bids)
+	assert.Len(t, asks, 1)
+}
+
+// TestEngine_IsCancelable answers from a status-only query with the same
+// rules CancelOrder applies.
+func TestEngine_IsCancelable(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	rows := map[int64][]driver.Value{
+		1: {"open", "1"},
+		2: {"partially_filled", "0.4"},
+		3: {"filled", "0"},
+		4: {"canceled", "0"},
+	}
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "SELECT status, remaining_quantity FROM orders") {
+			return nil, nil
+		}
+		row, ok := rows[args[0].(int64)]
+		if !ok {
+			return nil, nil
+		}
+		return &fakeRows{Cols: []string{"status", "remaining_quantity"}, Rows: [][]driver.Value{row}}, nil
+	}
+
+	for _, tc := range []struct {
+		id         int64
+		cancelable bool
+		reason     string
+	}{
+		{1, true, ""},
+		{2, true, ""},
+		{3, false, "order already filled"},
+		{4, false, "order already canceled"},
+	} {
+		cancelable, reason, err := eng.IsCancelable(tc.id)
+		require.NoError(t, err)
+		assert.Equal(t, tc.cancelable, cancelable, "order %d", tc.id)
+		assert.Equal(t, tc.reason, reason, "order %d", tc.id)
+	}
+
+	_, _, err := eng.IsCancelable(99)
+	assert.ErrorContains(t, err, "not found")
+	assert.Empty(t, fdb.QueriesMatching("SELECT id, client_order_id"), "must not fetch the full order")
+}
+
+// TestEngine_CancelOrderExpecting rejects a cancel naming the wrong symbol or
+// side without touching the order, requires the symbol when configured, and
+// cancels when both match.
+func TestEngine_CancelOrderExpecting(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CancelRequiresSymbol = true
+	eng, fdb := newFakeEngine(t, cfg)
+
+	resting, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "WHERE id = ?") {
+			return nil, nil
+		}
+		created := time.Now()
+		return &fakeRows{
+			Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+			Rows: [][]driver.Value{{resting.ID, nil, nil, "BTCUSD", "sell", "limit", "50000", "1", "1", "open", int64(0), created, created, nil, nil, nil}},
+		}, nil
+	}
+
+	_, err = eng.CancelOrder(resting.ID)
+	assert.ErrorIs(t, err, ErrCancelSymbolRequired)
+	_, err = eng.CancelOrderExpecting(resting.ID, CancelExpectation{Symbol: "ETHUSD"})
+	assert.ErrorIs(t, err, ErrCancelMismatch)
+	_, err = eng.CancelOrderExpecting(resting.ID, CancelExpectation{Symbol: "BTCUSD", Side: models.OrderSideBuy})
+	assert.ErrorIs(t, err, ErrCancelMismatch)
+	assert.Empty(t, fdb.ExecsMatching("UPDATE orders"), "mismatched cancels must not write")
+	_, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Len(t, asks, 1)
+
+	order, err := eng.CancelOrderExpecting(resting.ID, CancelExpectation{Symbol: "BTCUSD", Side: models.OrderSideSell})
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	_, asks = eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Empty(t, asks)
+}
+
+// TestEngine_RejectMarketWithoutLiquidity rejects a market order facing an
+// empty opposing side without writing anything, and still accepts one that
+// can trade.
+func TestEngine_RejectMarketWithoutLiquidity(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RejectMarketWithoutLiquidity = true
+	eng, fdb := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	require.NoError(t, err)
+	execs := len(fdb.ExecsMatching(""))
+
+	_, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 1))
+	assert.ErrorIs(t, err, ErrNoLiquidity)
+	assert.Len(t, fdb.ExecsMatching(""), execs, "a rejected order must not be written")
+
+	order, trades, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideSell, 2))
+	require.NoError(t, err)
+	assert.Len(t, trades, 1)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status, "the unfilled part is canceled as before")
+}
+
+// TestEngine_PlaceOrderQueuePosition reports where a resting remainder
+// landed in its level's queue, and nothing for an order that fully filled.
+func TestEngine_PlaceOrderQueuePosition(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+
+	first, err := eng.PlaceOrderDetailed(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.QueuePosition)
+	assert.True(t, first.QuantityAhead.IsZero())
+	_, err = eng.PlaceOrderDetailed(limitRequest("BTCUSD", models.OrderSideSell, 50000, 2))
+	require.NoError(t, err)
+
+	third, err := eng.PlaceOrderDetailed(limitRequest("BTCUSD", models.OrderSideSell, 50000, 0.5))
+	require.NoError(t, err)
+	assert.Equal(t, 3, third.QueuePosition)
+	assert.Equal(t, "3", third.QuantityAhead.String())
+
+	filled, err := eng.PlaceOrderDetailed(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1))
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusFilled, filled.Order.Status)
+	assert.Zero(t, filled.QueuePosition, "a filled order has no queue position")
+
+	partial, err := eng.PlaceOrderDetailed(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 4))
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPartiallyFilled, partial.Order.Status)
+	assert.Equal(t, 1, partial.QueuePosition, "the remainder rests alone on the bid side")
+	assert.True(t, partial.QuantityAhead.IsZero())
+}
@@ -0,0 +1,95 @@
+package engine
+
+import "errors"
+
+// ErrAutoPaused is returned by PlaceOrder while the commit latency guard has
+// paused order placement.
+var ErrAutoPaused = errors.New("order placement paused: database commit latency too high")
+
+// ErrCapacityExhausted is returned by PlaceOrder while the capacity guard has
+// found the database at or above its configured size or row limit.
+var ErrCapacityExhausted = errors.New("order placement paused: database capacity nearly exhausted")
+
+// ErrInvalidTier is returned by PlaceOrder when the requested priority tier is
+// negative or above the configured maximum.
+var ErrInvalidTier = errors.New("invalid priority tier")
+
+// ErrUnknownSymbol is returned when strict_symbols is enabled and an order
+// names a symbol that is not in the symbols registry.
+var ErrUnknownSymbol = errors.New("unknown_symbol")
+
+// ErrExpired is returned by PlaceOrder when an order arrives after its
+// valid_until time or later than max_latency_ms after sent_at.
+var ErrExpired = errors.New("expired")
+
+// ErrInvalidSymbolState is returned by ImportSymbolState when the bundle
+// fails validation.
+var ErrInvalidSymbolState = errors.New("invalid symbol state")
+
+// ErrSymbolNotEmpty is returned by ImportSymbolState when the target symbol
+// already has resting orders.
+var ErrSymbolNotEmpty = errors.New("symbol already has resting orders")
+
+// ErrPriceTooFar is returned by PlaceOrder when a resting limit order's price
+// is more than the symbol's max_tick_distance ticks from the opposing best.
+var ErrPriceTooFar = errors.New("price too far from market")
+
+// ErrPrecisionExceeded is returned by PlaceOrder when an order's quantity or
+// price has more decimal places than its symbol allows.
+var ErrPrecisionExceeded = errors.New("precision exceeded")
+
+// ErrInvalidLotSize is returned by PlaceOrder when an order's quantity is not
+// a multiple of its symbol's lot size.
+var ErrInvalidLotSize = errors.New("quantity not a multiple of lot size")
+
+// ErrInvalidQuantityStep is returned by PlaceOrder when an order's quantity
+// is above its symbol's step threshold but not a multiple of the step.
+var ErrInvalidQuantityStep = errors.New("quantity not a multiple of quantity step")
+
+// ErrBaseUnitsUnavailable is returned by BaseUnitScale for a symbol without
+// both quantity_precision and price_precision configured.
+var ErrBaseUnitsUnavailable = errors.New("base units not configured for symbol")
+
+// ErrOutsidePriceCollar is returned by PlaceOrder when a limit order's price
+// is further from the last trade price than the symbol's price collar.
+var ErrOutsidePriceCollar = errors.New("price outside collar")
+
+// ErrBookDeltasDisabled is returned by GetBookDelta when book_delta_history
+// is 0.
+var ErrBookDeltasDisabled = errors.New("order book deltas are disabled")
+
+// ErrCancelSymbolRequired is returned by CancelOrderExpecting when
+// cancel_requires_symbol is set and no symbol is given.
+var ErrCancelSymbolRequired = errors.New("symbol is required to cancel an order")
+
+// ErrCancelMismatch is returned by CancelOrderExpecting when the order's
+// symbol or side differs from the one given.
+var ErrCancelMismatch = errors.New("order does not match the expected symbol or side")
+
+// ErrNoLiquidity is returned by PlaceOrder for a market order facing an empty
+// opposing side when reject_market_without_liquidity is set.
+var ErrNoLiquidity = errors.New("no_liquidity")
+
+// ErrForeignOrderID is returned by ParseOrderID for a composite order ID
+// that no order here was placed under, such as one from another instance.
+var ErrForeignOrderID = errors.New("order ID belongs to another namespace")
+
+// ErrSymbolUnderReview is returned by PlaceOrder for a symbol whose recovered
+// book was crossed, until ClearReview lifts the flag.
+var ErrSymbolUnderReview = errors.New("symbol under review: recovered order book is crossed")
+
+// ErrNotUnderReview is returned by ClearReview for a symbol that isn't
+// flagged for review.
+var ErrNotUnderReview = errors.New("symbol is not under review")
+
+// ErrBookCrossed is returned by ClearReview while the symbol's book is still
+// crossed.
+var ErrBookCrossed = errors.New("order book is still crossed")
+
+// ErrOrderIDConflict is returned by ImportSymbolState when orders here
+// already use some of the bundle's order IDs.
+var ErrOrderIDConflict = errors.New("order IDs already in use on this instance")
+
+// ErrInvalidOrderID is returned for an order ID that is neither numeric nor
+// a well-formed composite ID.
+var ErrInvalidOrderID = errors.New("invalid order ID")
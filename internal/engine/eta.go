@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// EstimateVolumeWindow is how far back EstimateTimeToFill looks for volume
+// traded at the order's price.
+const EstimateVolumeWindow = time.Hour
+
+// EstimateTimeToFill estimates how long a resting limit order will take to
+// start and to finish filling. It assumes volume keeps trading at the order's
+// price at the rate seen over the last EstimateVolumeWindow and is consumed
+// in queue order: first the quantity ahead, then the order itself. It is a
+// rough guide only: cancels ahead, new higher-tier orders and price moves
+// are not modelled. With no recent volume at the price the durations are nil.
+func (e *Engine) EstimateTimeToFill(orderID int64) (*models.FillEstimate, error) {
+	order, err := e.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Price == nil {
+		return nil, fmt.Errorf("order %d is not resting", orderID)
+	}
+
+	position, ahead, remaining, ok := e.getOrderBook(order.Symbol).QueuePosition(order.ID, order.Side, *order.Price)
+	if !ok {
+		return nil, fmt.Errorf("order %d is not resting", orderID)
+	}
+
+	now := time.Now()
+	var volume decimal.Decimal
+	err = e.db.QueryRow(`
+		SELECT COALESCE(SUM(quantity), 0) FROM `+e.tradeTable(order.Symbol)+` t
+		WHERE symbol = ? AND price = ? AND executed_at >= ?
+	`, order.Symbol, *order.Price, now.Add(-EstimateVolumeWindow)).Scan(&volume)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent volume: %w", err)
+	}
+
+	estimate := &models.FillEstimate{
+		OrderID:           order.ID,
+		IsEstimate:        true,
+		QueuePosition:     position,
+		QuantityAhead:     ahead,
+		RemainingQuantity: remaining,
+		RecentVolume:      volume,
+		WindowSeconds:     EstimateVolumeWindow.Seconds(),
+	}
+	if !volume.IsPositive() {
+		estimate.Note = "no recent volume at this price; time to fill cannot be estimated"
+		return estimate, nil
+	}
+
+	// Seconds per unit of quantity at the recent rate.
+	perUnit := decimal.NewFromFloat(EstimateVolumeWindow.Seconds()).Div(volume)
+	first, _ := ahead.Mul(perUnit).Float64()
+	full, _ := ahead.Add(remaining).Mul(perUnit).Float64()
+	estimate.SecondsToFirstFill = &first
+	estimate.SecondsToFill = &full
+	return estimate, nil
+}
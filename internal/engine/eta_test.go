@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_EstimateTimeToFill extrapolates recent volume at the order's
+// price over the quantity queued ahead of it and its own remainder.
+func TestEngine_EstimateTimeToFill(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	for _, qty := range []float64{2, 1} {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, qty))
+		require.NoError(t, err)
+	}
+	target, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1.5))
+	require.NoError(t, err)
+
+	volume := "6" // per EstimateVolumeWindow, i.e. one unit every 600s
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		switch {
+		case strings.Contains(query, "WHERE id = ?"):
+			created := time.Now()
+			return &fakeRows{
+				Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+				Rows: [][]driver.Value{{target.ID, nil, nil, "BTCUSD", "sell", "limit", "50000", "1.5", "1.5", "open", int64(0), created, created, nil, nil, nil}},
+			}, nil
+		case strings.Contains(query, "COALESCE(SUM(quantity), 0)"):
+			return &fakeRows{Cols: []string{"volume"}, Rows: [][]driver.Value{{volume}}}, nil
+		}
+		return nil, nil
+	}
+
+	estimate, err := eng.EstimateTimeToFill(target.ID)
+	require.NoError(t, err)
+	assert.True(t, estimate.IsEstimate)
+	assert.Equal(t, 3, estimate.QueuePosition)
+	assert.True(t, decimal.NewFromInt(3).Equal(estimate.QuantityAhead))
+	require.NotNil(t, estimate.SecondsToFirstFill)
+	require.NotNil(t, estimate.SecondsToFill)
+	assert.InDelta(t, 1800, *estimate.SecondsToFirstFill, 0.001)
+	assert.InDelta(t, 2700, *estimate.SecondsToFill, 0.001)
+
+	volume = "0"
+	estimate, err = eng.EstimateTimeToFill(target.ID)
+	require.NoError(t, err)
+	assert.Nil(t, estimate.SecondsToFirstFill)
+	assert.Nil(t, estimate.SecondsToFill)
+	assert.NotEmpty(t, estimate.Note)
+}
@@ -0,0 +1,330 @@
+package engine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"order-matching-engine/internal/models"
+)
+
+// bookEvent is one change to a resting order, as stored in book_events.
+type bookEvent struct {
+	// Seq numbers the symbol's events in the order they were applied.
+	Seq     int64
+	OrderID int64
+	// State is the order after the change, or nil once it left the book.
+	State *models.Order
+}
+
+// orderEvent returns the event recording o's current state: a copy of it
+// while it rests, else its removal.
+func orderEvent(o *models.Order) bookEvent {
+	ev := bookEvent{OrderID: o.ID}
+	if o.Type == models.OrderTypeLimit && o.Price != nil && o.RemainingQuantity.IsPositive() &&
+		(o.Status == models.OrderStatusOpen || o.Status == models.OrderStatusPartiallyFilled) {
+		state := *o
+		ev.State = &state
+	}
+	return ev
+}
+
+// applyBookEvent applies ev to book. index maps the IDs of the book's orders
+// to the orders and is kept in step. A new order queues at the back of its
+// level; a known one is updated in place, keeping its queue position.
+func applyBookEvent(book *OrderBook, index map[int64]*models.Order, ev bookEvent) {
+	existing := index[ev.OrderID]
+	switch {
+	case ev.State == nil:
+		if existing != nil {
+			book.RemoveOrder(existing.ID, existing.Side, existing.Price)
+			delete(index, ev.OrderID)
+		}
+	case existing != nil:
+		*existing = *ev.State
+	default:
+		order := *ev.State
+		book.AddOrder(&order)
+		index[order.ID] = &order
+	}
+}
+
+// replayBookEvents rebuilds symbol's book from a snapshot of its resting
+// orders, in queue order, and the events logged after it, in Seq order.
+func replayBookEvents(symbol string, snapshot []models.Order, events []bookEvent) *OrderBook {
+	book := NewOrderBook(symbol)
+	index := make(map[int64]*models.Order, len(snapshot))
+	for i := range snapshot {
+		order := snapshot[i]
+		book.AddOrder(&order)
+		index[order.ID] = &order
+	}
+	for _, ev := range events {
+		applyBookEvent(book, index, ev)
+	}
+	return book
+}
+
+// encodeBookOrder stores an order as JSON.
+func encodeBookOrder(o *models.Order) (string, error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode order %d: %w", o.ID, err)
+	}
+	return string(b), nil
+}
+
+// restoreIDNamespace fills in the namespace of an order decoded from JSON,
+// which carries it only within GlobalID.
+func restoreIDNamespace(o *models.Order) {
+	o.IDNamespace = globalIDNamespace(o.GlobalID)
+}
+
+// bookEventLog tracks each symbol's position in the event log. Callers hold
+// the symbol's lock; mu guards the maps across symbols.
+type bookEventLog struct {
+	snapshotEvery int
+
+	mu sync.Mutex
+	// lastSeq is the Seq of each symbol's last committed event, and pending
+	// the number of its events since its last snapshot.
+	lastSeq map[string]int64
+	pending map[string]int
+}
+
+func newBookEventLog(cfg EventLogConfig) *bookEventLog {
+	return &bookEventLog{
+		snapshotEvery: cfg.SnapshotEvery,
+		lastSeq:       make(map[string]int64),
+		pending:       make(map[string]int),
+	}
+}
+
+// logBookEvents appends events for symbol to book_events within tx, numbered
+// after the symbol's last committed event, and returns the Seq of the last
+// one. It writes nothing and returns 0 when the event log is disabled or
+// there are no events. The caller holds the symbol lock and passes the
+// result to bookEventsCommitted once tx commits.
+func (e *Engine) logBookEvents(tx *sql.Tx, symbol string, events []bookEvent) (int64, error) {
+	if e.eventLog == nil || len(events) == 0 {
+		return 0, nil
+	}
+	e.eventLog.mu.Lock()
+	seq := e.eventLog.lastSeq[symbol]
+	e.eventLog.mu.Unlock()
+
+	args := make([]interface{}, 0, 4*len(events))
+	for _, ev := range events {
+		seq++
+		var state interface{}
+		if ev.State != nil {
+			encoded, err := encodeBookOrder(ev.State)
+			if err != nil {
+				return 0, err
+			}
+			state = encoded
+		}
+		args = append(args, symbol, seq, ev.OrderID, state)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?),", len(events)), ",")
+	if _, err := tx.Exec(`INSERT INTO book_events (symbol, seq, order_id, order_state) VALUES `+placeholders, args...); err != nil {
+		return 0, fmt.Errorf("failed to log book events: %w", err)
+	}
+	return seq, nil
+}
+
+// bookEventsCommitted records that symbol's events up to lastSeq committed
+// and snapshots the book once snapshot_every events have accumulated since
+// the last snapshot. A failed snapshot is retried after the next event. The
+// caller holds the symbol lock, so the book matches the log.
+func (e *Engine) bookEventsCommitted(symbol string, lastSeq int64) {
+	if e.eventLog == nil || lastSeq == 0 {
+		return
+	}
+	e.eventLog.mu.Lock()
+	e.eventLog.pending[symbol] += int(lastSeq - e.eventLog.lastSeq[symbol])
+	e.eventLog.lastSeq[symbol] = lastSeq
+	due := e.eventLog.pending[symbol] >= e.eventLog.snapshotEvery
+	e.eventLog.mu.Unlock()
+
+	if due {
+		if err := e.snapshotBook(symbol); err != nil {
+			log.Printf("[WARN] Failed to snapshot book for %s: %v", symbol, err)
+		}
+	}
+}
+
+// snapshotBook writes symbol's resting orders to book_snapshots and deletes
+// the events the snapshot covers, in one transaction, so recovery replays
+// only the events after it and none are lost if either write fails. The
+// caller holds the symbol lock.
+func (e *Engine) snapshotBook(symbol string) error {
+	orders := e.getOrderBook(symbol).RestingOrders()
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	encoded, err := json.Marshal(orders)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	e.eventLog.mu.Lock()
+	lastSeq := e.eventLog.lastSeq[symbol]
+	e.eventLog.mu.Unlock()
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	_, err = tx.Exec(`
+		INSERT INTO book_snapshots (symbol, last_seq, orders, created_at)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE last_seq = VALUES(last_seq), orders = VALUES(orders), created_at = VALUES(created_at)
+	`, symbol, lastSeq, string(encoded), time.Now())
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if _, err = tx.Exec(`DELETE FROM book_events WHERE symbol = ? AND seq <= ?`, symbol, lastSeq); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to truncate book events: %w", err)
+	}
+	if err = e.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	e.eventLog.mu.Lock()
+	e.eventLog.pending[symbol] = 0
+	e.eventLog.mu.Unlock()
+	return nil
+}
+
+// snapshotAllBooks snapshots every non-empty book, starting the event log
+// from the books loaded from the orders table.
+func (e *Engine) snapshotAllBooks() error {
+	e.globalMutex.RLock()
+	symbols := make([]string, 0, len(e.orderBooks))
+	for symbol, ob := range e.orderBooks {
+		if bids, asks := ob.GetOrderCount(); bids+asks > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	e.globalMutex.RUnlock()
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		symMtx := e.getSymbolMutex(symbol)
+		symMtx.Lock()
+		err := e.snapshotBook(symbol)
+		symMtx.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to snapshot book for %s: %w", symbol, err)
+		}
+	}
+	return nil
+}
+
+// loadBooksFromEventLog rebuilds every logged symbol's book from its latest
+// snapshot and the events after it. ok is false when nothing has been
+// logged yet, so the books must be loaded from the orders table instead.
+func (e *Engine) loadBooksFromEventLog() (loaded int, ok bool, err error) {
+	snapshots := make(map[string][]models.Order)
+	snapshotSeq := make(map[string]int64)
+	rows, err := e.db.Query(`SELECT symbol, last_seq, orders FROM book_snapshots`)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query book snapshots: %w", err)
+	}
+	for rows.Next() {
+		var symbol, encoded string
+		var seq int64
+		if err := rows.Scan(&symbol, &seq, &encoded); err != nil {
+			rows.Close()
+			return 0, false, fmt.Errorf("failed to scan book snapshot: %w", err)
+		}
+		var orders []models.Order
+		if err := json.Unmarshal([]byte(encoded), &orders); err != nil {
+			rows.Close()
+			return 0, false, fmt.Errorf("failed to decode book snapshot of %s: %w", symbol, err)
+		}
+		for i := range orders {
+			restoreIDNamespace(&orders[i])
+		}
+		snapshots[symbol] = orders
+		snapshotSeq[symbol] = seq
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, fmt.Errorf("error iterating book snapshots: %w", err)
+	}
+
+	events := make(map[string][]bookEvent)
+	rows, err = e.db.Query(`SELECT symbol, seq, order_id, order_state FROM book_events ORDER BY symbol, seq`)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query book events: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var symbol string
+		var ev bookEvent
+		var state sql.NullString
+		if err := rows.Scan(&symbol, &ev.Seq, &ev.OrderID, &state); err != nil {
+			return 0, false, fmt.Errorf("failed to scan book event: %w", err)
+		}
+		if ev.Seq <= snapshotSeq[symbol] {
+			continue
+		}
+		if state.Valid {
+			ev.State = &models.Order{}
+			if err := json.Unmarshal([]byte(state.String), ev.State); err != nil {
+				return 0, false, fmt.Errorf("failed to decode book event %s/%d: %w", symbol, ev.Seq, err)
+			}
+			restoreIDNamespace(ev.State)
+		}
+		events[symbol] = append(events[symbol], ev)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, fmt.Errorf("error iterating book events: %w", err)
+	}
+	if len(snapshots) == 0 && len(events) == 0 {
+		return 0, false, nil
+	}
+
+	symbols := make([]string, 0, len(snapshots)+len(events))
+	for symbol := range snapshots {
+		symbols = append(symbols, symbol)
+	}
+	for symbol := range events {
+		if _, seen := snapshots[symbol]; !seen {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+
+	e.eventLog.mu.Lock()
+	defer e.eventLog.mu.Unlock()
+	for _, symbol := range symbols {
+		book := replayBookEvents(symbol, snapshots[symbol], events[symbol])
+		e.replaceOrderBook(symbol, book)
+		bids, asks := book.GetOrderCount()
+		loaded += bids + asks
+
+		lastSeq := snapshotSeq[symbol]
+		if n := len(events[symbol]); n > 0 {
+			lastSeq = events[symbol][n-1].Seq
+		}
+		e.eventLog.lastSeq[symbol] = lastSeq
+		e.eventLog.pending[symbol] = len(events[symbol])
+	}
+	return loaded, true, nil
+}
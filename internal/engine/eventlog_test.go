@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-matching-engine/internal/models"
+)
+
+// eventLogConfig enables the event log with the given snapshot interval.
+func eventLogConfig(snapshotEvery int) Config {
+	cfg := DefaultConfig()
+	cfg.EventLog = EventLogConfig{Enabled: true, SnapshotEvery: snapshotEvery}
+	return cfg
+}
+
+// orderRow returns o as a row of orderColumns.
+func orderRow(o models.Order) []driver.Value {
+	var price interface{}
+	if o.Price != nil {
+		price = o.Price.String()
+	}
+	return []driver.Value{o.ID, nil, nil, o.Symbol, string(o.Side), string(o.Type), price,
+		o.InitialQuantity.String(), o.RemainingQuantity.String(), string(o.Status), int64(o.Tier),
+		o.CreatedAt, o.UpdatedAt, nil, nil, nil}
+}
+
+// serveRestingOrders answers order lookups by ID from eng's BTCUSD book.
+func serveRestingOrders(eng *Engine) func(string, []driver.Value) (*fakeRows, error) {
+	return func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "WHERE id = ?") {
+			return nil, nil
+		}
+		for _, o := range eng.getOrderBook("BTCUSD").RestingOrders() {
+			if o.ID == args[0].(int64) {
+				return &fakeRows{
+					Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+					Rows: [][]driver.Value{orderRow(o)},
+				}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// runEventLogScript places and cancels a mix of BTCUSD orders that rest,
+// fill, partially fill and leave the book.
+func runEventLogScript(t *testing.T, eng *Engine, fdb *fakeDB) {
+	t.Helper()
+
+	place := func(req *models.CreateOrderRequest) *models.Order {
+		order, _, err := eng.PlaceOrder(req)
+		require.NoError(t, err)
+		return order
+	}
+	place(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	place(limitRequest("BTCUSD", models.OrderSideSell, 50100, 2))
+	small := place(limitRequest("BTCUSD", models.OrderSideSell, 50100, 0.5))
+	low := place(limitRequest("BTCUSD", models.OrderSideBuy, 49900, 1))
+	place(limitRequest("BTCUSD", models.OrderSideBuy, 50050, 1.5))
+	place(marketRequest("BTCUSD", models.OrderSideSell, 0.2))
+	place(limitRequest("BTCUSD", models.OrderSideBuy, 50100, 1))
+
+	fdb.queryHook = serveRestingOrders(eng)
+	_, err := eng.CancelOrder(low.ID)
+	require.NoError(t, err)
+	_, err = eng.CancelOrder(small.ID)
+	require.NoError(t, err)
+	fdb.queryHook = nil
+	place(limitRequest("BTCUSD", models.OrderSideBuy, 49800, 3))
+}
+
+// loggedBookEvents decodes every event written to book_events, in order.
+func loggedBookEvents(t *testing.T, fdb *fakeDB) []bookEvent {
+	t.Helper()
+
+	var events []bookEvent
+	for _, call := range fdb.ExecsMatching("INSERT INTO book_events") {
+		require.Zero(t, len(call.Args)%4)
+		for i := 0; i < len(call.Args); i += 4 {
+			require.Equal(t, "BTCUSD", call.Args[i])
+			ev := bookEvent{Seq: call.Args[i+1].(int64), OrderID: call.Args[i+2].(int64)}
+			if state, ok := call.Args[i+3].(string); ok {
+				ev.State = &models.Order{}
+				require.NoError(t, json.Unmarshal([]byte(state), ev.State))
+				restoreIDNamespace(ev.State)
+			}
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// bookJSON encodes the resting orders of book, in queue order.
+func bookJSON(t *testing.T, book *OrderBook) string {
+	t.Helper()
+	b, err := json.Marshal(book.RestingOrders())
+	require.NoError(t, err)
+	return string(b)
+}
+
+// TestReplayBookEvents_SnapshotPlusRemainingLogEqualsFullLog replays the
+// logged events in full and, for every cut point, from a snapshot of the
+// events before it plus the events after it. Every replay must reproduce the
+// live book, queue order included.
+func TestReplayBookEvents_SnapshotPlusRemainingLogEqualsFullLog(t *testing.T) {
+	eng, fdb := newFakeEngine(t, eventLogConfig(1000))
+	runEventLogScript(t, eng, fdb)
+
+	events := loggedBookEvents(t, fdb)
+	require.NotEmpty(t, events)
+	for i, ev := range events {
+		require.Equal(t, int64(i+1), ev.Seq, "events must be numbered consecutively")
+	}
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO book_snapshots"))
+
+	live := bookJSON(t, eng.getOrderBook("BTCUSD"))
+	require.Equal(t, live, bookJSON(t, replayBookEvents("BTCUSD", nil, events)), "full-log replay must match the live book")
+
+	for k := 0; k <= len(events); k++ {
+		encoded, err := json.Marshal(replayBookEvents("BTCUSD", nil, events[:k]).RestingOrders())
+		require.NoError(t, err)
+		var snapshot []models.Order
+		require.NoError(t, json.Unmarshal(encoded, &snapshot))
+		for i := range snapshot {
+			restoreIDNamespace(&snapshot[i])
+		}
+		assert.Equal(t, live, bookJSON(t, replayBookEvents("BTCUSD", snapshot, events[k:])), "snapshot after %d events", k)
+	}
+}
+
+// TestEngine_EventLogSnapshotTruncates snapshots the book every
+// snapshot_every events and deletes the events it covers in the same
+// transaction. A snapshot whose commit fails keeps its events and is retried
+// after the next one.
+func TestEngine_EventLogSnapshotTruncates(t *testing.T) {
+	eng, fdb := newFakeEngine(t, eventLogConfig(2))
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO book_snapshots"))
+
+	commits := fdb.commits
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 1))
+	require.NoError(t, err)
+	snapshots := fdb.ExecsMatching("INSERT INTO book_snapshots")
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, int64(2), snapshots[0].Args[1])
+	var orders []models.Order
+	require.NoError(t, json.Unmarshal([]byte(snapshots[0].Args[2].(string)), &orders))
+	assert.Len(t, orders, 2)
+	deletes := fdb.ExecsMatching("DELETE FROM book_events")
+	require.Len(t, deletes, 1)
+	assert.Equal(t, []driver.Value{"BTCUSD", int64(2)}, deletes[0].Args)
+	assert.Equal(t, commits+2, fdb.commits, "placement and snapshot should each commit once")
+
+	fdb.commitHook = func() error {
+		if len(fdb.ExecsMatching("DELETE FROM book_events")) > 1 {
+			return errors.New("commit lost")
+		}
+		return nil
+	}
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50200, 1))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50300, 1))
+	require.NoError(t, err, "a failed snapshot must not fail the placement")
+	fdb.commitHook = nil
+
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50400, 1))
+	require.NoError(t, err)
+	deletes = fdb.ExecsMatching("DELETE FROM book_events")
+	require.Len(t, deletes, 3)
+	assert.Equal(t, []driver.Value{"BTCUSD", int64(5)}, deletes[2].Args, "the retried snapshot should cover every event")
+}
+
+// TestEngine_LoadOpenOrdersFromEventLog recovers a book from a snapshot and
+// the events after it without reading the orders table, then numbers new
+// events after the last one replayed.
+func TestEngine_LoadOpenOrdersFromEventLog(t *testing.T) {
+	src, srcDB := newFakeEngine(t, eventLogConfig(1000))
+	runEventLogScript(t, src, srcDB)
+	events := loggedBookEvents(t, srcDB)
+	cut := len(events) / 2
+	snapshot, err := json.Marshal(replayBookEvents("BTCUSD", nil, events[:cut]).RestingOrders())
+	require.NoError(t, err)
+
+	eng, fdb := newFakeEngine(t, eventLogConfig(1000))
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		switch {
+		case strings.Contains(query, "FROM book_snapshots"):
+			return &fakeRows{
+				Cols: []string{"symbol", "last_seq", "orders"},
+				Rows: [][]driver.Value{{"BTCUSD", events[cut-1].Seq, string(snapshot)}},
+			}, nil
+		case strings.Contains(query, "FROM book_events"):
+			// Rows the snapshot covers are skipped even if still present.
+			rows := &fakeRows{Cols: []string{"symbol", "seq", "order_id", "order_state"}}
+			for _, ev := range events[cut-1:] {
+				var state interface{}
+				if ev.State != nil {
+					encoded, err := encodeBookOrder(ev.State)
+					require.NoError(t, err)
+					state = encoded
+				}
+				rows.Rows = append(rows.Rows, []driver.Value{"BTCUSD", ev.Seq, ev.OrderID, state})
+			}
+			return rows, nil
+		}
+		return nil, nil
+	}
+	require.NoError(t, eng.LoadOpenOrders())
+	assert.Empty(t, fdb.QueriesMatching("status IN ('open', 'partially_filled')"), "orders table should not be read")
+	assert.Equal(t, bookJSON(t, src.getOrderBook("BTCUSD")), bookJSON(t, eng.getOrderBook("BTCUSD")))
+
+	fdb.queryHook = nil
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	require.NoError(t, err)
+	logged := fdb.ExecsMatching("INSERT INTO book_events")
+	require.Len(t, logged, 1)
+	assert.Equal(t, events[len(events)-1].Seq+1, logged[0].Args[1])
+}
+
+// TestEngine_LoadOpenOrdersStartsEventLog loads books from the orders table
+// when nothing has been logged and snapshots them to start the log.
+func TestEngine_LoadOpenOrdersStartsEventLog(t *testing.T) {
+	src, _ := newFakeEngine(t, DefaultConfig())
+	resting, _, err := src.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+
+	eng, fdb := newFakeEngine(t, eventLogConfig(1000))
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "status IN ('open', 'partially_filled')") {
+			return nil, nil
+		}
+		return &fakeRows{
+			Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+			Rows: [][]driver.Value{orderRow(*resting)},
+		}, nil
+	}
+	require.NoError(t, eng.LoadOpenOrders())
+
+	snapshots := fdb.ExecsMatching("INSERT INTO book_snapshots")
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "BTCUSD", snapshots[0].Args[0])
+	assert.Equal(t, int64(0), snapshots[0].Args[1])
+	var orders []models.Order
+	require.NoError(t, json.Unmarshal([]byte(snapshots[0].Args[2].(string)), &orders))
+	require.Len(t, orders, 1)
+	assert.Equal(t, resting.ID, orders[0].ID)
+}
+
+// TestConfig_ValidateEventLog requires a positive snapshot interval.
+func TestConfig_ValidateEventLog(t *testing.T) {
+	cfg := eventLogConfig(1000)
+	require.NoError(t, cfg.Validate())
+	assert.Contains(t, cfg.EnabledFeatures(), "event_log")
+
+	cfg.EventLog.SnapshotEvery = 0
+	assert.ErrorContains(t, cfg.Validate(), "snapshot_every")
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is an in-process database/sql driver used by engine unit tests.
+// It records every statement, hands out auto-increment IDs for INSERTs and
+// lets tests inject errors or canned rows. It does not interpret SQL.
+type fakeDB struct {
+	mutex sync.Mutex
+
+	nextInsertID int64
+	execs        []fakeCall
+	queries      []fakeCall
+	commits      int
+	rollbacks    int
+
+	// execHook, when set, may fail an Exec before it is recorded.
+	execHook func(query string, args []driver.Value) error
+	// queryHook, when set, supplies rows for a Query; nil rows means empty.
+	queryHook func(query string, args []driver.Value) (*fakeRows, error)
+	// commitHook, when set, runs on every Commit and may fail it.
+	commitHook func() error
+}
+
+// fakeCall is a recorded statement with its arguments.
+type fakeCall struct {
+	Query string
+	Args  []driver.Value
+}
+
+var (
+	fakeDBsMutex sync.Mutex
+	fakeDBs      = map[string]*fakeDB{}
+	fakeDBSeq    int
+)
+
+func init() {
+	sql.Register("enginefake", fakeDriver{})
+}
+
+// newFakeDB opens a fresh fake database for a test.
+func newFakeDB(t testing.TB) (*sql.DB, *fakeDB) {
+	t.Helper()
+
+	fakeDBsMutex.Lock()
+	fakeDBSeq++
+	name := fmt.Sprintf("fake-%d", fakeDBSeq)
+	fdb := &fakeDB{}
+	fakeDBs[name] = fdb
+	fakeDBsMutex.Unlock()
+
+	database, err := sql.Open("enginefake", name)
+	if err != nil {
+		t.Fatalf("failed to open fake db: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database, fdb
+}
+
+// newFakeEngine returns an engine backed by a fresh fake database.
+func newFakeEngine(t testing.TB, cfg Config) (*Engine, *fakeDB) {
+	t.Helper()
+
+	database, fdb := newFakeDB(t)
+	eng, err := NewEngine(database, cfg)
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	t.Cleanup(func() { eng.Close() })
+	return eng, fdb
+}
+
+// ExecsMatching returns recorded Execs whose query contains substr.
+func (f *fakeDB) ExecsMatching(substr string) []fakeCall {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return matchingCalls(f.execs, substr)
+}
+
+// QueriesMatching returns recorded Queries whose query contains substr.
+func (f *fakeDB) QueriesMatching(substr string) []fakeCall {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return matchingCalls(f.queries, substr)
+}
+
+func matchingCalls(calls []fakeCall, substr string) []fakeCall {
+	var out []fakeCall
+	for _, c := range calls {
+		if strings.Contains(c.Query, substr) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMutex.Lock()
+	defer fakeDBsMutex.Unlock()
+
+	fdb, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake db %q", name)
+	}
+	return &fakeConn{db: fdb}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{db: c.db}, nil }
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.mutex.Lock()
+	hook := tx.db.commitHook
+	tx.db.mutex.Unlock()
+
+	if hook != nil {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+
+	tx.db.mutex.Lock()
+	tx.db.commits++
+	tx.db.mutex.Unlock()
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.mutex.Lock()
+	tx.db.rollbacks++
+	tx.db.mutex.Unlock()
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mutex.Lock()
+	hook := s.db.execHook
+	s.db.mutex.Unlock()
+
+	if hook != nil {
+		if err := hook(s.query, args); err != nil {
+			return nil, err
+		}
+	}
+
+	s.db.mutex.Lock()
+	defer s.db.mutex.Unlock()
+
+	s.db.execs = append(s.db.execs, fakeCall{Query: s.query, Args: args})
+	var id int64
+	if strings.Contains(strings.ToUpper(s.query), "INSERT") {
+		s.db.nextInsertID++
+		id = s.db.nextInsertID
+	}
+	return fakeResult{lastInsertID: id}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mutex.Lock()
+	s.db.queries = append(s.db.queries, fakeCall{Query: s.query, Args: args})
+	hook := s.db.queryHook
+	s.db.mutex.Unlock()
+
+	if hook != nil {
+		rows, err := hook(s.query, args)
+		if err != nil {
+			return nil, err
+		}
+		if rows != nil {
+			return rows, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeResult struct {
+	lastInsertID int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+// fakeRows is a canned result set.
+type fakeRows struct {
+	Cols []string
+	Rows [][]driver.Value
+	pos  int
+}
+
+func (r *fakeRows) Columns() []string { return r.Cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.Rows[r.pos])
+	r.pos++
+	return nil
+}
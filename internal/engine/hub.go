@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"order-matching-engine/internal/models"
+)
+
+// DefaultSubscriptionBuffer is the channel buffer used when Subscribe is
+// given a non-positive size.
+const DefaultSubscriptionBuffer = 256
+
+// EventFilter reports whether a subscriber wants an event. Filters are called
+// from Publish with the hub's lock held and must not block.
+type EventFilter func(models.Event) bool
+
+// Hub fans engine events out to subscribers. Publishing never blocks the
+// matching path: an event is dropped for a subscriber whose buffer is full.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	// accounts indexes account subscriptions by account ID. They receive
+	// only events published with PublishAccount.
+	accounts map[string]map[*Subscription]struct{}
+}
+
+// Subscription is a live registration on a Hub. Events arrive on C until
+// Close is called.
+type Subscription struct {
+	C <-chan models.Event
+
+	ch      chan models.Event
+	filter  EventFilter
+	account string // set for account subscriptions
+	hub     *Hub
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{
+		subs:     make(map[*Subscription]struct{}),
+		accounts: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber receiving events accepted by filter (all
+// events when filter is nil) on a channel buffered to buffer events.
+func (h *Hub) Subscribe(buffer int, filter EventFilter) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	ch := make(chan models.Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// SubscribeAccount registers a subscriber receiving the events published for
+// accountID, on a channel buffered to buffer events.
+func (h *Hub) SubscribeAccount(accountID string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	ch := make(chan models.Event, buffer)
+	sub := &Subscription{C: ch, ch: ch, account: accountID, hub: h}
+
+	h.mu.Lock()
+	subs, ok := h.accounts[accountID]
+	if !ok {
+		subs = make(map[*Subscription]struct{})
+		h.accounts[accountID] = subs
+	}
+	subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// HasAccountSubscribers reports whether accountID has a live subscription,
+// letting publishers skip building events nobody will receive.
+func (h *Hub) HasAccountSubscribers(accountID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.accounts[accountID]) > 0
+}
+
+// PublishAccount delivers ev to accountID's subscribers without blocking.
+func (h *Hub) PublishAccount(accountID string, ev models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.accounts[accountID] {
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Publish delivers ev to every interested subscriber without blocking.
+func (h *Hub) Publish(ev models.Event) {
+	// Exclusive lock: filters may keep state (e.g. sampling counters) and
+	// must see events in publish order.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// SampleTrades returns a filter passing one in every trade events per symbol
+// (the 1st, every+1th, ...) and no other events. Selection is by counter, not
+// at random, so the sample is evenly spread and reproducible. A filter keeps
+// its own counters; use one per subscription.
+func SampleTrades(every int) EventFilter {
+	if every < 1 {
+		every = 1
+	}
+	seen := make(map[string]uint64)
+	return func(ev models.Event) bool {
+		if ev.Type != models.EventTrade {
+			return false
+		}
+		n := seen[ev.Symbol]
+		seen[ev.Symbol] = n + 1
+		return n%uint64(every) == 0
+	}
+}
+
+// Close unregisters the subscription and closes C. It is safe to call twice.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		if s.account != "" {
+			subs := s.hub.accounts[s.account]
+			delete(subs, s)
+			if len(subs) == 0 {
+				delete(s.hub.accounts, s.account)
+			}
+		} else {
+			delete(s.hub.subs, s)
+		}
+		s.hub.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Dropped returns how many events were discarded because C was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
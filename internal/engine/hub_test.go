@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drainEvents returns the events currently buffered on sub.
+func drainEvents(sub *Subscription) []models.Event {
+	var events []models.Event
+	for {
+		select {
+		case ev := <-sub.C:
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
+
+// TestEngine_PreCommitFillRetractedOnRollback fails the commit of a matching
+// placement and expects the provisional fill to be followed by a retraction
+// and no committed trade event; a later successful placement is confirmed.
+func TestEngine_PreCommitFillRetractedOnRollback(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PreCommitFillEvents = true
+	eng, fdb := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 2.0))
+	require.NoError(t, err)
+
+	sub := eng.Events().Subscribe(16, nil)
+	defer sub.Close()
+
+	fdb.commitHook = func() error { return errors.New("commit lost") }
+	_, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 0.5))
+	require.Error(t, err)
+
+	events := drainEvents(sub)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventFillProvisional, events[0].Type)
+	assert.True(t, events[0].Provisional)
+	assert.Equal(t, models.EventFillRetracted, events[1].Type)
+	assert.False(t, events[1].Provisional)
+	assert.Equal(t, events[0].MatchID, events[1].MatchID)
+	assert.Equal(t, events[0].Trade.Quantity.String(), events[1].Trade.Quantity.String())
+
+	fdb.commitHook = nil
+	_, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 0.5))
+	require.NoError(t, err)
+
+	events = drainEvents(sub)
+	require.Len(t, events, 3)
+	assert.Equal(t, models.EventFillProvisional, events[0].Type)
+	assert.Equal(t, models.EventFillConfirmed, events[1].Type)
+	assert.Equal(t, models.EventTrade, events[2].Type)
+	assert.Equal(t, events[0].MatchID, events[1].MatchID)
+}
+
+// TestHub_DropsForFullSubscriber checks a slow subscriber loses events
+// instead of blocking the publisher, and filters are honoured.
+func TestHub_DropsForFullSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Subscribe(1, nil)
+	defer slow.Close()
+	ethOnly := hub.Subscribe(8, func(ev models.Event) bool { return ev.Symbol == "ETHUSD" })
+	defer ethOnly.Close()
+
+	for _, symbol := range []string{"BTCUSD", "ETHUSD", "BTCUSD"} {
+		hub.Publish(models.Event{Type: models.EventTrade, Symbol: symbol})
+	}
+
+	assert.Len(t, drainEvents(slow), 1)
+	assert.Equal(t, uint64(2), slow.Dropped())
+	got := drainEvents(ethOnly)
+	require.Len(t, got, 1)
+	assert.Equal(t, "ETHUSD", got[0].Symbol)
+}
+
+// TestHub_SampledTradesApproximateRate publishes many trades over two symbols
+// and expects about one in N per symbol, with non-trade events excluded.
+func TestHub_SampledTradesApproximateRate(t *testing.T) {
+	const every, perSymbol = 10, 1000
+	hub := NewHub()
+	sub := hub.Subscribe(2*perSymbol, SampleTrades(every))
+	defer sub.Close()
+
+	for i := 0; i < perSymbol; i++ {
+		hub.Publish(models.Event{Type: models.EventTrade, Symbol: "BTCUSD"})
+		hub.Publish(models.Event{Type: models.EventTrade, Symbol: "ETHUSD"})
+		hub.Publish(models.Event{Type: models.EventFillProvisional, Symbol: "BTCUSD"})
+	}
+
+	counts := map[string]int{}
+	for _, ev := range drainEvents(sub) {
+		assert.Equal(t, models.EventTrade, ev.Type)
+		counts[ev.Symbol]++
+	}
+	for _, symbol := range []string{"BTCUSD", "ETHUSD"} {
+		assert.InDelta(t, perSymbol/every, counts[symbol], 1, "sample rate for %s", symbol)
+	}
+}
+
+// TestEngine_AccountStream places an account's order and fills it from
+// another account, and expects each account to see only the events touching
+// its own orders.
+func TestEngine_AccountStream(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+
+	maker := eng.SubscribeAccount("maker")
+	defer maker.Close()
+	taker := eng.SubscribeAccount("taker")
+	defer taker.Close()
+	other := eng.SubscribeAccount("other")
+	defer other.Close()
+	all := eng.Events().Subscribe(16, nil)
+	defer all.Close()
+
+	ask := limitRequest("BTCUSD", models.OrderSideSell, 50000, 1)
+	ask.AccountID = "maker"
+	resting, _, err := eng.PlaceOrder(ask)
+	require.NoError(t, err)
+
+	bid := marketRequest("BTCUSD", models.OrderSideBuy, 1)
+	bid.AccountID = "taker"
+	_, _, err = eng.PlaceOrder(bid)
+	require.NoError(t, err)
+
+	events := drainEvents(maker)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventOrderPlaced, events[0].Type)
+	assert.Equal(t, resting.ID, events[0].Order.ID)
+	assert.Equal(t, models.OrderStatusOpen, events[0].Order.Status)
+	assert.Equal(t, models.EventOrderFilled, events[1].Type)
+	assert.Equal(t, "maker", events[1].AccountID)
+	assert.Equal(t, resting.ID, events[1].Trade.SellOrderID)
+	assert.Equal(t, models.OrderStatusFilled, events[1].Order.Status)
+
+	events = drainEvents(taker)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventOrderPlaced, events[0].Type)
+	assert.Equal(t, models.OrderStatusFilled, events[0].Order.Status)
+	assert.Equal(t, models.EventOrderFilled, events[1].Type)
+	assert.Equal(t, events[0].Order.ID, events[1].Trade.BuyOrderID)
+
+	// Account events stay off the general stream.
+	for _, ev := range drainEvents(all) {
+		assert.Equal(t, models.EventTrade, ev.Type)
+	}
+	assert.Empty(t, drainEvents(other))
+}
+
+// TestHub_AccountSubscriptionClose removes a closed account subscription
+// from the index.
+func TestHub_AccountSubscriptionClose(t *testing.T) {
+	hub := NewHub()
+	sub := hub.SubscribeAccount("acct", 1)
+	assert.True(t, hub.HasAccountSubscribers("acct"))
+
+	sub.Close()
+	assert.False(t, hub.HasAccountSubscribers("acct"))
+	hub.PublishAccount("acct", models.Event{Type: models.EventOrderPlaced})
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"database/sql"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -74,7 +75,7 @@ func TestStartupRecovery(t *testing.T) {
 		require.NoError(t, err, "Failed to insert test order %d", o.id)
 	}
 
-	eng, err := NewEngine(database)
+	eng, err := NewEngine(database, DefaultConfig())
 	require.NoError(t, err)
 	defer eng.Close()
 
@@ -135,7 +136,7 @@ func TestConcurrentOrderPlacement(t *testing.T) {
 
 	cleanupTestData(t, database)
 
-	eng, err := NewEngine(database)
+	eng, err := NewEngine(database, DefaultConfig())
 	require.NoError(t, err)
 	defer eng.Close()
 
@@ -209,6 +210,323 @@ func TestConcurrentOrderPlacement(t *testing.T) {
 	cleanupTestData(t, database)
 }
 
+// TestGetOrderStatuses verifies a bulk lookup returns statuses for existing orders
+// and reports unknown IDs as not found.
+func TestGetOrderStatuses(t *testing.T) {
+	if os.Getenv("DB_DSN") == "" {
+		t.Skip("DB_DSN environment variable not set, skipping integration test")
+	}
+
+	database, err := db.Connect()
+	require.NoError(t, err)
+	defer database.Close()
+
+	cleanupTestData(t, database)
+
+	eng, err := NewEngine(database, DefaultConfig())
+	require.NoError(t, err)
+	defer eng.Close()
+
+	price := decimal.NewFromInt(50000)
+	sell, _, err := eng.PlaceOrder(&models.CreateOrderRequest{
+		Symbol:   "BTCUSD",
+		Side:     models.OrderSideSell,
+		Type:     models.OrderTypeLimit,
+		Price:    &price,
+		Quantity: decimal.NewFromFloat(1.0),
+	})
+	require.NoError(t, err)
+
+	buy, _, err := eng.PlaceOrder(&models.CreateOrderRequest{
+		Symbol:   "BTCUSD",
+		Side:     models.OrderSideBuy,
+		Type:     models.OrderTypeLimit,
+		Price:    &price,
+		Quantity: decimal.NewFromFloat(0.4),
+	})
+	require.NoError(t, err)
+
+	ref := func(id int64) models.OrderRef { return models.OrderRef(strconv.FormatInt(id, 10)) }
+	missing := ref(buy.ID + 1000)
+	found, notFound, err := eng.GetOrderStatuses([]models.OrderRef{ref(sell.ID), missing, ref(buy.ID)})
+	require.NoError(t, err)
+
+	require.Len(t, found, 2)
+	assert.Equal(t, sell.ID, found[0].OrderID)
+	assert.Equal(t, models.OrderStatusPartiallyFilled, found[0].Status)
+	assert.True(t, decimal.NewFromFloat(0.6).Equal(found[0].RemainingQuantity))
+	assert.Equal(t, buy.ID, found[1].OrderID)
+	assert.Equal(t, models.OrderStatusFilled, found[1].Status)
+	assert.True(t, found[1].RemainingQuantity.IsZero())
+
+	assert.Equal(t, []models.OrderRef{missing}, notFound)
+
+	cleanupTestData(t, database)
+}
+
+// TestGetBlockTrades seeds small and large trades and verifies the
+// min-quantity filter returns only the large ones, honoring the limit.
+func TestGetBlockTrades(t *testing.T) {
+	if os.Getenv("DB_DSN") == "" {
+		t.Skip("DB_DSN environment variable not set, skipping integration test")
+	}
+
+	database, err := db.Connect()
+	require.NoError(t, err)
+	defer database.Close()
+
+	cleanupTestData(t, database)
+
+	eng, err := NewEngine(database, DefaultConfig())
+	require.NoError(t, err)
+	defer eng.Close()
+
+	price := decimal.NewFromInt(50000)
+	for _, qty := range []float64{0.1, 5.0, 0.2, 10.0, 7.5} {
+		_, _, err := eng.PlaceOrder(&models.CreateOrderRequest{
+			Symbol: "BTCUSD", Side: models.OrderSideSell, Type: models.OrderTypeLimit,
+			Price: &price, Quantity: decimal.NewFromFloat(qty),
+		})
+		require.NoError(t, err)
+		_, trades, err := eng.PlaceOrder(&models.CreateOrderRequest{
+			Symbol: "BTCUSD", Side: models.OrderSideBuy, Type: models.OrderTypeMarket,
+			Quantity: decimal.NewFromFloat(qty),
+		})
+		require.NoError(t, err)
+		require.Len(t, trades, 1)
+	}
+
+	minQty := decimal.NewFromInt(5)
+	blocks, err := eng.GetBlockTrades("BTCUSD", minQty, 0)
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+	for _, trade := range blocks {
+		assert.True(t, trade.Quantity.GreaterThanOrEqual(minQty), "trade %d below minimum: %s", trade.ID, trade.Quantity)
+	}
+
+	limited, err := eng.GetBlockTrades("BTCUSD", minQty, 2)
+	require.NoError(t, err)
+	require.Len(t, limited, 2)
+	assert.True(t, decimal.NewFromFloat(7.5).Equal(limited[0].Quantity), "most recent block trade first")
+
+	all, err := eng.GetTrades("BTCUSD", 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+
+	cleanupTestData(t, database)
+}
+
+// TestQueryTradesWithClientIDs checks trades carry both orders' client IDs
+// when requested, omit a missing one, and stay lean by default.
+func TestQueryTradesWithClientIDs(t *testing.T) {
+	if os.Getenv("DB_DSN") == "" {
+		t.Skip("DB_DSN environment variable not set, skipping integration test")
+	}
+
+	database, err := db.Connect()
+	require.NoError(t, err)
+	defer database.Close()
+
+	cleanupTestData(t, database)
+
+	eng, err := NewEngine(database, DefaultConfig())
+	require.NoError(t, err)
+	defer eng.Close()
+
+	price := decimal.NewFromInt(50000)
+	makerID, takerID := "maker-1", "taker-1"
+	_, _, err = eng.PlaceOrder(&models.CreateOrderRequest{
+		ClientOrderID: &makerID, Symbol: "BTCUSD", Side: models.OrderSideSell, Type: models.OrderTypeLimit,
+		Price: &price, Quantity: decimal.NewFromInt(2),
+	})
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(&models.CreateOrderRequest{
+		ClientOrderID: &takerID, Symbol: "BTCUSD", Side: models.OrderSideBuy, Type: models.OrderTypeMarket,
+		Quantity: decimal.NewFromInt(1),
+	})
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(&models.CreateOrderRequest{
+		Symbol: "BTCUSD", Side: models.OrderSideBuy, Type: models.OrderTypeMarket,
+		Quantity: decimal.NewFromInt(1),
+	})
+	require.NoError(t, err)
+
+	trades, err := eng.QueryTrades(TradeFilter{Symbol: "BTCUSD", IncludeClientIDs: true})
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+	assert.Nil(t, trades[0].BuyClientOrderID, "most recent taker had no client ID")
+	require.NotNil(t, trades[0].SellClientOrderID)
+	assert.Equal(t, makerID, *trades[0].SellClientOrderID)
+	require.NotNil(t, trades[1].BuyClientOrderID)
+	assert.Equal(t, takerID, *trades[1].BuyClientOrderID)
+	require.NotNil(t, trades[1].SellClientOrderID)
+	assert.Equal(t, makerID, *trades[1].SellClientOrderID)
+
+	lean, err := eng.GetTrades("BTCUSD", 0)
+	require.NoError(t, err)
+	require.Len(t, lean, 2)
+	assert.Nil(t, lean[1].BuyClientOrderID)
+	assert.Nil(t, lean[1].SellClientOrderID)
+
+	cleanupTestData(t, database)
+}
+
+// TestGetRecentCancels cancels several orders and verifies they come back
+// latest first, excluding open orders and other symbols.
+func TestGetRecentCancels(t *testing.T) {
+	if os.Getenv("DB_DSN") == "" {
+		t.Skip("DB_DSN environment variable not set, skipping integration test")
+	}
+
+	database, err := db.Connect()
+	require.NoError(t, err)
+	defer database.Close()
+
+	cleanupTestData(t, database)
+
+	eng, err := NewEngine(database, DefaultConfig())
+	require.NoError(t, err)
+	defer eng.Close()
+
+	place := func(symbol string, price int64) *models.Order {
+		p := decimal.NewFromInt(price)
+		order, _, err := eng.PlaceOrder(&models.CreateOrderRequest{
+			Symbol: symbol, Side: models.OrderSideBuy, Type: models.OrderTypeLimit,
+			Price: &p, Quantity: decimal.NewFromFloat(1.0),
+		})
+		require.NoError(t, err)
+		return order
+	}
+
+	var canceled []int64
+	for _, price := range []int64{49000, 49100, 49200} {
+		order := place("BTCUSD", price)
+		_, err := eng.CancelOrder(order.ID)
+		require.NoError(t, err)
+		canceled = append(canceled, order.ID)
+	}
+	place("BTCUSD", 49300) // still open
+	other := place("ETHUSDT", 3000)
+	_, err = eng.CancelOrder(other.ID)
+	require.NoError(t, err)
+
+	orders, err := eng.GetRecentCancels("BTCUSD", 10)
+	require.NoError(t, err)
+	require.Len(t, orders, 3)
+	for i, order := range orders {
+		assert.Equal(t, canceled[len(canceled)-1-i], order.ID, "latest cancel first")
+		assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	}
+
+	limited, err := eng.GetRecentCancels("BTCUSD", 2)
+	require.NoError(t, err)
+	require.Len(t, limited, 2)
+	assert.Equal(t, canceled[2], limited[0].ID)
+
+	cleanupTestData(t, database)
+}
+
+// TestInsertTradeIgnoresDuplicate re-inserts a persisted trade, as a retried
+// write would, and verifies no duplicate row is created.
+func TestInsertTradeIgnoresDuplicate(t *testing.T) {
+	if os.Getenv("DB_DSN") == "" {
+		t.Skip("DB_DSN environment variable not set, skipping integration test")
+	}
+
+	database, err := db.Connect()
+	require.NoError(t, err)
+	defer database.Close()
+
+	cleanupTestData(t, database)
+
+	eng, err := NewEngine(database, DefaultConfig())
+	require.NoError(t, err)
+	defer eng.Close()
+
+	price := decimal.NewFromInt(50000)
+	_, _, err = eng.PlaceOrder(&models.CreateOrderRequest{
+		Symbol: "BTCUSD", Side: models.OrderSideSell, Type: models.OrderTypeLimit,
+		Price: &price, Quantity: decimal.NewFromFloat(1.0),
+	})
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(&models.CreateOrderRequest{
+		Symbol: "BTCUSD", Side: models.OrderSideBuy, Type: models.OrderTypeMarket,
+		Quantity: decimal.NewFromFloat(1.0),
+	})
+	require.NoError(t, err)
+
+	trades, err := eng.GetTrades("BTCUSD", 0)
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+
+	// Retry the write with the stored trade.
+	tx, err := database.Begin()
+	require.NoError(t, err)
+	require.NoError(t, eng.insertTrade(tx, trades[0]))
+	require.NoError(t, tx.Commit())
+
+	trades, err = eng.GetTrades("BTCUSD", 0)
+	require.NoError(t, err)
+	assert.Len(t, trades, 1, "re-inserted trade must not be duplicated")
+
+	cleanupTestData(t, database)
+}
+
+// TestGetPosition trades an account through buys and sells against another
+// account and checks the persisted history nets to the expected position.
+func TestGetPosition(t *testing.T) {
+	if os.Getenv("DB_DSN") == "" {
+		t.Skip("DB_DSN environment variable not set, skipping integration test")
+	}
+
+	database, err := db.Connect()
+	require.NoError(t, err)
+	defer database.Close()
+
+	cleanupTestData(t, database)
+
+	eng, err := NewEngine(database, DefaultConfig())
+	require.NoError(t, err)
+	defer eng.Close()
+
+	trade := func(maker, taker string, makerSide models.OrderSide, qty float64) {
+		price := decimal.NewFromInt(50000)
+		takerSide := models.OrderSideBuy
+		if makerSide == models.OrderSideBuy {
+			takerSide = models.OrderSideSell
+		}
+		_, _, err := eng.PlaceOrder(&models.CreateOrderRequest{
+			AccountID: maker, Symbol: "BTCUSD", Side: makerSide, Type: models.OrderTypeLimit,
+			Price: &price, Quantity: decimal.NewFromFloat(qty),
+		})
+		require.NoError(t, err)
+		_, trades, err := eng.PlaceOrder(&models.CreateOrderRequest{
+			AccountID: taker, Symbol: "BTCUSD", Side: takerSide, Type: models.OrderTypeMarket,
+			Quantity: decimal.NewFromFloat(qty),
+		})
+		require.NoError(t, err)
+		require.Len(t, trades, 1)
+	}
+
+	trade("acct-2", "acct-1", models.OrderSideSell, 2.0) // acct-1 buys 2
+	trade("acct-1", "acct-2", models.OrderSideSell, 0.5) // acct-1 sells 0.5
+	trade("acct-2", "acct-1", models.OrderSideBuy, 0.3)  // acct-1 sells 0.3
+
+	position, err := eng.GetPosition("acct-1", "BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(1.2).Equal(position), "got %s", position)
+
+	position, err = eng.GetPosition("acct-2", "BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromFloat(-1.2).Equal(position), "got %s", position)
+
+	position, err = eng.GetPosition("acct-3", "BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, position.IsZero())
+
+	cleanupTestData(t, database)
+}
+
 // cleanupTestData removes test data for symbols used in integration tests.
 func cleanupTestData(t *testing.T, database *sql.DB) {
 	_, err := database.Exec("DELETE FROM trades WHERE symbol IN ('BTCUSD', 'ETHUSDT')")
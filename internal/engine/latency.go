@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// commitLatencyMonitor tracks a sliding window of DB commit latencies and
+// decides when order placement should be paused or resumed. Using separate
+// pause and resume thresholds avoids flapping around a single value.
+type commitLatencyMonitor struct {
+	cfg CommitLatencyGuardConfig
+
+	mutex   sync.Mutex
+	samples []time.Duration // ring buffer of the last cfg.Window commits
+	next    int
+	count   int
+	paused  bool
+}
+
+// newCommitLatencyMonitor returns a monitor for the given guard config.
+func newCommitLatencyMonitor(cfg CommitLatencyGuardConfig) *commitLatencyMonitor {
+	return &commitLatencyMonitor{
+		cfg:     cfg,
+		samples: make([]time.Duration, cfg.Window),
+	}
+}
+
+// Observe records a commit latency. It reports whether the paused state
+// changed and what the state is now.
+func (m *commitLatencyMonitor) Observe(d time.Duration) (changed, paused bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.samples[m.next] = d
+	m.next = (m.next + 1) % len(m.samples)
+	if m.count < len(m.samples) {
+		m.count++
+	}
+
+	// Only judge a full window so a single slow commit cannot trip the guard.
+	if m.count < len(m.samples) {
+		return false, m.paused
+	}
+
+	avg := m.averageLocked()
+	switch {
+	case !m.paused && avg >= m.cfg.PauseThreshold.Duration:
+		m.paused = true
+		return true, true
+	case m.paused && avg <= m.cfg.ResumeThreshold.Duration:
+		m.paused = false
+		return true, false
+	}
+	return false, m.paused
+}
+
+// Paused reports whether placement is currently paused.
+func (m *commitLatencyMonitor) Paused() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.paused
+}
+
+// Average returns the mean latency of the recorded window.
+func (m *commitLatencyMonitor) Average() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.averageLocked()
+}
+
+func (m *commitLatencyMonitor) averageLocked() time.Duration {
+	if m.count == 0 {
+		return 0
+	}
+	var total time.Duration
+	for i := 0; i < m.count; i++ {
+		total += m.samples[i]
+	}
+	return total / time.Duration(m.count)
+}
+
+// IsAutoPaused reports whether the commit latency guard has paused order placement.
+func (e *Engine) IsAutoPaused() bool {
+	return e.latencyMonitor != nil && e.latencyMonitor.Paused()
+}
+
+// observeCommit feeds a commit latency to the guard and starts the recovery
+// probe when placement gets paused.
+func (e *Engine) observeCommit(d time.Duration) {
+	if e.latencyMonitor == nil {
+		return
+	}
+	changed, paused := e.latencyMonitor.Observe(d)
+	if !changed {
+		return
+	}
+	if paused {
+		log.Printf("[WARN] Commit latency %v above %v, pausing order placement",
+			e.latencyMonitor.Average(), e.config.CommitLatencyGuard.PauseThreshold.Duration)
+		go e.probeCommitLatency()
+		return
+	}
+	log.Printf("[INFO] Commit latency %v recovered, resuming order placement", e.latencyMonitor.Average())
+}
+
+// probeCommitLatency commits empty transactions while placement is paused so
+// the guard keeps receiving samples. It exits once placement resumes or the
+// engine is closed.
+func (e *Engine) probeCommitLatency() {
+	ticker := time.NewTicker(e.config.CommitLatencyGuard.ProbeInterval.Duration)
+	defer ticker.Stop()
+
+	for e.IsAutoPaused() {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		}
+
+		tx, err := e.db.Begin()
+		if err != nil {
+			log.Printf("[ERROR] Commit latency probe failed to begin transaction: %v", err)
+			continue
+		}
+		if err := e.commit(tx); err != nil {
+			log.Printf("[ERROR] Commit latency probe failed to commit: %v", err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_CommitLatencyGuardPausesAndResumes simulates slow commits in the
+// store, expects placement to be auto-paused, then lets commits recover and
+// expects the probe to resume placement.
+func TestEngine_CommitLatencyGuardPausesAndResumes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CommitLatencyGuard = CommitLatencyGuardConfig{
+		Enabled:         true,
+		Window:          2,
+		PauseThreshold:  Duration{20 * time.Millisecond},
+		ResumeThreshold: Duration{5 * time.Millisecond},
+		ProbeInterval:   Duration{5 * time.Millisecond},
+	}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	var slow atomic.Bool
+	slow.Store(true)
+	fdb.commitHook = func() error {
+		if slow.Load() {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return nil
+	}
+
+	// One slow commit is not a sustained spike.
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	require.NoError(t, err)
+	assert.False(t, eng.IsAutoPaused())
+
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	require.NoError(t, err)
+	require.True(t, eng.IsAutoPaused(), "two slow commits should trip the guard")
+
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.ErrorIs(t, err, ErrAutoPaused)
+
+	var metrics bytes.Buffer
+	require.NoError(t, eng.WriteMetrics(&metrics))
+	assert.Contains(t, metrics.String(), "engine_auto_paused 1")
+
+	slow.Store(false)
+	require.Eventually(t, func() bool { return !eng.IsAutoPaused() }, time.Second, 5*time.Millisecond,
+		"probe commits should clear the pause once latency recovers")
+
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.NoError(t, err)
+
+	metrics.Reset()
+	require.NoError(t, eng.WriteMetrics(&metrics))
+	assert.Contains(t, metrics.String(), "engine_auto_paused 0")
+}
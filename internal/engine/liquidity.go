@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"math"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Parameters of the liquidity score. Each component saturates towards 1, so
+// the reference values are where a component scores one half.
+const (
+	// LiquidityDepthLevels is how many levels per side count towards depth.
+	LiquidityDepthLevels = 10
+	// LiquiditySpreadReference is the relative spread, (ask-bid)/mid, at
+	// which the spread component is 0.5 (10 basis points).
+	LiquiditySpreadReference = 0.001
+	// LiquidityDepthReference is the notional (price × quantity, in quote
+	// currency) over both sides at which the depth component is 0.5.
+	LiquidityDepthReference = 100000
+)
+
+// Weights of the components in the liquidity score; they sum to 1.
+const (
+	liquiditySpreadWeight  = 0.4
+	liquidityDepthWeight   = 0.4
+	liquidityBalanceWeight = 0.2
+)
+
+// GetLiquidityScore rates how liquid symbol's book is on a 0–100 scale, for
+// comparing symbols at a glance. It combines three components, each in 0–1:
+//
+//   - spread: r / (r + spread), where spread is (ask-bid)/mid and r is
+//     LiquiditySpreadReference; 0 unless both sides have orders.
+//   - depth: n / (n + LiquidityDepthReference), where n is the notional of
+//     the top LiquidityDepthLevels levels of both sides.
+//   - balance: the smaller side's notional over the larger's; 0 for an
+//     empty or one-sided book.
+//
+// score = 100 × (0.4 × spread + 0.4 × depth + 0.2 × balance). An empty book
+// scores 0.
+func (e *Engine) GetLiquidityScore(symbol string) models.LiquidityScoreResponse {
+	bids, asks := e.getOrderBook(symbol).GetAggregatedLevels(LiquidityDepthLevels)
+	bidDepth, askDepth := levelsNotional(bids), levelsNotional(asks)
+	resp := models.LiquidityScoreResponse{Symbol: symbol, BidDepth: bidDepth, AskDepth: askDepth}
+
+	var spreadScore float64
+	if len(bids) > 0 && len(asks) > 0 {
+		bid, ask := bids[0].Price, asks[0].Price
+		mid := bid.Add(ask).Div(decimal.NewFromInt(2))
+		spread := ask.Sub(bid).Div(mid)
+		resp.Spread = &spread
+		spreadScore = LiquiditySpreadReference / (LiquiditySpreadReference + math.Max(spread.InexactFloat64(), 0))
+	}
+
+	total := bidDepth.Add(askDepth).InexactFloat64()
+	depthScore := total / (total + LiquidityDepthReference)
+
+	var balanceScore float64
+	if bidDepth.IsPositive() && askDepth.IsPositive() {
+		balanceScore = decimal.Min(bidDepth, askDepth).Div(decimal.Max(bidDepth, askDepth)).InexactFloat64()
+	}
+
+	resp.SpreadScore = roundScore(spreadScore)
+	resp.DepthScore = roundScore(depthScore)
+	resp.BalanceScore = roundScore(balanceScore)
+	resp.Score = roundScore(liquiditySpreadWeight*spreadScore + liquidityDepthWeight*depthScore + liquidityBalanceWeight*balanceScore)
+	return resp
+}
+
+// levelsNotional sums price × quantity over levels.
+func levelsNotional(levels []models.OrderBookLevel) decimal.Decimal {
+	total := decimal.Zero
+	for _, l := range levels {
+		total = total.Add(l.Price.Mul(l.Quantity))
+	}
+	return total
+}
+
+// roundScore maps a 0–1 component onto 0–100 with two decimals.
+func roundScore(v float64) float64 {
+	return math.Round(v*10000) / 100
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"testing"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_LiquidityScore checks a deep, tight book outscores a thin, wide
+// one, and that empty and one-sided books are handled.
+func TestEngine_LiquidityScore(t *testing.T) {
+	eng, _ := newFakeEngine(t, DefaultConfig())
+
+	empty := eng.GetLiquidityScore("BTCUSD")
+	assert.Zero(t, empty.Score)
+	assert.Nil(t, empty.Spread)
+
+	place := func(req *models.CreateOrderRequest) {
+		t.Helper()
+		_, _, err := eng.PlaceOrder(req)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		place(limitRequest("BTCUSD", models.OrderSideBuy, float64(49995-i*5), 2))
+		place(limitRequest("BTCUSD", models.OrderSideSell, float64(50005+i*5), 2))
+	}
+	place(limitRequest("ETHUSD", models.OrderSideBuy, 2800, 0.1))
+	place(limitRequest("ETHUSD", models.OrderSideSell, 3200, 0.5))
+
+	deep := eng.GetLiquidityScore("BTCUSD")
+	thin := eng.GetLiquidityScore("ETHUSD")
+	assert.Greater(t, deep.Score, thin.Score)
+	assert.Greater(t, deep.SpreadScore, thin.SpreadScore)
+	assert.Greater(t, deep.DepthScore, thin.DepthScore)
+	assert.Greater(t, deep.BalanceScore, thin.BalanceScore)
+	assert.LessOrEqual(t, deep.Score, 100.0)
+
+	place(limitRequest("SOLUSD", models.OrderSideBuy, 150, 10))
+	oneSided := eng.GetLiquidityScore("SOLUSD")
+	assert.Nil(t, oneSided.Spread)
+	assert.Zero(t, oneSided.SpreadScore)
+	assert.Zero(t, oneSided.BalanceScore)
+	assert.Positive(t, oneSided.Score, "depth still counts")
+}
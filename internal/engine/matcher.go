@@ -13,8 +13,73 @@ type MatchResult struct {
 	Trades            []models.Trade
 	UpdatedOrders     []*models.Order
 	IncomingOrderLeft *models.Order // nil if fully filled
+	// TradeCapReached reports that matching stopped at MatchOptions.MaxTrades.
+	TradeCapReached bool
+	// SelfTradeCanceled lists resting orders canceled by self-trade
+	// prevention; they are also in UpdatedOrders.
+	SelfTradeCanceled []*models.Order
+	// SelfTradeStopped reports that self-trade prevention canceled the
+	// incoming order's remainder.
+	SelfTradeStopped bool
 }
 
+// MatchOptions adjusts how Match finalizes the incoming order.
+type MatchOptions struct {
+	// RestMarketRemainder converts the unfilled remainder of a market order
+	// that traded at least once into a limit order at its last fill price,
+	// returned in IncomingOrderLeft, instead of canceling it.
+	RestMarketRemainder bool
+	// ImmediateOrCancel cancels the unfilled remainder of a limit order
+	// instead of resting it.
+	ImmediateOrCancel bool
+	// MaxTrades, when positive, stops matching once the incoming order has
+	// produced this many trades. A limit remainder then rests unless it would
+	// cross the book, and any other remainder is canceled. 0 is unlimited.
+	MaxTrades int
+	// SelfTradePrevention, when set, stops an order from trading against a
+	// resting order of the same account: models.SelfTradeCancelResting
+	// cancels the resting order and keeps matching, SelfTradeCancelIncoming
+	// cancels the incoming order's remainder. Orders without an account
+	// never trigger it.
+	SelfTradePrevention string
+	// Trace, when non-nil, receives a step for every matching decision.
+	// Leave nil in normal operation: nothing is recorded or allocated.
+	Trace *MatchTrace
+}
+
+// MatchTrace collects the steps of one Match call.
+type MatchTrace struct {
+	Steps []models.MatchTraceStep
+}
+
+// add appends a step. It is a no-op on a nil trace.
+func (t *MatchTrace) add(action string, resting *models.Order, reason string) {
+	if t == nil {
+		return
+	}
+	step := models.MatchTraceStep{Step: len(t.Steps) + 1, Action: action, Reason: reason}
+	if resting != nil {
+		step.RestingOrderID = resting.ID
+		step.Price = resting.Price
+	}
+	t.Steps = append(t.Steps, step)
+}
+
+// addQuantity appends a step carrying a copy of quantity. Taking the copy
+// here, past the nil check, keeps untraced matching allocation-free.
+func (t *MatchTrace) addQuantity(action string, resting *models.Order, quantity decimal.Decimal, reason string) {
+	if t == nil {
+		return
+	}
+	q := quantity
+	t.add(action, resting, reason)
+	t.Steps[len(t.Steps)-1].Quantity = &q
+}
+
+// maxMatchSizeHint caps the initial capacity of a MatchResult so a deep book
+// doesn't make every small order allocate for a full sweep.
+const maxMatchSizeHint = 16
+
 // Matcher implements the order matching algorithm using price-time priority.
 type Matcher struct{}
 
@@ -25,9 +90,24 @@ func NewMatcher() *Matcher { return &Matcher{} }
 // Returns the trades executed and any updated/resting orders. If the incoming
 // limit order is not fully filled, IncomingOrderLeft will contain the leftover.
 func (m *Matcher) Match(incomingOrder *models.Order, orderBook *OrderBook) *MatchResult {
+	return m.MatchWithOptions(incomingOrder, orderBook, MatchOptions{})
+}
+
+// MatchWithOptions is Match with explicit MatchOptions.
+func (m *Matcher) MatchWithOptions(incomingOrder *models.Order, orderBook *OrderBook, opts MatchOptions) *MatchResult {
+	// Size the result from the opposing depth so a sweep doesn't regrow the
+	// slices level by level. UpdatedOrders also holds the incoming order.
+	opposite := models.OrderSideSell
+	if incomingOrder.Side == models.OrderSideSell {
+		opposite = models.OrderSideBuy
+	}
+	sizeHint := orderBook.levelCount(opposite)
+	if sizeHint > maxMatchSizeHint {
+		sizeHint = maxMatchSizeHint
+	}
 	result := &MatchResult{
-		Trades:        make([]models.Trade, 0),
-		UpdatedOrders: make([]*models.Order, 0),
+		Trades:        make([]models.Trade, 0, sizeHint),
+		UpdatedOrders: make([]*models.Order, 0, sizeHint+1),
 	}
 
 	// Work on a copy so we can return the updated incoming order state.
@@ -35,20 +115,46 @@ func (m *Matcher) Match(incomingOrder *models.Order, orderBook *OrderBook) *Matc
 	executedAt := time.Now()
 
 	if incomingOrder.Side == models.OrderSideBuy {
-		m.matchBuyOrder(&workingOrder, orderBook, result, executedAt)
+		m.matchBuyOrder(&workingOrder, orderBook, result, executedAt, &opts)
 	} else {
-		m.matchSellOrder(&workingOrder, orderBook, result, executedAt)
+		m.matchSellOrder(&workingOrder, orderBook, result, executedAt, &opts)
 	}
 
 	// Finalize incoming order status according to remaining quantity and type.
 	if !workingOrder.RemainingQuantity.IsZero() {
-		if workingOrder.Type == models.OrderTypeLimit {
+		// A capped order may still be marketable; resting it then would
+		// cross the book.
+		capped := result.TradeCapReached
+		crossing := capped && m.crossesBook(&workingOrder, orderBook)
+		if workingOrder.Type == models.OrderTypeLimit && !opts.ImmediateOrCancel && !crossing && !result.SelfTradeStopped {
 			if workingOrder.RemainingQuantity.LessThan(workingOrder.InitialQuantity) {
 				workingOrder.Status = models.OrderStatusPartiallyFilled
 			}
 			result.IncomingOrderLeft = &workingOrder
+			opts.Trace.addQuantity(models.TraceRest, nil, workingOrder.RemainingQuantity, "")
+		} else if workingOrder.Type == models.OrderTypeMarket && opts.RestMarketRemainder && len(result.Trades) > 0 &&
+			!capped && !result.SelfTradeStopped {
+			// Marketable-limit behaviour: the leftover rests at the last
+			// fill price, which is now the best price on its side.
+			lastPrice := result.Trades[len(result.Trades)-1].Price
+			workingOrder.Type = models.OrderTypeLimit
+			workingOrder.Price = &lastPrice
+			workingOrder.Status = models.OrderStatusPartiallyFilled
+			result.IncomingOrderLeft = &workingOrder
+			opts.Trace.addQuantity(models.TraceRest, nil, workingOrder.RemainingQuantity, "market remainder converted to limit at last fill price")
 		} else {
-			// Market orders: leftover is canceled when no more matches exist.
+			// Market, IOC and self-trade-stopped orders: leftover is
+			// canceled when no more matches exist.
+			reason := "market remainder"
+			switch {
+			case result.SelfTradeStopped:
+				reason = "self-trade prevention"
+			case capped:
+				reason = "trade cap reached"
+			case workingOrder.Type == models.OrderTypeLimit:
+				reason = "immediate-or-cancel remainder"
+			}
+			opts.Trace.addQuantity(models.TraceCancel, nil, workingOrder.RemainingQuantity, reason)
 			workingOrder.Status = models.OrderStatusCanceled
 			workingOrder.RemainingQuantity = decimal.Zero
 			result.UpdatedOrders = append(result.UpdatedOrders, &workingOrder)
@@ -61,19 +167,35 @@ func (m *Matcher) Match(incomingOrder *models.Order, orderBook *OrderBook) *Matc
 	return result
 }
 
-func (m *Matcher) matchBuyOrder(buyOrder *models.Order, orderBook *OrderBook, result *MatchResult, executedAt time.Time) {
+func (m *Matcher) matchBuyOrder(buyOrder *models.Order, orderBook *OrderBook, result *MatchResult, executedAt time.Time, opts *MatchOptions) {
+	trace := opts.Trace
 	for !buyOrder.RemainingQuantity.IsZero() {
+		if opts.MaxTrades > 0 && len(result.Trades) >= opts.MaxTrades {
+			result.TradeCapReached = true
+			trace.add(models.TraceStop, nil, "trade cap reached")
+			return
+		}
 		bestAsk := orderBook.GetBestAsk()
 		if bestAsk == nil {
+			trace.add(models.TraceStop, nil, "opposite side empty")
 			return
 		}
+		trace.addQuantity(models.TraceCandidate, bestAsk, bestAsk.RemainingQuantity, "")
 
 		if !m.canMatch(buyOrder, bestAsk) {
+			trace.add(models.TraceStop, bestAsk, "best price not marketable")
 			return
 		}
+		if opts.SelfTradePrevention != "" && buyOrder.AccountID != "" && buyOrder.AccountID == bestAsk.AccountID {
+			if !m.preventSelfTrade(bestAsk, orderBook, result, executedAt, opts) {
+				return
+			}
+			continue
+		}
 
 		trade := m.executeTrade(buyOrder, bestAsk, executedAt)
 		result.Trades = append(result.Trades, trade)
+		trace.addQuantity(models.TraceTrade, bestAsk, trade.Quantity, "")
 
 		// Update quantities and statuses
 		tradeQuantity := trade.Quantity
@@ -91,19 +213,35 @@ func (m *Matcher) matchBuyOrder(buyOrder *models.Order, orderBook *OrderBook, re
 	}
 }
 
-func (m *Matcher) matchSellOrder(sellOrder *models.Order, orderBook *OrderBook, result *MatchResult, executedAt time.Time) {
+func (m *Matcher) matchSellOrder(sellOrder *models.Order, orderBook *OrderBook, result *MatchResult, executedAt time.Time, opts *MatchOptions) {
+	trace := opts.Trace
 	for !sellOrder.RemainingQuantity.IsZero() {
+		if opts.MaxTrades > 0 && len(result.Trades) >= opts.MaxTrades {
+			result.TradeCapReached = true
+			trace.add(models.TraceStop, nil, "trade cap reached")
+			return
+		}
 		bestBid := orderBook.GetBestBid()
 		if bestBid == nil {
+			trace.add(models.TraceStop, nil, "opposite side empty")
 			return
 		}
+		trace.addQuantity(models.TraceCandidate, bestBid, bestBid.RemainingQuantity, "")
 
 		if !m.canMatch(sellOrder, bestBid) {
+			trace.add(models.TraceStop, bestBid, "best price not marketable")
 			return
 		}
+		if opts.SelfTradePrevention != "" && sellOrder.AccountID != "" && sellOrder.AccountID == bestBid.AccountID {
+			if !m.preventSelfTrade(bestBid, orderBook, result, executedAt, opts) {
+				return
+			}
+			continue
+		}
 
 		trade := m.executeTrade(sellOrder, bestBid, executedAt)
 		result.Trades = append(result.Trades, trade)
+		trace.addQuantity(models.TraceTrade, bestBid, trade.Quantity, "")
 
 		tradeQuantity := trade.Quantity
 		sellOrder.RemainingQuantity = sellOrder.RemainingQuantity.Sub(tradeQuantity)
@@ -135,6 +273,34 @@ func (m *Matcher) canMatch(incomingOrder, restingOrder *models.Order) bool {
 	return incomingOrder.Price.LessThanOrEqual(*restingOrder.Price)
 }
 
+// preventSelfTrade applies self-trade prevention to a resting order of the
+// incoming order's own account and reports whether matching may continue.
+func (m *Matcher) preventSelfTrade(resting *models.Order, orderBook *OrderBook, result *MatchResult, executedAt time.Time, opts *MatchOptions) bool {
+	if opts.SelfTradePrevention != models.SelfTradeCancelResting {
+		opts.Trace.add(models.TraceStop, resting, "self-trade prevention: incoming order canceled")
+		result.SelfTradeStopped = true
+		return false
+	}
+	opts.Trace.addQuantity(models.TraceSkip, resting, resting.RemainingQuantity, "self-trade prevention: resting order canceled")
+	orderBook.RemoveOrder(resting.ID, resting.Side, resting.Price)
+	resting.Status = models.OrderStatusCanceled
+	resting.RemainingQuantity = decimal.Zero
+	resting.UpdatedAt = executedAt
+	result.UpdatedOrders = append(result.UpdatedOrders, resting)
+	result.SelfTradeCanceled = append(result.SelfTradeCanceled, resting)
+	return true
+}
+
+// crossesBook reports whether order could still trade against the best
+// opposing order.
+func (m *Matcher) crossesBook(order *models.Order, orderBook *OrderBook) bool {
+	best := orderBook.GetBestAsk()
+	if order.Side == models.OrderSideSell {
+		best = orderBook.GetBestBid()
+	}
+	return best != nil && m.canMatch(order, best)
+}
+
 // executeTrade creates a trade between two matching orders.
 // Price selection rules:
 // - Limit/Limit: use the resting order's price (price-time priority).
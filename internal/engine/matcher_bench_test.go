@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Run with: go test ./internal/engine -run '^$' -bench BenchmarkMatcher -benchmem
+
+// benchBook builds a sell-side book with ordersPerLevel orders of qty at each of
+// levels consecutive prices starting at 50000.
+func benchBook(levels, ordersPerLevel int, qty decimal.Decimal) *OrderBook {
+	ob := NewOrderBook("BTCUSD")
+	id := int64(1)
+	created := time.Now().Add(-time.Hour)
+	for l := 0; l < levels; l++ {
+		price := decimal.NewFromInt(int64(50000 + l))
+		for i := 0; i < ordersPerLevel; i++ {
+			ob.AddOrder(&models.Order{
+				ID:                id,
+				Symbol:            "BTCUSD",
+				Side:              models.OrderSideSell,
+				Type:              models.OrderTypeLimit,
+				Price:             &price,
+				InitialQuantity:   qty,
+				RemainingQuantity: qty,
+				Status:            models.OrderStatusOpen,
+				CreatedAt:         created,
+			})
+			id++
+		}
+	}
+	return ob
+}
+
+// benchMarketBuy returns an incoming market buy for qty.
+func benchMarketBuy(qty decimal.Decimal) *models.Order {
+	return &models.Order{
+		ID:                1 << 40,
+		Symbol:            "BTCUSD",
+		Side:              models.OrderSideBuy,
+		Type:              models.OrderTypeMarket,
+		InitialQuantity:   qty,
+		RemainingQuantity: qty,
+		Status:            models.OrderStatusOpen,
+		CreatedAt:         time.Now(),
+	}
+}
+
+// runMatchBenchmark rebuilds the book outside the timer on every iteration so
+// only Match itself is measured.
+func runMatchBenchmark(b *testing.B, levels, ordersPerLevel int, restingQty, incomingQty decimal.Decimal) {
+	matcher := NewMatcher()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		ob := benchBook(levels, ordersPerLevel, restingQty)
+		incoming := benchMarketBuy(incomingQty)
+		b.StartTimer()
+
+		matcher.Match(incoming, ob)
+	}
+}
+
+// BenchmarkMatcher_SingleFill: one incoming order fully fills one resting order
+// that stays partially on the book.
+func BenchmarkMatcher_SingleFill(b *testing.B) {
+	runMatchBenchmark(b, 10, 1, decimal.NewFromInt(2), decimal.NewFromInt(1))
+}
+
+// BenchmarkMatcher_MultiLevelSweep: a market order sweeps 20 of 50 levels.
+func BenchmarkMatcher_MultiLevelSweep(b *testing.B) {
+	runMatchBenchmark(b, 50, 1, decimal.NewFromInt(1), decimal.NewFromInt(20))
+}
+
+// BenchmarkMatcher_DeepPartial: a market order eats 50 of 200 orders queued at
+// the best price.
+func BenchmarkMatcher_DeepPartial(b *testing.B) {
+	runMatchBenchmark(b, 1, 200, decimal.NewFromInt(1), decimal.NewFromInt(50))
+}
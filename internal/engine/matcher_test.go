@@ -467,3 +467,222 @@ func TestMatcher_MarketLimitPriceRule(t *testing.T) {
 		t.Errorf("Expected trade price %s (limit order price), got %s", limitPrice.String(), trade.Price.String())
 	}
 }
+
+// TestMatcher_HigherTierMatchesFirst verifies a higher-tier order queues ahead of
+// an earlier lower-tier order at the same price, while FIFO holds within a tier.
+func TestMatcher_HigherTierMatchesFirst(t *testing.T) {
+	matcher := NewMatcher()
+	orderBook := NewOrderBook("BTCUSD")
+
+	sellPrice := decimal.NewFromInt(50000)
+	tiers := []int{0, 1, 1}
+	for i, tier := range tiers {
+		orderBook.AddOrder(&models.Order{
+			ID:                int64(i + 1),
+			Symbol:            "BTCUSD",
+			Side:              models.OrderSideSell,
+			Type:              models.OrderTypeLimit,
+			Price:             &sellPrice,
+			InitialQuantity:   decimal.NewFromFloat(0.5),
+			RemainingQuantity: decimal.NewFromFloat(0.5),
+			Status:            models.OrderStatusOpen,
+			Tier:              tier,
+			CreatedAt:         time.Now().Add(time.Duration(i-3) * time.Minute),
+		})
+	}
+
+	incomingOrder := &models.Order{
+		ID:                4,
+		Symbol:            "BTCUSD",
+		Side:              models.OrderSideBuy,
+		Type:              models.OrderTypeMarket,
+		InitialQuantity:   decimal.NewFromFloat(1.0),
+		RemainingQuantity: decimal.NewFromFloat(1.0),
+		Status:            models.OrderStatusOpen,
+		CreatedAt:         time.Now(),
+	}
+
+	result := matcher.Match(incomingOrder, orderBook)
+
+	if len(result.Trades) != 2 {
+		t.Fatalf("Expected 2 trades, got %d", len(result.Trades))
+	}
+	// Both tier-1 orders fill in arrival order before the earlier tier-0 order.
+	if result.Trades[0].SellOrderID != 2 || result.Trades[1].SellOrderID != 3 {
+		t.Errorf("Expected sell orders 2 then 3 to match, got %d then %d",
+			result.Trades[0].SellOrderID, result.Trades[1].SellOrderID)
+	}
+
+	bestAsk := orderBook.GetBestAsk()
+	if bestAsk == nil || bestAsk.ID != 1 {
+		t.Errorf("Expected tier-0 order 1 to remain at the head of the level")
+	}
+}
+
+// TestOrderBook_LevelsStaySortedAcrossAddRemove adds levels out of price order
+// and empties some, checking both sides keep best-first ordering.
+func TestOrderBook_LevelsStaySortedAcrossAddRemove(t *testing.T) {
+	orderBook := NewOrderBook("BTCUSD")
+
+	// IDs 5 and 6 are the bid and ask at 50000.
+	prices := []int64{50200, 49800, 50000, 50100, 49900}
+	nextID := int64(1)
+	for _, p := range prices {
+		for _, side := range []models.OrderSide{models.OrderSideBuy, models.OrderSideSell} {
+			price := decimal.NewFromInt(p)
+			orderBook.AddOrder(&models.Order{
+				ID:                nextID,
+				Symbol:            "BTCUSD",
+				Side:              side,
+				Type:              models.OrderTypeLimit,
+				Price:             &price,
+				InitialQuantity:   decimal.NewFromInt(1),
+				RemainingQuantity: decimal.NewFromInt(1),
+				Status:            models.OrderStatusOpen,
+			})
+			nextID++
+		}
+	}
+
+	// Empty the 50000 level on both sides.
+	removed := decimal.NewFromInt(50000)
+	if !orderBook.RemoveOrder(5, models.OrderSideBuy, &removed) {
+		t.Fatal("Expected bid at 50000 to be removed")
+	}
+	if !orderBook.RemoveOrder(6, models.OrderSideSell, &removed) {
+		t.Fatal("Expected ask at 50000 to be removed")
+	}
+
+	bids, asks := orderBook.GetAggregatedLevels(10)
+	expectedBids := []int64{50200, 50100, 49900, 49800}
+	expectedAsks := []int64{49800, 49900, 50100, 50200}
+
+	if len(bids) != len(expectedBids) || len(asks) != len(expectedAsks) {
+		t.Fatalf("Expected %d bids and %d asks, got %d and %d", len(expectedBids), len(expectedAsks), len(bids), len(asks))
+	}
+	for i, p := range expectedBids {
+		if !bids[i].Price.Equal(decimal.NewFromInt(p)) {
+			t.Errorf("Bid level %d: expected %d, got %s", i, p, bids[i].Price.String())
+		}
+	}
+	for i, p := range expectedAsks {
+		if !asks[i].Price.Equal(decimal.NewFromInt(p)) {
+			t.Errorf("Ask level %d: expected %d, got %s", i, p, asks[i].Price.String())
+		}
+	}
+
+	if best := orderBook.GetBestBid(); best == nil || !best.Price.Equal(decimal.NewFromInt(50200)) {
+		t.Errorf("Expected best bid at 50200, got %v", best)
+	}
+	if best := orderBook.GetBestAsk(); best == nil || !best.Price.Equal(decimal.NewFromInt(49800)) {
+		t.Errorf("Expected best ask at 49800, got %v", best)
+	}
+}
+
+// TestOrderBook_EquivalentPricesShareLevel rests orders at 50000, 50000.00
+// and 5E4 and checks they form one level that matches in arrival order.
+func TestOrderBook_EquivalentPricesShareLevel(t *testing.T) {
+	matcher := NewMatcher()
+	orderBook := NewOrderBook("BTCUSD")
+
+	for i, raw := range []string{"50000", "50000.00", "5E4"} {
+		price := decimal.RequireFromString(raw)
+		orderBook.AddOrder(&models.Order{
+			ID:                int64(i + 1),
+			Symbol:            "BTCUSD",
+			Side:              models.OrderSideSell,
+			Type:              models.OrderTypeLimit,
+			Price:             &price,
+			InitialQuantity:   decimal.NewFromInt(1),
+			RemainingQuantity: decimal.NewFromInt(1),
+			Status:            models.OrderStatusOpen,
+		})
+	}
+
+	_, asks := orderBook.GetAggregatedLevelsWithCounts(10)
+	if len(asks) != 1 || asks[0].OrderCount != 3 || !asks[0].Quantity.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("Expected one ask level with 3 orders totalling 3, got %+v", asks)
+	}
+
+	buyPrice := decimal.RequireFromString("50000.000")
+	result := matcher.Match(&models.Order{
+		ID:                4,
+		Symbol:            "BTCUSD",
+		Side:              models.OrderSideBuy,
+		Type:              models.OrderTypeLimit,
+		Price:             &buyPrice,
+		InitialQuantity:   decimal.NewFromInt(3),
+		RemainingQuantity: decimal.NewFromInt(3),
+		Status:            models.OrderStatusOpen,
+	}, orderBook)
+
+	if len(result.Trades) != 3 {
+		t.Fatalf("Expected 3 trades, got %d", len(result.Trades))
+	}
+	for i, trade := range result.Trades {
+		if trade.SellOrderID != int64(i+1) {
+			t.Errorf("Trade %d: expected sell order %d, got %d", i, i+1, trade.SellOrderID)
+		}
+	}
+	if _, asks := orderBook.GetAggregatedLevels(10); len(asks) != 0 {
+		t.Errorf("Expected the level to be emptied and removed, got %+v", asks)
+	}
+}
+
+// TestOrderBook_OldestOrderAge checks each level reports the age of its FIFO
+// head, and that the age moves to the next order once the head fills.
+func TestOrderBook_OldestOrderAge(t *testing.T) {
+	matcher := NewMatcher()
+	orderBook := NewOrderBook("BTCUSD")
+	now := time.Now()
+
+	add := func(id int64, side models.OrderSide, price int64, age time.Duration) {
+		p := decimal.NewFromInt(price)
+		orderBook.AddOrder(&models.Order{
+			ID:                id,
+			Symbol:            "BTCUSD",
+			Side:              side,
+			Type:              models.OrderTypeLimit,
+			Price:             &p,
+			InitialQuantity:   decimal.NewFromInt(1),
+			RemainingQuantity: decimal.NewFromInt(1),
+			Status:            models.OrderStatusOpen,
+			CreatedAt:         now.Add(-age),
+		})
+	}
+	add(1, models.OrderSideSell, 50000, 90*time.Second)
+	add(2, models.OrderSideSell, 50000, 30*time.Second)
+	add(3, models.OrderSideSell, 50100, 10*time.Second)
+	add(4, models.OrderSideBuy, 49900, 5*time.Minute)
+
+	bids, asks := orderBook.GetAggregatedLevelsDetailed(10, LevelDetail{OldestAgeAt: now})
+	expect := func(level models.OrderBookLevel, seconds float64) {
+		t.Helper()
+		if level.OldestOrderAge == nil || *level.OldestOrderAge != seconds {
+			t.Errorf("Level %s: expected oldest order age %v, got %v", level.Price, seconds, level.OldestOrderAge)
+		}
+	}
+	if len(bids) != 1 || len(asks) != 2 {
+		t.Fatalf("Expected 1 bid and 2 ask levels, got %d and %d", len(bids), len(asks))
+	}
+	expect(bids[0], 300)
+	expect(asks[0], 90)
+	expect(asks[1], 10)
+
+	matcher.Match(&models.Order{
+		ID:                5,
+		Symbol:            "BTCUSD",
+		Side:              models.OrderSideBuy,
+		Type:              models.OrderTypeMarket,
+		InitialQuantity:   decimal.NewFromInt(1),
+		RemainingQuantity: decimal.NewFromInt(1),
+		Status:            models.OrderStatusOpen,
+	}, orderBook)
+
+	_, asks = orderBook.GetAggregatedLevelsDetailed(10, LevelDetail{OldestAgeAt: now})
+	expect(asks[0], 30)
+
+	if _, asks := orderBook.GetAggregatedLevels(10); asks[0].OldestOrderAge != nil {
+		t.Error("Expected no age unless requested")
+	}
+}
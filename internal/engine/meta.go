@@ -0,0 +1,34 @@
+package engine
+
+import "order-matching-engine/internal/models"
+
+// maxAssetCodeLength bounds the asset and currency codes of a symbol.
+const maxAssetCodeLength = 16
+
+// validAssetCode reports whether code is 1-16 letters and digits.
+func validAssetCode(code string) bool {
+	if code == "" || len(code) > maxAssetCodeLength {
+		return false
+	}
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// SymbolMeta returns the display metadata registered for symbol in the
+// symbols config. Settings that aren't configured, or all of them for an
+// unregistered symbol, are left empty.
+func (e *Engine) SymbolMeta(symbol string) models.SymbolMeta {
+	sc, _ := e.config.symbolConfig(symbol)
+	return models.SymbolMeta{
+		Symbol:            symbol,
+		BaseAsset:         sc.BaseAsset,
+		QuoteAsset:        sc.QuoteAsset,
+		DisplayCurrency:   sc.DisplayCurrency,
+		PricePrecision:    sc.PricePrecision,
+		QuantityPrecision: sc.QuantityPrecision,
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-matching-engine/internal/models"
+)
+
+// TestEngine_SymbolMeta returns the registered symbol definition as
+// metadata, which book and trade responses carry under "meta".
+func TestEngine_SymbolMeta(t *testing.T) {
+	pricePrecision, quantityPrecision := int32(2), int32(6)
+	cfg := DefaultConfig()
+	cfg.Symbols = map[string]SymbolConfig{
+		"BTCUSD": {
+			BaseAsset:         "BTC",
+			QuoteAsset:        "USD",
+			DisplayCurrency:   "EUR",
+			PricePrecision:    &pricePrecision,
+			QuantityPrecision: &quantityPrecision,
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	eng, _ := newFakeEngine(t, cfg)
+
+	meta := eng.SymbolMeta("BTCUSD")
+	assert.Equal(t, models.SymbolMeta{
+		Symbol:            "BTCUSD",
+		BaseAsset:         "BTC",
+		QuoteAsset:        "USD",
+		DisplayCurrency:   "EUR",
+		PricePrecision:    &pricePrecision,
+		QuantityPrecision: &quantityPrecision,
+	}, meta)
+	assert.Equal(t, models.SymbolMeta{Symbol: "ETHUSD"}, eng.SymbolMeta("ETHUSD"), "unregistered symbols have no metadata")
+
+	bids, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	body, err := json.Marshal(models.OrderBookResponse{Symbol: "BTCUSD", Bids: bids, Asks: asks, Meta: &meta})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"symbol":"BTCUSD","bids":[],"asks":[],"meta":{"symbol":"BTCUSD","base_asset":"BTC","quote_asset":"USD","display_currency":"EUR","price_precision":2,"quantity_precision":6}}`, string(body))
+	body, err = json.Marshal(models.TradeResponse{Trades: []models.Trade{}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"trades":[]}`, string(body), "meta is omitted unless requested")
+
+	cfg.Symbols["BTCUSD"] = SymbolConfig{DisplayCurrency: "US DOLLAR"}
+	assert.ErrorContains(t, cfg.Validate(), "display_currency")
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// OtherSymbolLabel is the symbol label under which trades on symbols without
+// their own label are counted, keeping metric cardinality bounded.
+const OtherSymbolLabel = "_other"
+
+// WriteMetrics writes engine metrics in the Prometheus text exposition format.
+func (e *Engine) WriteMetrics(w io.Writer) error {
+	paused := 0
+	if e.IsAutoPaused() {
+		paused = 1
+	}
+	if _, err := fmt.Fprintf(w,
+		"# HELP engine_auto_paused Whether order placement is paused by the commit latency guard (1 = paused).\n"+
+			"# TYPE engine_auto_paused gauge\n"+
+			"engine_auto_paused %d\n", paused); err != nil {
+		return err
+	}
+
+	if e.latencyMonitor != nil {
+		if _, err := fmt.Fprintf(w,
+			"# HELP engine_commit_latency_seconds Average latency of the most recent DB commits.\n"+
+				"# TYPE engine_commit_latency_seconds gauge\n"+
+				"engine_commit_latency_seconds %g\n", e.latencyMonitor.Average().Seconds()); err != nil {
+			return err
+		}
+	}
+	if e.watchdog != nil {
+		if err := e.writeWatchdogMetrics(w); err != nil {
+			return err
+		}
+	}
+	return e.symbolMetrics.write(w)
+}
+
+// symbolTradeCounters are the counters kept for one symbol label.
+type symbolTradeCounters struct {
+	trades uint64
+	volume decimal.Decimal
+}
+
+// symbolTradeMetrics counts committed trades and traded base quantity per
+// symbol label. A symbol gets its own label if it is allowlisted or, with no
+// allowlist, if fewer than limit symbols have one already; all others share
+// OtherSymbolLabel.
+type symbolTradeMetrics struct {
+	allowed map[string]bool // nil when there is no allowlist
+	limit   int
+
+	mutex    sync.Mutex
+	counters map[string]*symbolTradeCounters
+}
+
+// newSymbolTradeMetrics returns counters for the given allowlist and label limit.
+func newSymbolTradeMetrics(allowlist []string, limit int) *symbolTradeMetrics {
+	m := &symbolTradeMetrics{limit: limit, counters: make(map[string]*symbolTradeCounters)}
+	if len(allowlist) > 0 {
+		m.allowed = make(map[string]bool, len(allowlist))
+		for _, symbol := range allowlist {
+			m.allowed[symbol] = true
+		}
+	}
+	return m
+}
+
+// record adds trades executed on symbol.
+func (m *symbolTradeMetrics) record(symbol string, trades []models.Trade) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	c := m.countersFor(symbol)
+	for _, trade := range trades {
+		c.trades++
+		c.volume = c.volume.Add(trade.Quantity)
+	}
+}
+
+// countersFor returns the counters for symbol's label, assigning a label on
+// first use. Caller holds the mutex.
+func (m *symbolTradeMetrics) countersFor(symbol string) *symbolTradeCounters {
+	if c, ok := m.counters[symbol]; ok {
+		return c
+	}
+	label := symbol
+	if m.allowed != nil && !m.allowed[symbol] {
+		label = OtherSymbolLabel
+	}
+	// The other bucket does not count towards the limit.
+	if m.allowed == nil && len(m.counters)-m.hasOther() >= m.limit {
+		label = OtherSymbolLabel
+	}
+	c, ok := m.counters[label]
+	if !ok {
+		c = &symbolTradeCounters{}
+		m.counters[label] = c
+	}
+	return c
+}
+
+// hasOther is 1 if the other bucket exists. Caller holds the mutex.
+func (m *symbolTradeMetrics) hasOther() int {
+	if _, ok := m.counters[OtherSymbolLabel]; ok {
+		return 1
+	}
+	return 0
+}
+
+// write emits engine_trades_total and engine_volume_total, one sample per
+// symbol label in label order.
+func (m *symbolTradeMetrics) write(w io.Writer) error {
+	m.mutex.Lock()
+	labels := make([]string, 0, len(m.counters))
+	for label := range m.counters {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	snapshot := make([]symbolTradeCounters, len(labels))
+	for i, label := range labels {
+		snapshot[i] = *m.counters[label]
+	}
+	m.mutex.Unlock()
+
+	if _, err := fmt.Fprint(w,
+		"# HELP engine_trades_total Trades executed, by symbol.\n"+
+			"# TYPE engine_trades_total counter\n"); err != nil {
+		return err
+	}
+	for i, label := range labels {
+		if _, err := fmt.Fprintf(w, "engine_trades_total{symbol=%q} %d\n", label, snapshot[i].trades); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(w,
+		"# HELP engine_volume_total Base quantity traded, by symbol.\n"+
+			"# TYPE engine_volume_total counter\n"); err != nil {
+		return err
+	}
+	for i, label := range labels {
+		if _, err := fmt.Fprintf(w, "engine_volume_total{symbol=%q} %s\n", label, snapshot[i].volume); err != nil {
+			return err
+		}
+	}
+	return nil
+}
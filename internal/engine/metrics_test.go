@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_SymbolTradeMetrics checks trades on two symbols increment their
+// own labeled counters and that symbols past the label limit share the
+// other label.
+func TestEngine_SymbolTradeMetrics(t *testing.T) {
+	trade := func(eng *Engine, symbol string, price, qty float64) {
+		_, _, err := eng.PlaceOrder(limitRequest(symbol, models.OrderSideSell, price, qty))
+		require.NoError(t, err)
+		_, trades, err := eng.PlaceOrder(marketRequest(symbol, models.OrderSideBuy, qty))
+		require.NoError(t, err)
+		require.Len(t, trades, 1)
+	}
+	scrape := func(eng *Engine) string {
+		var out bytes.Buffer
+		require.NoError(t, eng.WriteMetrics(&out))
+		return out.String()
+	}
+
+	eng, _ := newFakeEngine(t, DefaultConfig())
+	trade(eng, "BTCUSD", 50000, 1.5)
+	trade(eng, "BTCUSD", 50000, 0.25)
+	trade(eng, "ETHUSD", 3000, 4)
+
+	metrics := scrape(eng)
+	assert.Contains(t, metrics, `engine_trades_total{symbol="BTCUSD"} 2`)
+	assert.Contains(t, metrics, `engine_trades_total{symbol="ETHUSD"} 1`)
+	assert.Contains(t, metrics, `engine_volume_total{symbol="BTCUSD"} 1.75`)
+	assert.Contains(t, metrics, `engine_volume_total{symbol="ETHUSD"} 4`)
+	assert.NotContains(t, metrics, OtherSymbolLabel)
+
+	cfg := DefaultConfig()
+	cfg.MetricsSymbolLimit = 1
+	eng, _ = newFakeEngine(t, cfg)
+	trade(eng, "BTCUSD", 50000, 1)
+	trade(eng, "ETHUSD", 3000, 2)
+	trade(eng, "SOLUSD", 150, 3)
+
+	metrics = scrape(eng)
+	assert.Contains(t, metrics, `engine_trades_total{symbol="BTCUSD"} 1`)
+	assert.Contains(t, metrics, `engine_trades_total{symbol="_other"} 2`)
+	assert.Contains(t, metrics, `engine_volume_total{symbol="_other"} 5`)
+	assert.NotContains(t, metrics, "ETHUSD")
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"order-matching-engine/internal/models"
+)
+
+// orderIDSeparator joins an order ID namespace and the numeric ID.
+const orderIDSeparator = "-"
+
+// maxOrderIDNamespaceLength bounds Config.OrderIDNamespace.
+const maxOrderIDNamespaceLength = 32
+
+// validOrderIDNamespace reports whether ns is 1 to 32 letters, digits or
+// underscores, so it cannot contain the separator.
+func validOrderIDNamespace(ns string) bool {
+	if ns == "" || len(ns) > maxOrderIDNamespaceLength {
+		return false
+	}
+	for _, r := range ns {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// formatGlobalOrderID returns the composite ID "<ns>-<orderID>", or "" when
+// ns is empty.
+func formatGlobalOrderID(ns string, orderID int64) string {
+	if ns == "" {
+		return ""
+	}
+	return ns + orderIDSeparator + strconv.FormatInt(orderID, 10)
+}
+
+// globalIDNamespace returns the namespace of composite ID globalID, or ""
+// when it is empty or malformed.
+func globalIDNamespace(globalID string) string {
+	ns, _, ok := strings.Cut(globalID, orderIDSeparator)
+	if !ok || !validOrderIDNamespace(ns) {
+		return ""
+	}
+	return ns
+}
+
+// GlobalOrderID returns the composite ID orderID gets in the configured
+// order_id_namespace, or "" when none is configured.
+func (e *Engine) GlobalOrderID(orderID int64) string {
+	return formatGlobalOrderID(e.config.OrderIDNamespace, orderID)
+}
+
+// parseOrderRef splits a client-given order ID into its numeric ID and, for
+// a composite ID, its namespace.
+func parseOrderRef(s string) (id int64, ns string, err error) {
+	if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return id, "", nil
+	}
+	ns, seq, ok := strings.Cut(s, orderIDSeparator)
+	if ok {
+		id, err = strconv.ParseInt(seq, 10, 64)
+	}
+	if !ok || err != nil || !validOrderIDNamespace(ns) {
+		return 0, "", fmt.Errorf("%w: %q", ErrInvalidOrderID, s)
+	}
+	return id, ns, nil
+}
+
+// ParseOrderID resolves an order ID given by a client: a plain numeric ID,
+// or a composite ID, which must name the namespace its order was placed
+// under. A composite ID matching no order that way returns ErrForeignOrderID.
+func (e *Engine) ParseOrderID(s string) (int64, error) {
+	id, ns, err := parseOrderRef(s)
+	if err != nil || ns == "" {
+		return id, err
+	}
+	var stored sql.NullString
+	err = e.db.QueryRow(`SELECT id_namespace FROM orders WHERE id = ?`, id).Scan(&stored)
+	if err == sql.ErrNoRows || err == nil && stored.String != ns {
+		return 0, fmt.Errorf("%w: no order %s", ErrForeignOrderID, s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up order %s: %w", s, err)
+	}
+	return id, nil
+}
+
+// setGlobalID fills in order's composite ID from its namespace.
+func (e *Engine) setGlobalID(order *models.Order) {
+	order.GlobalID = formatGlobalOrderID(order.IDNamespace, order.ID)
+}
+
+// nullableString stores an empty namespace or composite ID as NULL.
+func nullableString(ns string) interface{} {
+	if ns == "" {
+		return nil
+	}
+	return ns
+}
+
+// setTradeGlobalIDs fills in the counterparties' composite IDs on trades
+// between incoming and the resting orders it matched, each in the namespace
+// its order was placed under.
+func setTradeGlobalIDs(trades []models.Trade, incoming *models.Order, resting []*models.Order) {
+	globalID := func(id int64) string {
+		if id == incoming.ID {
+			return formatGlobalOrderID(incoming.IDNamespace, id)
+		}
+		for _, o := range resting {
+			if o.ID == id {
+				return formatGlobalOrderID(o.IDNamespace, id)
+			}
+		}
+		return ""
+	}
+	for i := range trades {
+		trades[i].BuyGlobalOrderID = globalID(trades[i].BuyOrderID)
+		trades[i].SellGlobalOrderID = globalID(trades[i].SellOrderID)
+	}
+}
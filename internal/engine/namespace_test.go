@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_OrderIDNamespace checks orders are stored with the configured
+// namespace and carry a composite ID, trades carry both counterparties'
+// composite IDs, and composite IDs resolve for lookups and cancels only in
+// the namespace their order was placed under.
+func TestEngine_OrderIDNamespace(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OrderIDNamespace = "nyc_1"
+	eng, fdb := newFakeEngine(t, cfg)
+
+	placed, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	globalID := fmt.Sprintf("nyc_1-%d", placed.ID)
+	assert.Equal(t, globalID, placed.GlobalID)
+	inserts := fdb.ExecsMatching("INSERT INTO orders")
+	require.Len(t, inserts, 1)
+	assert.Equal(t, "nyc_1", inserts[0].Args[0], "the namespace is stored with the order")
+
+	maker, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 49000, 1))
+	require.NoError(t, err)
+	taker, trades, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 1))
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, taker.GlobalID, trades[0].BuyGlobalOrderID)
+	assert.Equal(t, maker.GlobalID, trades[0].SellGlobalOrderID)
+	tradeInserts := fdb.ExecsMatching("INSERT INTO trades")
+	require.Len(t, tradeInserts, 1)
+	assert.Equal(t, taker.GlobalID, tradeInserts[0].Args[3])
+	assert.Equal(t, maker.GlobalID, tradeInserts[0].Args[4])
+
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if args[0] != placed.ID {
+			return nil, nil
+		}
+		if strings.Contains(query, "SELECT id_namespace") {
+			return &fakeRows{Cols: []string{"id_namespace"}, Rows: [][]driver.Value{{"nyc_1"}}}, nil
+		}
+		if !strings.Contains(query, "WHERE id = ?") {
+			return nil, nil
+		}
+		created := time.Now()
+		return &fakeRows{
+			Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+			Rows: [][]driver.Value{{placed.ID, nil, nil, "BTCUSD", "sell", "limit", "50000", "1", "1", "open", int64(0), created, created, nil, nil, "nyc_1"}},
+		}, nil
+	}
+
+	id, err := eng.ParseOrderID(globalID)
+	require.NoError(t, err)
+	assert.Equal(t, placed.ID, id)
+	id, err = eng.ParseOrderID(fmt.Sprint(placed.ID))
+	require.NoError(t, err, "plain numeric IDs are still accepted")
+	assert.Equal(t, placed.ID, id)
+
+	order, err := eng.GetOrder(id)
+	require.NoError(t, err)
+	assert.Equal(t, globalID, order.GlobalID)
+	canceled, err := eng.CancelOrder(id)
+	require.NoError(t, err)
+	assert.Equal(t, globalID, canceled.GlobalID)
+
+	_, err = eng.ParseOrderID(fmt.Sprintf("ldn_2-%d", placed.ID))
+	assert.ErrorIs(t, err, ErrForeignOrderID)
+	_, err = eng.ParseOrderID(fmt.Sprintf("nyc_1-%d", placed.ID+100))
+	assert.ErrorIs(t, err, ErrForeignOrderID, "no such order")
+	for _, bad := range []string{"nyc_1-", "nyc_1-x", "abc", "bad ns-5"} {
+		_, err = eng.ParseOrderID(bad)
+		assert.ErrorIs(t, err, ErrInvalidOrderID, bad)
+	}
+
+	// The namespace is read from the order, so its composite ID keeps
+	// resolving after the instance is reconfigured without one.
+	plain, pfdb := newFakeEngine(t, DefaultConfig())
+	pfdb.queryHook = fdb.queryHook
+	id, err = plain.ParseOrderID(globalID)
+	require.NoError(t, err)
+	assert.Equal(t, placed.ID, id)
+	order, err = plain.GetOrder(id)
+	require.NoError(t, err)
+	assert.Equal(t, globalID, order.GlobalID)
+
+	placed, _, err = plain.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	assert.Empty(t, placed.GlobalID, "numeric IDs only by default")
+	assert.Nil(t, pfdb.ExecsMatching("INSERT INTO orders")[0].Args[0])
+
+	cfg.OrderIDNamespace = "has-dash"
+	assert.Error(t, cfg.Validate())
+}
+
+// TestEngine_GetOrderStatusesCompositeIDs checks a bulk lookup accepts
+// numeric and composite IDs, matches a composite ID only in its order's
+// namespace, and echoes unmatched IDs as given.
+func TestEngine_GetOrderStatusesCompositeIDs(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "SELECT id, id_namespace, status") {
+			return nil, nil
+		}
+		return &fakeRows{
+			Cols: []string{"id", "id_namespace", "status", "remaining_quantity"},
+			Rows: [][]driver.Value{
+				{int64(5), "nyc_1", "open", "1"},
+				{int64(7), nil, "filled", "0"},
+			},
+		}, nil
+	}
+
+	found, notFound, err := eng.GetOrderStatuses([]models.OrderRef{"nyc_1-5", "ldn_2-5", "7", "nyc_1-7", "8", "5"})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, int64(5), found[0].OrderID)
+	assert.Equal(t, "nyc_1-5", found[0].GlobalOrderID)
+	assert.Equal(t, models.OrderStatusOpen, found[0].Status)
+	assert.Equal(t, int64(7), found[1].OrderID)
+	assert.Empty(t, found[1].GlobalOrderID)
+	assert.Equal(t, []models.OrderRef{"ldn_2-5", "nyc_1-7", "8"}, notFound)
+
+	_, _, err = eng.GetOrderStatuses([]models.OrderRef{"5", "nyc_1-x"})
+	assert.ErrorIs(t, err, ErrInvalidOrderID)
+}
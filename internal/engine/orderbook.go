@@ -3,21 +3,31 @@ package engine
 import (
 	"sort"
 	"sync"
+	"time"
 
 	"order-matching-engine/internal/models"
 
 	"github.com/shopspring/decimal"
 )
 
-// PriceLevel is a FIFO queue of orders at a specific price.
+// PriceLevel is a queue of orders at a specific price, ordered by priority
+// tier (highest first) and FIFO within a tier.
 type PriceLevel struct {
 	Price  decimal.Decimal
 	Orders []*models.Order
 }
 
-// Add appends an order to the end of the price level (FIFO).
+// Add queues an order behind every order of the same or a higher tier, so
+// higher tiers jump ahead of lower ones while FIFO holds within a tier.
+// With all orders at tier 0 this is a plain append.
 func (pl *PriceLevel) Add(order *models.Order) {
-	pl.Orders = append(pl.Orders, order)
+	i := len(pl.Orders)
+	for i > 0 && pl.Orders[i-1].Tier < order.Tier {
+		i--
+	}
+	pl.Orders = append(pl.Orders, nil)
+	copy(pl.Orders[i+1:], pl.Orders[i:])
+	pl.Orders[i] = order
 }
 
 // Remove removes an order by ID and preserves FIFO order.
@@ -37,6 +47,19 @@ func (pl *PriceLevel) IsEmpty() bool {
 	return len(pl.Orders) == 0
 }
 
+// oldestCreatedAt returns the earliest CreatedAt at this level. Without
+// priority tiers that is the FIFO head; a higher tier can queue a newer
+// order ahead of it.
+func (pl *PriceLevel) oldestCreatedAt() time.Time {
+	oldest := pl.Orders[0].CreatedAt
+	for _, order := range pl.Orders[1:] {
+		if order.CreatedAt.Before(oldest) {
+			oldest = order.CreatedAt
+		}
+	}
+	return oldest
+}
+
 // GetTotalQuantity sums remaining quantities at this price level.
 func (pl *PriceLevel) GetTotalQuantity() decimal.Decimal {
 	total := decimal.Zero
@@ -51,23 +74,37 @@ func (pl *PriceLevel) GetTotalQuantity() decimal.Decimal {
 type OrderBook struct {
 	Symbol string
 
-	// Price lookup: string key is price.String()
+	// Price lookup: string key is priceKey(price)
 	Bids map[string]*PriceLevel // bids indexed by price (descending)
 	Asks map[string]*PriceLevel // asks indexed by price (ascending)
 
-	// Cached sorted price slices for iteration (bidPrices: desc, askPrices: asc)
-	bidPrices []decimal.Decimal
-	askPrices []decimal.Decimal
+	// Sorted level slices for iteration (bidLevels: desc, askLevels: asc).
+	// They hold the same *PriceLevel as the maps, so the best level is read
+	// without a map lookup and an emptied level is dropped in place.
+	bidLevels []*PriceLevel
+	askLevels []*PriceLevel
+
+	// emptySince is when the book was created or last lost its final
+	// order. It is only meaningful while the book is empty.
+	emptySince time.Time
 
 	mutex sync.RWMutex
 }
 
+// priceKey returns the level key for price. Equal prices get the same key
+// whatever their scale: String drops trailing fractional zeros, so 50000,
+// 50000.00 and 5E4 are all "50000".
+func priceKey(price decimal.Decimal) string {
+	return price.String()
+}
+
 // NewOrderBook constructs an OrderBook for the given symbol.
 func NewOrderBook(symbol string) *OrderBook {
 	return &OrderBook{
-		Symbol: symbol,
-		Bids:   make(map[string]*PriceLevel),
-		Asks:   make(map[string]*PriceLevel),
+		Symbol:     symbol,
+		Bids:       make(map[string]*PriceLevel),
+		Asks:       make(map[string]*PriceLevel),
+		emptySince: time.Now(),
 	}
 }
 
@@ -80,22 +117,26 @@ func (ob *OrderBook) AddOrder(order *models.Order) {
 	if order.Price == nil {
 		return
 	}
-	priceKey := order.Price.String()
+	key := priceKey(*order.Price)
 
 	if order.Side == models.OrderSideBuy {
-		if ob.Bids[priceKey] == nil {
-			ob.Bids[priceKey] = &PriceLevel{Price: *order.Price}
+		pl := ob.Bids[key]
+		if pl == nil {
+			pl = &PriceLevel{Price: *order.Price}
+			ob.Bids[key] = pl
+			ob.bidLevels = insertLevel(ob.bidLevels, pl, decimal.Decimal.GreaterThan)
 		}
-		ob.Bids[priceKey].Add(order)
-		ob.refreshBidPrices()
+		pl.Add(order)
 		return
 	}
 
-	if ob.Asks[priceKey] == nil {
-		ob.Asks[priceKey] = &PriceLevel{Price: *order.Price}
+	pl := ob.Asks[key]
+	if pl == nil {
+		pl = &PriceLevel{Price: *order.Price}
+		ob.Asks[key] = pl
+		ob.askLevels = insertLevel(ob.askLevels, pl, decimal.Decimal.LessThan)
 	}
-	ob.Asks[priceKey].Add(order)
-	ob.refreshAskPrices()
+	pl.Add(order)
 }
 
 // RemoveOrder deletes an order from the book by ID, side and price.
@@ -107,14 +148,15 @@ func (ob *OrderBook) RemoveOrder(orderID int64, side models.OrderSide, price *de
 	if price == nil {
 		return false
 	}
-	priceKey := price.String()
+	key := priceKey(*price)
 
 	if side == models.OrderSideBuy {
-		if pl := ob.Bids[priceKey]; pl != nil {
+		if pl := ob.Bids[key]; pl != nil {
 			if pl.Remove(orderID) {
 				if pl.IsEmpty() {
-					delete(ob.Bids, priceKey)
-					ob.refreshBidPrices()
+					delete(ob.Bids, key)
+					ob.bidLevels = removeLevel(ob.bidLevels, pl)
+					ob.markIfEmpty()
 				}
 				return true
 			}
@@ -122,11 +164,12 @@ func (ob *OrderBook) RemoveOrder(orderID int64, side models.OrderSide, price *de
 		return false
 	}
 
-	if pl := ob.Asks[priceKey]; pl != nil {
+	if pl := ob.Asks[key]; pl != nil {
 		if pl.Remove(orderID) {
 			if pl.IsEmpty() {
-				delete(ob.Asks, priceKey)
-				ob.refreshAskPrices()
+				delete(ob.Asks, key)
+				ob.askLevels = removeLevel(ob.askLevels, pl)
+				ob.markIfEmpty()
 			}
 			return true
 		}
@@ -134,19 +177,34 @@ func (ob *OrderBook) RemoveOrder(orderID int64, side models.OrderSide, price *de
 	return false
 }
 
+// markIfEmpty stamps emptySince once the last level is gone. Caller holds
+// the write lock.
+func (ob *OrderBook) markIfEmpty() {
+	if len(ob.bidLevels) == 0 && len(ob.askLevels) == 0 {
+		ob.emptySince = time.Now()
+	}
+}
+
+// idleSince reports whether the book is empty and, if so, since when.
+func (ob *OrderBook) idleSince() (time.Time, bool) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	if len(ob.bidLevels) > 0 || len(ob.askLevels) > 0 {
+		return time.Time{}, false
+	}
+	return ob.emptySince, true
+}
+
 // GetBestBid returns the first (oldest) order at the highest bid price, or nil.
 func (ob *OrderBook) GetBestBid() *models.Order {
 	ob.mutex.RLock()
 	defer ob.mutex.RUnlock()
 
-	if len(ob.bidPrices) == 0 {
+	if len(ob.bidLevels) == 0 || len(ob.bidLevels[0].Orders) == 0 {
 		return nil
 	}
-	bestPrice := ob.bidPrices[0]
-	if pl := ob.Bids[bestPrice.String()]; pl != nil && len(pl.Orders) > 0 {
-		return pl.Orders[0]
-	}
-	return nil
+	return ob.bidLevels[0].Orders[0]
 }
 
 // GetBestAsk returns the first (oldest) order at the lowest ask price, or nil.
@@ -154,14 +212,37 @@ func (ob *OrderBook) GetBestAsk() *models.Order {
 	ob.mutex.RLock()
 	defer ob.mutex.RUnlock()
 
-	if len(ob.askPrices) == 0 {
+	if len(ob.askLevels) == 0 || len(ob.askLevels[0].Orders) == 0 {
 		return nil
 	}
-	bestPrice := ob.askPrices[0]
-	if pl := ob.Asks[bestPrice.String()]; pl != nil && len(pl.Orders) > 0 {
-		return pl.Orders[0]
+	return ob.askLevels[0].Orders[0]
+}
+
+// levelCount returns the number of price levels on one side of the book.
+func (ob *OrderBook) levelCount(side models.OrderSide) int {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	if side == models.OrderSideBuy {
+		return len(ob.bidLevels)
 	}
-	return nil
+	return len(ob.askLevels)
+}
+
+// levelQuantity returns the total quantity resting on side at price, zero if
+// there is no such level.
+func (ob *OrderBook) levelQuantity(side models.OrderSide, price decimal.Decimal) decimal.Decimal {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	levels := ob.Asks
+	if side == models.OrderSideBuy {
+		levels = ob.Bids
+	}
+	if pl := levels[priceKey(price)]; pl != nil {
+		return pl.GetTotalQuantity()
+	}
+	return decimal.Zero
 }
 
 // GetTopLevels returns up to depth aggregated price levels for each side.
@@ -170,54 +251,221 @@ func (ob *OrderBook) GetTopLevels(depth int) (bids []PriceLevel, asks []PriceLev
 	ob.mutex.RLock()
 	defer ob.mutex.RUnlock()
 
-	bidCount := depth
-	if bidCount > len(ob.bidPrices) {
-		bidCount = len(ob.bidPrices)
+	for _, pl := range topLevels(ob.bidLevels, depth) {
+		if !pl.IsEmpty() {
+			bids = append(bids, PriceLevel{Price: pl.Price})
+		}
 	}
-	for i := 0; i < bidCount; i++ {
-		price := ob.bidPrices[i]
-		if pl := ob.Bids[price.String()]; pl != nil && !pl.IsEmpty() {
-			bids = append(bids, PriceLevel{Price: price})
+	for _, pl := range topLevels(ob.askLevels, depth) {
+		if !pl.IsEmpty() {
+			asks = append(asks, PriceLevel{Price: pl.Price})
 		}
 	}
+	return bids, asks
+}
+
+// GetAggregatedLevels returns up to depth levels per side with the total
+// remaining quantity at each price, best prices first.
+func (ob *OrderBook) GetAggregatedLevels(depth int) (bids, asks []models.OrderBookLevel) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	return aggregateLevels(ob.bidLevels, depth, LevelDetail{}), aggregateLevels(ob.askLevels, depth, LevelDetail{})
+}
+
+// GetAggregatedLevelsWithCounts is GetAggregatedLevels with each level's
+// OrderCount set to the number of orders resting there.
+func (ob *OrderBook) GetAggregatedLevelsWithCounts(depth int) (bids, asks []models.OrderBookLevel) {
+	return ob.GetAggregatedLevelsDetailed(depth, LevelDetail{Counts: true})
+}
+
+// LevelDetail selects the optional fields of aggregated book levels.
+type LevelDetail struct {
+	// Counts sets OrderCount.
+	Counts bool
+	// OldestAgeAt, when non-zero, sets OldestOrderAge to the age at this
+	// time of the level's oldest resting order.
+	OldestAgeAt time.Time
+}
+
+// GetAggregatedLevelsDetailed is GetAggregatedLevels with the level fields
+// selected by detail.
+func (ob *OrderBook) GetAggregatedLevelsDetailed(depth int, detail LevelDetail) (bids, asks []models.OrderBookLevel) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	return aggregateLevels(ob.bidLevels, depth, detail), aggregateLevels(ob.askLevels, depth, detail)
+}
 
-	askCount := depth
-	if askCount > len(ob.askPrices) {
-		askCount = len(ob.askPrices)
+// aggregateLevels sums the first depth levels of one side, adding the
+// fields selected by detail. Caller holds the lock.
+func aggregateLevels(levels []*PriceLevel, depth int, detail LevelDetail) []models.OrderBookLevel {
+	top := topLevels(levels, depth)
+	out := make([]models.OrderBookLevel, 0, len(top))
+	for _, pl := range top {
+		if !pl.IsEmpty() {
+			level := models.OrderBookLevel{Price: pl.Price, Quantity: pl.GetTotalQuantity()}
+			if detail.Counts {
+				level.OrderCount = len(pl.Orders)
+			}
+			if !detail.OldestAgeAt.IsZero() {
+				age := detail.OldestAgeAt.Sub(pl.oldestCreatedAt()).Seconds()
+				level.OldestOrderAge = &age
+			}
+			out = append(out, level)
+		}
 	}
-	for i := 0; i < askCount; i++ {
-		price := ob.askPrices[i]
-		if pl := ob.Asks[price.String()]; pl != nil && !pl.IsEmpty() {
-			asks = append(asks, PriceLevel{Price: price})
+	return out
+}
+
+// Clone returns a deep copy of the book. Orders are copied so matching
+// against the clone never mutates orders resting in the original.
+func (ob *OrderBook) Clone() *OrderBook {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	clone := NewOrderBook(ob.Symbol)
+	clone.emptySince = ob.emptySince
+	cloneSide := func(dst map[string]*PriceLevel, src []*PriceLevel) []*PriceLevel {
+		levels := make([]*PriceLevel, len(src))
+		for i, pl := range src {
+			orders := make([]*models.Order, len(pl.Orders))
+			for j, o := range pl.Orders {
+				cp := *o
+				orders[j] = &cp
+			}
+			levels[i] = &PriceLevel{Price: pl.Price, Orders: orders}
+			dst[priceKey(pl.Price)] = levels[i]
 		}
+		return levels
 	}
-	return bids, asks
+	clone.bidLevels = cloneSide(clone.Bids, ob.bidLevels)
+	clone.askLevels = cloneSide(clone.Asks, ob.askLevels)
+	return clone
 }
 
-// refreshBidPrices rebuilds the cached bidPrices slice and sorts it descending.
-func (ob *OrderBook) refreshBidPrices() {
-	ob.bidPrices = make([]decimal.Decimal, 0, len(ob.Bids))
-	for _, pl := range ob.Bids {
-		if !pl.IsEmpty() {
-			ob.bidPrices = append(ob.bidPrices, pl.Price)
+// replaceContents gives ob the levels of src, which must not be used
+// afterwards.
+func (ob *OrderBook) replaceContents(src *OrderBook) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+	ob.Bids, ob.Asks = src.Bids, src.Asks
+	ob.bidLevels, ob.askLevels = src.bidLevels, src.askLevels
+	ob.emptySince = src.emptySince
+}
+
+// RestingOrders returns copies of every resting order, bids then asks, each
+// side best price first and in queue priority within a level. Adding them
+// to an empty book in this order reproduces the book.
+func (ob *OrderBook) RestingOrders() []models.Order {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	var orders []models.Order
+	for _, levels := range [][]*PriceLevel{ob.bidLevels, ob.askLevels} {
+		for _, pl := range levels {
+			for _, o := range pl.Orders {
+				orders = append(orders, *o)
+			}
 		}
 	}
-	sort.Slice(ob.bidPrices, func(i, j int) bool {
-		return ob.bidPrices[i].GreaterThan(ob.bidPrices[j]) // desc
-	})
+	return orders
 }
 
-// refreshAskPrices rebuilds the cached askPrices slice and sorts it ascending.
-func (ob *OrderBook) refreshAskPrices() {
-	ob.askPrices = make([]decimal.Decimal, 0, len(ob.Asks))
-	for _, pl := range ob.Asks {
-		if !pl.IsEmpty() {
-			ob.askPrices = append(ob.askPrices, pl.Price)
+// QueuePosition returns a resting order's place in its price level's queue
+// (1 = head) and the remaining quantity of the orders ahead of it, and its
+// own remaining quantity. ok is false if the order is not resting at price.
+func (ob *OrderBook) QueuePosition(orderID int64, side models.OrderSide, price decimal.Decimal) (position int, quantityAhead, remaining decimal.Decimal, ok bool) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	levels := ob.Asks
+	if side == models.OrderSideBuy {
+		levels = ob.Bids
+	}
+	pl := levels[priceKey(price)]
+	if pl == nil {
+		return 0, decimal.Zero, decimal.Zero, false
+	}
+	quantityAhead = decimal.Zero
+	for i, o := range pl.Orders {
+		if o.ID == orderID {
+			return i + 1, quantityAhead, o.RemainingQuantity, true
+		}
+		quantityAhead = quantityAhead.Add(o.RemainingQuantity)
+	}
+	return 0, decimal.Zero, decimal.Zero, false
+}
+
+// ordersCreatedBefore returns copies of up to limit resting orders created
+// before cutoff, bids then asks.
+func (ob *OrderBook) ordersCreatedBefore(cutoff time.Time, limit int) []models.Order {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	var orders []models.Order
+	for _, levels := range [][]*PriceLevel{ob.bidLevels, ob.askLevels} {
+		for _, pl := range levels {
+			for _, o := range pl.Orders {
+				if len(orders) == limit {
+					return orders
+				}
+				if o.CreatedAt.Before(cutoff) {
+					orders = append(orders, *o)
+				}
+			}
+		}
+	}
+	return orders
+}
+
+// FindOrder returns the resting order with the given ID, or nil.
+func (ob *OrderBook) FindOrder(orderID int64) *models.Order {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	for _, levels := range []map[string]*PriceLevel{ob.Bids, ob.Asks} {
+		for _, pl := range levels {
+			for _, o := range pl.Orders {
+				if o.ID == orderID {
+					return o
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// topLevels returns at most the first depth levels.
+func topLevels(levels []*PriceLevel, depth int) []*PriceLevel {
+	if depth < 0 {
+		return nil
+	}
+	if depth < len(levels) {
+		return levels[:depth]
+	}
+	return levels
+}
+
+// insertLevel inserts pl into levels, which is sorted so that before(a, b)
+// holds for every a preceding b.
+func insertLevel(levels []*PriceLevel, pl *PriceLevel, before func(a, b decimal.Decimal) bool) []*PriceLevel {
+	i := sort.Search(len(levels), func(i int) bool { return before(pl.Price, levels[i].Price) })
+	levels = append(levels, nil)
+	copy(levels[i+1:], levels[i:])
+	levels[i] = pl
+	return levels
+}
+
+// removeLevel drops pl from levels without reallocating. Matching empties
+// the best level, so the scan usually stops at index 0.
+func removeLevel(levels []*PriceLevel, pl *PriceLevel) []*PriceLevel {
+	for i, l := range levels {
+		if l == pl {
+			return append(levels[:i], levels[i+1:]...)
 		}
 	}
-	sort.Slice(ob.askPrices, func(i, j int) bool {
-		return ob.askPrices[i].LessThan(ob.askPrices[j]) // asc
-	})
+	return levels
 }
 
 // GetOrderCount returns counts of bid and ask orders in the book.
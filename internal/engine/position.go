@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// GetPosition returns accountID's net base position in symbol: the quantity
+// it bought minus the quantity it sold, summed over its trade history. An
+// account with no trades has a zero position. A trade between two orders of
+// the same account counts on both sides and so nets to zero.
+func (e *Engine) GetPosition(accountID, symbol string) (decimal.Decimal, error) {
+	rows, err := e.db.Query(`
+		SELECT b.account_id, s.account_id, t.quantity
+		FROM `+e.tradeTable(symbol)+` t
+		JOIN orders b ON b.id = t.buy_order_id
+		JOIN orders s ON s.id = t.sell_order_id
+		WHERE t.symbol = ? AND (b.account_id = ? OR s.account_id = ?)
+	`, symbol, accountID, accountID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to query trades: %w", err)
+	}
+	defer rows.Close()
+
+	position := decimal.Zero
+	for rows.Next() {
+		var buyAccount, sellAccount sql.NullString
+		var quantity decimal.Decimal
+		if err := rows.Scan(&buyAccount, &sellAccount, &quantity); err != nil {
+			return decimal.Zero, fmt.Errorf("failed to scan trade: %w", err)
+		}
+		if buyAccount.String == accountID {
+			position = position.Add(quantity)
+		}
+		if sellAccount.String == accountID {
+			position = position.Sub(quantity)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to read trades: %w", err)
+	}
+	return position, nil
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_GetPosition nets a sequence of buys and sells, including a
+// self-trade, into a known position and reports zero without trades.
+func TestEngine_GetPosition(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	var history [][]driver.Value
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "JOIN orders b") {
+			return nil, nil
+		}
+		return &fakeRows{Cols: []string{"buy_account", "sell_account", "quantity"}, Rows: history}, nil
+	}
+
+	position, err := eng.GetPosition("acct-1", "BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, position.IsZero(), "no trades means a flat position")
+
+	history = [][]driver.Value{
+		{"acct-1", "acct-2", "1.5"},
+		{"acct-1", nil, "0.25"},
+		{"acct-2", "acct-1", "0.4"},
+		{nil, "acct-1", "0.1"},
+		{"acct-1", "acct-1", "3"}, // self-trade nets to zero
+	}
+	position, err = eng.GetPosition("acct-1", "BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("1.25").Equal(position), "got %s", position)
+
+	queries := fdb.QueriesMatching("JOIN orders b")
+	require.Len(t, queries, 2)
+	assert.Equal(t, []driver.Value{"BTCUSD", "acct-1", "acct-1"}, queries[1].Args)
+}
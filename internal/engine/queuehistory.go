@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// Queue history event names reported in QueuePositionSnapshot.Event.
+const (
+	QueueEventPlaced           = "placed"
+	QueueEventAheadAdded       = "ahead_added"
+	QueueEventAheadPartialFill = "ahead_partially_filled"
+	QueueEventAheadFilled      = "ahead_filled"
+	QueueEventAheadCanceled    = "ahead_canceled"
+	QueueEventFilled           = "filled"
+	QueueEventCanceled         = "canceled"
+)
+
+// levelOrder is the subset of an order needed to replay its price level.
+type levelOrder struct {
+	ID              int64
+	InitialQuantity decimal.Decimal
+	Status          models.OrderStatus
+	Tier            int
+	// Rested is false for orders that filled or were canceled during their
+	// own placement and so were never in the book.
+	Rested    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// levelFill is a single execution against a resting order at the level.
+type levelFill struct {
+	OrderID    int64
+	Quantity   decimal.Decimal
+	ExecutedAt time.Time
+}
+
+// GetQueuePositionHistory reconstructs how a limit order's position in its
+// price level's FIFO queue evolved over its resting life. The timeline is
+// rebuilt by replaying arrivals and cancels (from orders) and fills (from
+// trades) of the orders queued ahead of it at the same symbol, side and price:
+// earlier orders of the same or a higher tier, and later orders of a higher
+// tier. Orders that filled or were canceled during their own placement never
+// rested and are skipped. Timestamps carry the DB's precision, so events within the same
+// second are ordered arrivals, then fills, then cancels.
+func (e *Engine) GetQueuePositionHistory(orderID int64) ([]models.QueuePositionSnapshot, error) {
+	order, err := e.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Type != models.OrderTypeLimit || order.Price == nil {
+		return nil, fmt.Errorf("order %d is not a limit order and never rested", orderID)
+	}
+
+	// Orders at the same level that queue ahead of this one and had not yet
+	// left the book when it was placed.
+	rows, err := e.db.Query(`
+		SELECT id, initial_quantity, status, tier, rested, created_at, updated_at
+		FROM orders
+		WHERE symbol = ? AND side = ? AND type = 'limit' AND price = ?
+		  AND (tier > ? OR (tier = ? AND id < ?))
+		  AND (status IN ('open', 'partially_filled') OR updated_at >= ?)
+		ORDER BY id ASC
+	`, order.Symbol, order.Side, *order.Price, order.Tier, order.Tier, order.ID, order.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price level orders: %w", err)
+	}
+	defer rows.Close()
+
+	var ahead []levelOrder
+	for rows.Next() {
+		var lo levelOrder
+		if err := rows.Scan(&lo.ID, &lo.InitialQuantity, &lo.Status, &lo.Tier, &lo.Rested, &lo.CreatedAt, &lo.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price level order: %w", err)
+		}
+		ahead = append(ahead, lo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price level orders: %w", err)
+	}
+
+	fills, err := e.loadLevelFills(order.Symbol, order.Side, ahead)
+	if err != nil {
+		return nil, err
+	}
+
+	target := levelOrder{
+		ID:              order.ID,
+		InitialQuantity: order.InitialQuantity,
+		Status:          order.Status,
+		Tier:            order.Tier,
+		Rested:          true,
+		CreatedAt:       order.CreatedAt,
+		UpdatedAt:       order.UpdatedAt,
+	}
+	return buildQueuePositionHistory(target, ahead, fills, time.Now()), nil
+}
+
+// loadLevelFills returns executions against the given resting orders of symbol.
+func (e *Engine) loadLevelFills(symbol string, side models.OrderSide, orders []levelOrder) ([]levelFill, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	// The resting order's ID sits in the column matching its side.
+	idColumn := "buy_order_id"
+	if side == models.OrderSideSell {
+		idColumn = "sell_order_id"
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(orders)), ",")
+	args := make([]interface{}, len(orders))
+	for i, o := range orders {
+		args[i] = o.ID
+	}
+
+	rows, err := e.db.Query(`
+		SELECT `+idColumn+`, quantity, executed_at
+		FROM `+e.tradeTable(symbol)+` t
+		WHERE `+idColumn+` IN (`+placeholders+`)
+		ORDER BY executed_at ASC, id ASC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query level fills: %w", err)
+	}
+	defer rows.Close()
+
+	var fills []levelFill
+	for rows.Next() {
+		var f levelFill
+		if err := rows.Scan(&f.OrderID, &f.Quantity, &f.ExecutedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan level fill: %w", err)
+		}
+		fills = append(fills, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating level fills: %w", err)
+	}
+	return fills, nil
+}
+
+// buildQueuePositionHistory replays arrivals, fills and cancels of the orders
+// queued ahead of target and returns a snapshot at placement, after every
+// change ahead of it, and when target itself left the book. now bounds the
+// replay for orders that are still resting.
+func buildQueuePositionHistory(target levelOrder, ahead []levelOrder, fills []levelFill, now time.Time) []models.QueuePositionSnapshot {
+	const (
+		eventAdd = iota
+		eventFill
+		eventCancel
+	)
+	type levelEvent struct {
+		at       time.Time
+		kind     int
+		orderID  int64
+		quantity decimal.Decimal // initial quantity for adds, fill size for fills
+	}
+
+	known := make(map[int64]bool, len(ahead))
+	events := make([]levelEvent, 0, len(fills)+2*len(ahead))
+	for _, o := range ahead {
+		if !o.Rested {
+			continue
+		}
+		known[o.ID] = true
+		events = append(events, levelEvent{at: o.CreatedAt, kind: eventAdd, orderID: o.ID, quantity: o.InitialQuantity})
+		if o.Status == models.OrderStatusCanceled {
+			events = append(events, levelEvent{at: o.UpdatedAt, kind: eventCancel, orderID: o.ID})
+		}
+	}
+	for _, f := range fills {
+		if known[f.OrderID] {
+			events = append(events, levelEvent{at: f.ExecutedAt, kind: eventFill, orderID: f.OrderID, quantity: f.Quantity})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		return events[i].kind < events[j].kind
+	})
+
+	end := now
+	if target.Status == models.OrderStatusFilled || target.Status == models.OrderStatusCanceled {
+		end = target.UpdatedAt
+	}
+
+	// remaining holds the open quantity of each order currently ahead.
+	remaining := make(map[int64]decimal.Decimal, len(ahead))
+	snapshot := func(at time.Time, event string, orderID int64) models.QueuePositionSnapshot {
+		position := 1
+		quantityAhead := decimal.Zero
+		for _, qty := range remaining {
+			position++
+			quantityAhead = quantityAhead.Add(qty)
+		}
+		return models.QueuePositionSnapshot{
+			Timestamp:     at,
+			Event:         event,
+			EventOrderID:  orderID,
+			Position:      position,
+			QuantityAhead: quantityAhead,
+		}
+	}
+
+	// apply mutates the level and returns the event name, or "" if the event
+	// does not concern an order currently ahead.
+	apply := func(ev levelEvent) string {
+		if ev.kind == eventAdd {
+			remaining[ev.orderID] = ev.quantity
+			return QueueEventAheadAdded
+		}
+		qty, ok := remaining[ev.orderID]
+		if !ok {
+			return ""
+		}
+		if ev.kind == eventCancel {
+			delete(remaining, ev.orderID)
+			return QueueEventAheadCanceled
+		}
+		qty = qty.Sub(ev.quantity)
+		if qty.Sign() <= 0 {
+			delete(remaining, ev.orderID)
+			return QueueEventAheadFilled
+		}
+		remaining[ev.orderID] = qty
+		return QueueEventAheadPartialFill
+	}
+
+	// Replay everything that happened before target arrived. Orders with a
+	// lower ID arrived first even when the timestamps tie.
+	i := 0
+	for ; i < len(events); i++ {
+		ev := events[i]
+		arrivedFirst := ev.kind == eventAdd && ev.orderID < target.ID && !ev.at.After(target.CreatedAt)
+		if !ev.at.Before(target.CreatedAt) && !arrivedFirst {
+			break
+		}
+		apply(ev)
+	}
+
+	history := []models.QueuePositionSnapshot{snapshot(target.CreatedAt, QueueEventPlaced, target.ID)}
+	for ; i < len(events) && !events[i].at.After(end); i++ {
+		if name := apply(events[i]); name != "" {
+			history = append(history, snapshot(events[i].at, name, events[i].orderID))
+		}
+	}
+
+	switch target.Status {
+	case models.OrderStatusFilled:
+		history = append(history, snapshot(end, QueueEventFilled, target.ID))
+	case models.OrderStatusCanceled:
+		history = append(history, snapshot(end, QueueEventCanceled, target.ID))
+	}
+	return history
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuildQueuePositionHistory replays a constructed level: one order leaves
+// before the target arrives, then the orders ahead are partially filled,
+// canceled and filled until the target reaches the head and fills.
+func TestBuildQueuePositionHistory(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	ahead := []levelOrder{
+		{ID: 1, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusFilled, Rested: true, CreatedAt: at(-3), UpdatedAt: at(3)},
+		{ID: 2, InitialQuantity: decimal.NewFromFloat(2.0), Status: models.OrderStatusCanceled, Rested: true, CreatedAt: at(-2), UpdatedAt: at(2)},
+		{ID: 3, InitialQuantity: decimal.NewFromFloat(0.5), Status: models.OrderStatusFilled, Rested: true, CreatedAt: at(-2), UpdatedAt: at(-1)},
+	}
+	fills := []levelFill{
+		{OrderID: 3, Quantity: decimal.NewFromFloat(0.5), ExecutedAt: at(-1)},
+		{OrderID: 1, Quantity: decimal.NewFromFloat(0.4), ExecutedAt: at(1)},
+		{OrderID: 1, Quantity: decimal.NewFromFloat(0.6), ExecutedAt: at(3)},
+	}
+	target := levelOrder{
+		ID:              4,
+		InitialQuantity: decimal.NewFromFloat(1.0),
+		Status:          models.OrderStatusFilled,
+		Rested:          true,
+		CreatedAt:       at(0),
+		UpdatedAt:       at(4),
+	}
+
+	history := buildQueuePositionHistory(target, ahead, fills, at(10))
+
+	expected := []struct {
+		at       time.Time
+		event    string
+		orderID  int64
+		position int
+		qtyAhead float64
+	}{
+		{at(0), QueueEventPlaced, 4, 3, 3.0},
+		{at(1), QueueEventAheadPartialFill, 1, 3, 2.6},
+		{at(2), QueueEventAheadCanceled, 2, 2, 0.6},
+		{at(3), QueueEventAheadFilled, 1, 1, 0},
+		{at(4), QueueEventFilled, 4, 1, 0},
+	}
+
+	require.Len(t, history, len(expected))
+	for i, exp := range expected {
+		snap := history[i]
+		assert.True(t, exp.at.Equal(snap.Timestamp), "snapshot %d: timestamp", i)
+		assert.Equal(t, exp.event, snap.Event, "snapshot %d: event", i)
+		assert.Equal(t, exp.orderID, snap.EventOrderID, "snapshot %d: event order", i)
+		assert.Equal(t, exp.position, snap.Position, "snapshot %d: position", i)
+		assert.True(t, decimal.NewFromFloat(exp.qtyAhead).Equal(snap.QuantityAhead),
+			"snapshot %d: expected quantity ahead %v, got %s", i, exp.qtyAhead, snap.QuantityAhead)
+	}
+}
+
+// TestBuildQueuePositionHistory_StillResting stops the replay at now for open orders.
+func TestBuildQueuePositionHistory_StillResting(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	ahead := []levelOrder{
+		{ID: 1, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusCanceled, Rested: true, CreatedAt: base.Add(-time.Minute), UpdatedAt: base.Add(time.Hour)},
+	}
+	target := levelOrder{ID: 2, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base}
+
+	history := buildQueuePositionHistory(target, ahead, nil, base.Add(time.Minute))
+
+	require.Len(t, history, 1, "cancel after now must not be replayed")
+	assert.Equal(t, QueueEventPlaced, history[0].Event)
+	assert.Equal(t, 2, history[0].Position)
+}
+
+// TestBuildQueuePositionHistory_HigherTierJumpsAhead shows a later higher-tier
+// arrival pushing the target back, and a same-second earlier arrival counted
+// at placement.
+func TestBuildQueuePositionHistory_HigherTierJumpsAhead(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	ahead := []levelOrder{
+		{ID: 1, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base},
+		{ID: 3, InitialQuantity: decimal.NewFromFloat(0.5), Status: models.OrderStatusOpen, Tier: 2, Rested: true, CreatedAt: base.Add(time.Minute), UpdatedAt: base.Add(time.Minute)},
+	}
+	target := levelOrder{ID: 2, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base}
+
+	history := buildQueuePositionHistory(target, ahead, nil, base.Add(time.Hour))
+
+	require.Len(t, history, 2)
+	assert.Equal(t, QueueEventPlaced, history[0].Event)
+	assert.Equal(t, 2, history[0].Position)
+	assert.Equal(t, QueueEventAheadAdded, history[1].Event)
+	assert.Equal(t, int64(3), history[1].EventOrderID)
+	assert.Equal(t, 3, history[1].Position)
+	assert.True(t, decimal.NewFromFloat(1.5).Equal(history[1].QuantityAhead))
+}
+
+// TestBuildQueuePositionHistory_IOCNeverRests ignores a later higher-tier IOC
+// order at the price, which was canceled during its placement and never
+// queued ahead of the target, even though it carries the same timestamps as
+// an order that rested.
+func TestBuildQueuePositionHistory_IOCNeverRests(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	iocAt := base.Add(time.Minute)
+
+	ahead := []levelOrder{
+		{ID: 1, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base},
+		{ID: 3, InitialQuantity: decimal.NewFromFloat(0.5), Status: models.OrderStatusCanceled, Tier: 2, CreatedAt: iocAt, UpdatedAt: iocAt},
+	}
+	target := levelOrder{ID: 2, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base}
+
+	history := buildQueuePositionHistory(target, ahead, nil, base.Add(time.Hour))
+
+	require.Len(t, history, 1)
+	assert.Equal(t, QueueEventPlaced, history[0].Event)
+	assert.Equal(t, 2, history[0].Position)
+	assert.True(t, decimal.NewFromFloat(1.0).Equal(history[0].QuantityAhead))
+}
+
+// TestBuildQueuePositionHistory_CanceledWithinSecondOfPlacement keeps an order
+// that rested ahead of the target and was canceled within the same truncated
+// second it was placed in: its cancel is replayed rather than the order being
+// dropped as never rested.
+func TestBuildQueuePositionHistory_CanceledWithinSecondOfPlacement(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	placedAt := base.Add(time.Minute)
+
+	ahead := []levelOrder{
+		{ID: 1, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base},
+		{ID: 3, InitialQuantity: decimal.NewFromFloat(0.5), Status: models.OrderStatusCanceled, Tier: 2, Rested: true, CreatedAt: placedAt, UpdatedAt: placedAt},
+	}
+	target := levelOrder{ID: 2, InitialQuantity: decimal.NewFromFloat(1.0), Status: models.OrderStatusOpen, Rested: true, CreatedAt: base, UpdatedAt: base}
+
+	history := buildQueuePositionHistory(target, ahead, nil, base.Add(time.Hour))
+
+	require.Len(t, history, 3)
+	assert.Equal(t, QueueEventPlaced, history[0].Event)
+	assert.Equal(t, QueueEventAheadAdded, history[1].Event)
+	assert.Equal(t, 3, history[1].Position)
+	assert.True(t, decimal.NewFromFloat(1.5).Equal(history[1].QuantityAhead))
+	assert.Equal(t, QueueEventAheadCanceled, history[2].Event)
+	assert.Equal(t, int64(3), history[2].EventOrderID)
+	assert.Equal(t, 2, history[2].Position)
+	assert.True(t, decimal.NewFromFloat(1.0).Equal(history[2].QuantityAhead))
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"log"
+	"time"
+)
+
+// runBookReaper periodically drops books that have been empty for longer
+// than Config.IdleBookTTL. It exits when the engine is closed.
+func (e *Engine) runBookReaper() {
+	ticker := time.NewTicker(e.config.IdleBookTTL.Duration / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case now := <-ticker.C:
+			if n := e.reapIdleBooks(now); n > 0 {
+				log.Printf("[INFO] Reaped %d idle order books", n)
+			}
+		}
+	}
+}
+
+// reapIdleBooks removes every book that has held no orders since before
+// now minus IdleBookTTL and returns how many were removed. Each removal
+// happens under the symbol lock, so no placement or cancel is using the
+// book, and a later getOrderBook simply creates a fresh one. Symbol locks
+// and last prices are kept.
+func (e *Engine) reapIdleBooks(now time.Time) int {
+	cutoff := now.Add(-e.config.IdleBookTTL.Duration)
+	idle := func(ob *OrderBook) bool {
+		since, empty := ob.idleSince()
+		return empty && !since.After(cutoff)
+	}
+
+	e.globalMutex.RLock()
+	var candidates []string
+	for symbol, ob := range e.orderBooks {
+		if idle(ob) {
+			candidates = append(candidates, symbol)
+		}
+	}
+	e.globalMutex.RUnlock()
+
+	reaped := 0
+	for _, symbol := range candidates {
+		symbolMutex := e.getSymbolMutex(symbol)
+		symbolMutex.Lock()
+		e.globalMutex.Lock()
+		// Re-check: an order may have rested since the scan.
+		if ob, ok := e.orderBooks[symbol]; ok && idle(ob) {
+			delete(e.orderBooks, symbol)
+			reaped++
+		}
+		e.globalMutex.Unlock()
+		symbolMutex.Unlock()
+	}
+	return reaped
+}
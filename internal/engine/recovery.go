@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"order-matching-engine/internal/models"
+)
+
+// crossed reports whether the best bid is at or above the best ask. A book
+// built by matching never is; a recovered one can be after bad data.
+func (ob *OrderBook) crossed() bool {
+	bid, ask := ob.GetBestBid(), ob.GetBestAsk()
+	return bid != nil && ask != nil && bid.Price.GreaterThanOrEqual(*ask.Price)
+}
+
+// resolveCrossedBooks checks every recovered book and handles a crossed one
+// per crossed_book_recovery: it is uncrossed by matching, or its symbol is
+// put under review. A book that fails to uncross is put under review too.
+func (e *Engine) resolveCrossedBooks() {
+	e.globalMutex.RLock()
+	symbols := make([]string, 0, len(e.orderBooks))
+	for symbol := range e.orderBooks {
+		symbols = append(symbols, symbol)
+	}
+	e.globalMutex.RUnlock()
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		ob := e.getOrderBook(symbol)
+		if !ob.crossed() {
+			continue
+		}
+		bid, ask := ob.GetBestBid().Price, ob.GetBestAsk().Price
+		if e.config.CrossedBookRecovery == CrossedBookMatch {
+			trades, err := e.uncrossBook(symbol)
+			if err == nil {
+				log.Printf("[WARN] Recovered book for %s was crossed (best bid %s >= best ask %s); uncrossed with %d recovery trades",
+					symbol, bid, ask, len(trades))
+				continue
+			}
+			log.Printf("[ERROR] Failed to uncross recovered book for %s: %v", symbol, err)
+		}
+		e.setUnderReview(symbol, true)
+		log.Printf("[WARN] Recovered book for %s is crossed (best bid %s >= best ask %s); placement halted pending review",
+			symbol, bid, ask)
+	}
+}
+
+// uncrossBook matches the crossing orders of symbol's book against each
+// other until it is no longer crossed, and returns the trades. Of the two
+// orders at the top of the book, the later one is matched as if it had just
+// arrived, so trades execute at the earlier order's price. Trades and order
+// updates commit in one transaction; the book changes only if it commits.
+func (e *Engine) uncrossBook(symbol string) ([]models.Trade, error) {
+	symMtx := e.getSymbolMutex(symbol)
+	symMtx.Lock()
+	defer symMtx.Unlock()
+
+	book := e.getOrderBook(symbol).Clone()
+	tx, err := e.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	var trades []models.Trade
+	var updated []*models.Order
+	var events []bookEvent
+	for book.crossed() {
+		incoming := book.GetBestBid()
+		if ask := book.GetBestAsk(); ask.CreatedAt.After(incoming.CreatedAt) ||
+			ask.CreatedAt.Equal(incoming.CreatedAt) && ask.ID > incoming.ID {
+			incoming = ask
+		}
+		book.RemoveOrder(incoming.ID, incoming.Side, incoming.Price)
+		result := e.matcher.Match(incoming, book)
+		setTradeGlobalIDs(result.Trades, incoming, result.UpdatedOrders)
+
+		for _, trade := range result.Trades {
+			if err := e.insertTrade(tx, trade); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+		// The incoming order left the book; a remainder re-queues at the back.
+		events = append(events, bookEvent{OrderID: incoming.ID})
+		for _, u := range result.UpdatedOrders {
+			if u.ID != incoming.ID {
+				events = append(events, orderEvent(u))
+			}
+		}
+		changed := result.UpdatedOrders
+		if left := result.IncomingOrderLeft; left != nil {
+			changed = append(changed, left)
+			book.AddOrder(left)
+			events = append(events, orderEvent(left))
+		}
+		for _, u := range changed {
+			_, err := tx.Stmt(e.updateOrderStmt).Exec(u.RemainingQuantity, u.Status, u.UpdatedAt, u.ID)
+			if err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to update order %d: %w", u.ID, err)
+			}
+		}
+		trades = append(trades, result.Trades...)
+		updated = append(updated, changed...)
+	}
+	lastSeq, err := e.logBookEvents(tx, symbol, events)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := e.commit(tx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	e.replaceOrderBook(symbol, book)
+	e.bookEventsCommitted(symbol, lastSeq)
+	for _, u := range updated {
+		e.cacheCompletedOrder(u)
+	}
+	if len(trades) > 0 {
+		e.setLastPrice(symbol, trades[len(trades)-1].Price)
+		e.symbolMetrics.record(symbol, trades)
+	}
+	return trades, nil
+}
+
+// setUnderReview flags or clears symbol for manual review. Placement on a
+// flagged symbol fails with ErrSymbolUnderReview; cancels still work.
+func (e *Engine) setUnderReview(symbol string, flagged bool) {
+	e.reviewMutex.Lock()
+	defer e.reviewMutex.Unlock()
+	if flagged {
+		e.underReview[symbol] = true
+	} else {
+		delete(e.underReview, symbol)
+	}
+}
+
+// UnderReview reports whether symbol is flagged for manual review.
+func (e *Engine) UnderReview(symbol string) bool {
+	e.reviewMutex.RLock()
+	defer e.reviewMutex.RUnlock()
+	return e.underReview[symbol]
+}
+
+// SymbolsUnderReview returns the symbols flagged for manual review, sorted.
+func (e *Engine) SymbolsUnderReview() []string {
+	e.reviewMutex.RLock()
+	defer e.reviewMutex.RUnlock()
+	symbols := make([]string, 0, len(e.underReview))
+	for symbol := range e.underReview {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// ClearReview lifts the review flag from symbol so placement resumes. It
+// fails with ErrBookCrossed while the book is still crossed; cancel the
+// offending orders first.
+func (e *Engine) ClearReview(symbol string) error {
+	if !e.UnderReview(symbol) {
+		return fmt.Errorf("%w: %s", ErrNotUnderReview, symbol)
+	}
+	symMtx := e.getSymbolMutex(symbol)
+	symMtx.Lock()
+	defer symMtx.Unlock()
+	if e.getOrderBook(symbol).crossed() {
+		return fmt.Errorf("%w: %s", ErrBookCrossed, symbol)
+	}
+	e.setUnderReview(symbol, false)
+	log.Printf("[INFO] Review cleared for %s; placement resumed", symbol)
+	return nil
+}
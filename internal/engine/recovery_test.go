@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-matching-engine/internal/models"
+)
+
+// crossedBookRows serves a recovered BTCUSD book whose older bid at 50100
+// crosses a newer ask at 50000, plus an uncrossed ETHUSD book, and the
+// matching order row for lookups by ID.
+func crossedBookRows(fdb *fakeDB) {
+	base := time.Now().Add(-time.Hour)
+	rows := map[int64][]driver.Value{
+		1: {int64(1), nil, nil, "BTCUSD", "buy", "limit", "50100", "1", "1", "open", int64(0), base, base, nil, nil, nil},
+		2: {int64(2), nil, nil, "BTCUSD", "sell", "limit", "50000", "2", "2", "open", int64(0), base.Add(time.Minute), base.Add(time.Minute), nil, nil, nil},
+		3: {int64(3), nil, nil, "BTCUSD", "sell", "limit", "50200", "1", "1", "open", int64(0), base.Add(2 * time.Minute), base.Add(2 * time.Minute), nil, nil, nil},
+		4: {int64(4), nil, nil, "ETHUSD", "buy", "limit", "3000", "1", "1", "open", int64(0), base, base, nil, nil, nil},
+	}
+	cols := strings.Split(strings.Join(strings.Fields(orderColumns), ""), ",")
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		switch {
+		case strings.Contains(query, "status IN ('open', 'partially_filled')"):
+			return &fakeRows{Cols: cols, Rows: [][]driver.Value{rows[1], rows[2], rows[3], rows[4]}}, nil
+		case strings.Contains(query, "WHERE id = ?"):
+			if row, ok := rows[args[0].(int64)]; ok {
+				return &fakeRows{Cols: cols, Rows: [][]driver.Value{row}}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// TestEngine_CrossedBookRecoveryMatch uncrosses a recovered book by matching
+// the newer crossing order against the older one at the older one's price.
+func TestEngine_CrossedBookRecoveryMatch(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CrossedBookRecovery = CrossedBookMatch
+	eng, fdb := newFakeEngine(t, cfg)
+	crossedBookRows(fdb)
+
+	require.NoError(t, eng.LoadOpenOrders())
+
+	trades := fdb.ExecsMatching("INSERT INTO trades")
+	require.Len(t, trades, 1)
+	updates := fdb.ExecsMatching("UPDATE orders")
+	require.Len(t, updates, 2)
+	assert.Equal(t, int64(1), updates[0].Args[3])
+	assert.Equal(t, string(models.OrderStatusFilled), updates[0].Args[1])
+	assert.Equal(t, int64(2), updates[1].Args[3])
+	assert.Equal(t, string(models.OrderStatusPartiallyFilled), updates[1].Args[1])
+
+	ob := eng.getOrderBook("BTCUSD")
+	assert.False(t, ob.crossed())
+	bids, asks := ob.GetAggregatedLevels(10)
+	assert.Empty(t, bids)
+	require.Len(t, asks, 2)
+	assert.Equal(t, "50000", asks[0].Price.String())
+	assert.Equal(t, "1", asks[0].Quantity.String())
+	price, ok, err := eng.GetLastPrice("BTCUSD")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "50100", price.String(), "the recovery trade executes at the older order's price")
+
+	assert.Empty(t, eng.SymbolsUnderReview())
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.NoError(t, err)
+}
+
+// TestEngine_CrossedBookRecoveryReview leaves a crossed book as recovered,
+// halts placement on its symbol only, and resumes once it is uncrossed.
+func TestEngine_CrossedBookRecoveryReview(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+	crossedBookRows(fdb)
+
+	require.NoError(t, eng.LoadOpenOrders())
+
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO trades"))
+	assert.True(t, eng.getOrderBook("BTCUSD").crossed())
+	assert.Equal(t, []string{"BTCUSD"}, eng.SymbolsUnderReview())
+
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.ErrorIs(t, err, ErrSymbolUnderReview)
+	_, _, err = eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideBuy, 2900, 1))
+	assert.NoError(t, err, "other symbols keep trading")
+
+	assert.ErrorIs(t, eng.ClearReview("BTCUSD"), ErrBookCrossed)
+	assert.ErrorIs(t, eng.ClearReview("ETHUSD"), ErrNotUnderReview)
+
+	_, err = eng.CancelOrder(1)
+	require.NoError(t, err, "cancels are allowed while under review")
+	require.NoError(t, eng.ClearReview("BTCUSD"))
+	assert.Empty(t, eng.SymbolsUnderReview())
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1))
+	assert.NoError(t, err)
+}
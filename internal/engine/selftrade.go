@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"sync"
+
+	"order-matching-engine/internal/models"
+)
+
+// selfTradeCounts holds one account's self-trade prevention cancels.
+type selfTradeCounts struct {
+	incoming uint64
+	resting  uint64
+}
+
+// selfTradeStats counts self-trade prevention cancels per account.
+type selfTradeStats struct {
+	mu       sync.Mutex
+	accounts map[string]*selfTradeCounts
+}
+
+func newSelfTradeStats() *selfTradeStats {
+	return &selfTradeStats{accounts: make(map[string]*selfTradeCounts)}
+}
+
+// record counts the self-trade prevention cancels of a committed placement
+// of order.
+func (s *selfTradeStats) record(order *models.Order, result *MatchResult) {
+	if !result.SelfTradeStopped && len(result.SelfTradeCanceled) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if result.SelfTradeStopped {
+		s.counts(order.AccountID).incoming++
+	}
+	for _, resting := range result.SelfTradeCanceled {
+		s.counts(resting.AccountID).resting++
+	}
+}
+
+// counts returns accountID's counters, creating them. s.mu must be held.
+func (s *selfTradeStats) counts(accountID string) *selfTradeCounts {
+	c := s.accounts[accountID]
+	if c == nil {
+		c = &selfTradeCounts{}
+		s.accounts[accountID] = c
+	}
+	return c
+}
+
+// SelfTradeStats returns the self-trade prevention cancels of accountID's
+// orders since startup, split by whether the canceled order was the incoming
+// or the resting one.
+func (e *Engine) SelfTradeStats(accountID string) models.SelfTradeStatsResponse {
+	resp := models.SelfTradeStatsResponse{AccountID: accountID}
+	e.selfTrades.mu.Lock()
+	defer e.selfTrades.mu.Unlock()
+	if c := e.selfTrades.accounts[accountID]; c != nil {
+		resp.IncomingCanceled = c.incoming
+		resp.RestingCanceled = c.resting
+	}
+	return resp
+}
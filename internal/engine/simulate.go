@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"order-matching-engine/internal/models"
+)
+
+// MaxSimulationOperations caps the number of operations accepted by SimulateOperations.
+const MaxSimulationOperations = 1000
+
+// SimulateOperations applies a sequence of place/cancel operations to a copy of
+// the symbol's current book and returns the resulting trades and final book.
+// Live state is never touched: nothing is written to the DB and the in-memory
+// book is only read, under the symbol lock, to take the copy.
+//
+// Simulated orders get negative IDs (-1, -2, ...) in operation order so a later
+// cancel operation can refer to them without colliding with real order IDs.
+// An invalid operation is reported in its result and the sequence continues.
+func (e *Engine) SimulateOperations(symbol string, ops []models.SimulationOperation) (*models.SimulationResponse, error) {
+	if len(ops) > MaxSimulationOperations {
+		return nil, fmt.Errorf("too many operations: %d (max %d)", len(ops), MaxSimulationOperations)
+	}
+	if err := e.checkSymbol(symbol); err != nil {
+		return nil, err
+	}
+
+	symbolMutex := e.getSymbolMutex(symbol)
+	symbolMutex.Lock()
+	book := e.getOrderBook(symbol).Clone()
+	symbolMutex.Unlock()
+
+	resp := &models.SimulationResponse{
+		Symbol:  symbol,
+		Results: make([]models.SimulationOperationResult, 0, len(ops)),
+		Trades:  make([]models.Trade, 0),
+	}
+
+	var nextID int64
+	for _, op := range ops {
+		result := models.SimulationOperationResult{Action: op.Action}
+
+		switch op.Action {
+		case models.SimulationActionPlace:
+			nextID--
+			order, trades, err := e.simulatePlace(book, symbol, op.Order, nextID)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			result.OrderID = order.ID
+			result.Status = order.Status
+			resp.Trades = append(resp.Trades, trades...)
+
+		case models.SimulationActionCancel:
+			result.OrderID = op.OrderID
+			order := book.FindOrder(op.OrderID)
+			if order == nil {
+				result.Error = "order not found in book"
+				break
+			}
+			book.RemoveOrder(order.ID, order.Side, order.Price)
+			result.Status = models.OrderStatusCanceled
+
+		default:
+			result.Error = fmt.Sprintf("unknown action %q", op.Action)
+		}
+
+		resp.Results = append(resp.Results, result)
+	}
+
+	resp.Bids, resp.Asks = book.GetAggregatedLevels(math.MaxInt)
+	return resp, nil
+}
+
+// simulatePlace validates a simulated order and matches it against book,
+// resting any limit remainder there.
+func (e *Engine) simulatePlace(book *OrderBook, symbol string, req *models.CreateOrderRequest, id int64) (*models.Order, []models.Trade, error) {
+	if req == nil {
+		return nil, nil, fmt.Errorf("order is required for place")
+	}
+	if req.Symbol == "" {
+		cp := *req
+		cp.Symbol = symbol
+		req = &cp
+	}
+	if req.Symbol != symbol {
+		return nil, nil, fmt.Errorf("order symbol %s does not match simulation symbol %s", req.Symbol, symbol)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if req.Tier < 0 || req.Tier > e.config.MaxPriorityTier {
+		return nil, nil, fmt.Errorf("%w: %d (max %d)", ErrInvalidTier, req.Tier, e.config.MaxPriorityTier)
+	}
+
+	now := time.Now()
+	order := &models.Order{
+		ID:                id,
+		ClientOrderID:     req.ClientOrderID,
+		Symbol:            req.Symbol,
+		Side:              req.Side,
+		Type:              req.Type,
+		Price:             req.Price,
+		InitialQuantity:   req.Quantity,
+		RemainingQuantity: req.Quantity,
+		Status:            models.OrderStatusOpen,
+		Tier:              req.Tier,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	result := e.matcher.MatchWithOptions(order, book, e.matchOptions(req))
+	if result.IncomingOrderLeft != nil {
+		book.AddOrder(result.IncomingOrderLeft)
+		return result.IncomingOrderLeft, result.Trades, nil
+	}
+	for _, u := range result.UpdatedOrders {
+		if u.ID == order.ID {
+			return u, result.Trades, nil
+		}
+	}
+	return order, result.Trades, nil
+}
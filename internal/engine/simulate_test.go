@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"testing"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_SimulateOperationsLeavesLiveBookUnchanged runs a mixed sequence
+// against a copy of live liquidity and checks the simulated trades and book,
+// then confirms the live book and its orders are untouched.
+func TestEngine_SimulateOperationsLeavesLiveBookUnchanged(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	// Live book: asks at 50000 (1.0) and 50100 (2.0), bid at 49000 (1.0).
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1.0))
+	require.NoError(t, err)
+	liveAsk2, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 2.0))
+	require.NoError(t, err)
+	_, _, err = eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1.0))
+	require.NoError(t, err)
+
+	liveBidsBefore, liveAsksBefore := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	execsBefore := len(fdb.ExecsMatching(""))
+
+	ops := []models.SimulationOperation{
+		// Sweeps the 50000 level and takes 0.5 from 50100.
+		{Action: models.SimulationActionPlace, Order: marketRequest("BTCUSD", models.OrderSideBuy, 1.5)},
+		// Cancels the rest of the live 50100 ask.
+		{Action: models.SimulationActionCancel, OrderID: liveAsk2.ID},
+		// Rests a new ask, then cancels it by its simulated ID.
+		{Action: models.SimulationActionPlace, Order: limitRequest("BTCUSD", models.OrderSideSell, 50200, 3.0)},
+		{Action: models.SimulationActionCancel, OrderID: -2},
+		// Rests a bid above the live one.
+		{Action: models.SimulationActionPlace, Order: limitRequest("BTCUSD", models.OrderSideBuy, 49500, 0.7)},
+		{Action: models.SimulationActionCancel, OrderID: 12345},
+	}
+
+	resp, err := eng.SimulateOperations("BTCUSD", ops)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Trades, 2)
+	assert.True(t, decimal.NewFromInt(50000).Equal(resp.Trades[0].Price))
+	assert.True(t, decimal.NewFromFloat(1.0).Equal(resp.Trades[0].Quantity))
+	assert.True(t, decimal.NewFromInt(50100).Equal(resp.Trades[1].Price))
+	assert.True(t, decimal.NewFromFloat(0.5).Equal(resp.Trades[1].Quantity))
+
+	require.Len(t, resp.Results, len(ops))
+	assert.Equal(t, models.OrderStatusFilled, resp.Results[0].Status)
+	assert.Equal(t, int64(-1), resp.Results[0].OrderID)
+	assert.Equal(t, models.OrderStatusCanceled, resp.Results[1].Status)
+	assert.Equal(t, models.OrderStatusOpen, resp.Results[2].Status)
+	assert.Equal(t, models.OrderStatusCanceled, resp.Results[3].Status)
+	assert.Equal(t, int64(-3), resp.Results[4].OrderID)
+	assert.NotEmpty(t, resp.Results[5].Error, "canceling an unknown order should be reported")
+
+	assert.Empty(t, resp.Asks)
+	require.Len(t, resp.Bids, 2)
+	assert.True(t, decimal.NewFromInt(49500).Equal(resp.Bids[0].Price))
+	assert.True(t, decimal.NewFromFloat(0.7).Equal(resp.Bids[0].Quantity))
+	assert.True(t, decimal.NewFromInt(49000).Equal(resp.Bids[1].Price))
+
+	// Live state is unchanged.
+	liveBidsAfter, liveAsksAfter := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Equal(t, liveBidsBefore, liveBidsAfter)
+	assert.Equal(t, liveAsksBefore, liveAsksAfter)
+	assert.True(t, decimal.NewFromFloat(2.0).Equal(liveAsk2.RemainingQuantity))
+	resting := eng.getOrderBook("BTCUSD").FindOrder(liveAsk2.ID)
+	require.NotNil(t, resting)
+	assert.True(t, decimal.NewFromFloat(2.0).Equal(resting.RemainingQuantity))
+	assert.Len(t, fdb.ExecsMatching(""), execsBefore, "simulation must not write to the DB")
+}
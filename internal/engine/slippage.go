@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// slippageScale is the number of decimals slippage is stored with, matching
+// the orders.slippage column.
+const slippageScale = 8
+
+// referencePrice returns the best price opposing a market order on side, or
+// nil if that side of ob is empty. Caller holds the symbol lock.
+func referencePrice(ob *OrderBook, side models.OrderSide) *decimal.Decimal {
+	best := ob.GetBestAsk()
+	if side == models.OrderSideSell {
+		best = ob.GetBestBid()
+	}
+	if best == nil {
+		return nil
+	}
+	price := *best.Price
+	return &price
+}
+
+// realizedSlippage returns how far the average price of trades moved from
+// reference, as a fraction of reference: positive when the order on side
+// did worse than the reference, negative when it did better.
+func realizedSlippage(side models.OrderSide, reference decimal.Decimal, trades []models.Trade) decimal.Decimal {
+	notional, quantity := decimal.Zero, decimal.Zero
+	for _, t := range trades {
+		notional = notional.Add(t.Price.Mul(t.Quantity))
+		quantity = quantity.Add(t.Quantity)
+	}
+	average := notional.Div(quantity)
+	moved := average.Sub(reference)
+	if side == models.OrderSideSell {
+		moved = moved.Neg()
+	}
+	return moved.Div(reference).Round(slippageScale)
+}
+
+// incomingAfterMatch returns the incoming order as result left it.
+func incomingAfterMatch(orderID int64, result *MatchResult) *models.Order {
+	if result.IncomingOrderLeft != nil {
+		return result.IncomingOrderLeft
+	}
+	for _, u := range result.UpdatedOrders {
+		if u.ID == orderID {
+			return u
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"testing"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_MarketOrderSlippage checks market orders sweeping several
+// levels record their slippage against the best price at submission, on
+// both sides, and that an order meeting an empty book records none.
+func TestEngine_MarketOrderSlippage(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	for _, ask := range []struct{ price, qty float64 }{{100, 1}, {101, 1}, {102, 2}} {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, ask.price, ask.qty))
+		require.NoError(t, err)
+	}
+
+	// 1 @ 100, 1 @ 101, 1 @ 102: average 101, 1% above the best ask.
+	order, trades, err := eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 3))
+	require.NoError(t, err)
+	require.Len(t, trades, 3)
+	require.NotNil(t, order.ReferencePrice)
+	require.NotNil(t, order.Slippage)
+	assert.True(t, decimal.NewFromInt(100).Equal(*order.ReferencePrice))
+	assert.True(t, decimal.RequireFromString("0.01").Equal(*order.Slippage), "got %s", order.Slippage)
+
+	updates := fdb.ExecsMatching("SET reference_price")
+	require.Len(t, updates, 1)
+	assert.Equal(t, "100", updates[0].Args[0])
+	assert.Equal(t, "0.01", updates[0].Args[1])
+	assert.Equal(t, order.ID, updates[0].Args[2])
+
+	for _, bid := range []float64{100, 99} {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, bid, 1))
+		require.NoError(t, err)
+	}
+	// 1 @ 100, 1 @ 99: average 99.5, 0.5% below the best bid.
+	order, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideSell, 2))
+	require.NoError(t, err)
+	require.NotNil(t, order.Slippage)
+	assert.True(t, decimal.RequireFromString("0.005").Equal(*order.Slippage), "got %s", order.Slippage)
+
+	order, _, err = eng.PlaceOrder(marketRequest("BTCUSD", models.OrderSideSell, 1))
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCanceled, order.Status)
+	assert.Nil(t, order.ReferencePrice, "no bids to take a reference from")
+	assert.Nil(t, order.Slippage)
+	assert.Len(t, fdb.ExecsMatching("SET reference_price"), 2)
+}
@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// SymbolStateVersion is the bundle format written by ExportSymbolState.
+const SymbolStateVersion = 1
+
+// ExportTradeLimit is the number of most recent trades included in a bundle.
+const ExportTradeLimit = 1000
+
+// SymbolState is a portable snapshot of one symbol, written by
+// ExportSymbolState and replayed by ImportSymbolState on another instance.
+type SymbolState struct {
+	Version    int       `json:"version"`
+	Symbol     string    `json:"symbol"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Config is the exporting instance's settings for the symbol, if it had
+	// any. It is informational: the importing instance keeps its own config.
+	Config    *SymbolConfig    `json:"config,omitempty"`
+	LastPrice *decimal.Decimal `json:"last_price,omitempty"`
+	// OpenOrders lists resting orders bids then asks, best price first and
+	// in queue priority within a level.
+	OpenOrders []models.Order `json:"open_orders"`
+	// RecentTrades holds up to ExportTradeLimit trades, most recent first,
+	// for reference. They are not written on import.
+	RecentTrades []models.Trade `json:"recent_trades"`
+}
+
+// ExportSymbolState captures the symbol's resting orders, recent trades, last
+// price and config. The book and last price are read under the symbol lock so
+// they are consistent with each other.
+func (e *Engine) ExportSymbolState(symbol string) (*SymbolState, error) {
+	trades, err := e.GetTrades(symbol, ExportTradeLimit)
+	if err != nil {
+		return nil, err
+	}
+	if trades == nil {
+		trades = []models.Trade{}
+	}
+
+	state := &SymbolState{
+		Version:      SymbolStateVersion,
+		Symbol:       symbol,
+		RecentTrades: trades,
+	}
+	if sc, ok := e.config.symbolConfig(symbol); ok {
+		state.Config = &sc
+	}
+
+	symbolMutex := e.getSymbolMutex(symbol)
+	symbolMutex.Lock()
+	defer symbolMutex.Unlock()
+
+	state.ExportedAt = time.Now()
+	state.OpenOrders = e.getOrderBook(symbol).RestingOrders()
+	if state.OpenOrders == nil {
+		state.OpenOrders = []models.Order{}
+	}
+	price, ok, err := e.GetLastPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		state.LastPrice = &price
+	}
+	return state, nil
+}
+
+// ImportSymbolState validates state and recreates its open orders, with their
+// original IDs and queue order, on this instance: they are inserted in one
+// transaction and then added to the book, and the last price is seeded. The
+// symbol's book must be empty, and no order of any symbol here may already
+// use one of the bundle's IDs (ErrOrderIDConflict). Orders keep the
+// namespace of their composite ID. Trades in the bundle are not imported.
+// Returns the number of orders imported.
+func (e *Engine) ImportSymbolState(state *SymbolState) (int, error) {
+	if err := state.validate(e.config.MaxPriorityTier); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidSymbolState, err)
+	}
+	if err := e.checkSymbol(state.Symbol); err != nil {
+		return 0, err
+	}
+
+	symbolMutex := e.getSymbolMutex(state.Symbol)
+	symbolMutex.Lock()
+	defer symbolMutex.Unlock()
+
+	orderBook := e.getOrderBook(state.Symbol)
+	if bids, asks := orderBook.GetOrderCount(); bids+asks > 0 {
+		return 0, fmt.Errorf("%w: %s has %d", ErrSymbolNotEmpty, state.Symbol, bids+asks)
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	taken, err := takenOrderIDs(tx, state.OpenOrders)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(taken) > 0 {
+		tx.Rollback()
+		shown := taken
+		if len(shown) > 10 {
+			shown = shown[:10]
+		}
+		return 0, fmt.Errorf("%w: %d of the bundle's IDs, including %v", ErrOrderIDConflict, len(taken), shown)
+	}
+
+	var events []bookEvent
+	for i := range state.OpenOrders {
+		o := &state.OpenOrders[i]
+		o.IDNamespace = globalIDNamespace(o.GlobalID)
+		o.GlobalID = formatGlobalOrderID(o.IDNamespace, o.ID)
+		_, err = tx.Exec(`
+			INSERT INTO orders (
+				id, id_namespace, client_order_id, account_id, symbol, side, type, price,
+				initial_quantity, remaining_quantity, status, tier,
+				created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, o.ID, nullableString(o.IDNamespace), o.ClientOrderID, nullableAccountID(o.AccountID), o.Symbol, o.Side, o.Type, *o.Price,
+			o.InitialQuantity, o.RemainingQuantity, o.Status, o.Tier,
+			o.CreatedAt, o.UpdatedAt)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to insert order %d: %w", o.ID, err)
+		}
+		events = append(events, orderEvent(o))
+	}
+	lastSeq, err := e.logBookEvents(tx, state.Symbol, events)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err = e.commit(tx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	imported := make([]*models.Order, len(state.OpenOrders))
+	for i := range state.OpenOrders {
+		order := state.OpenOrders[i]
+		orderBook.AddOrder(&order)
+		imported[i] = &order
+	}
+	e.bookEventsCommitted(state.Symbol, lastSeq)
+	e.recordBookChange(state.Symbol, orderBook, imported)
+	if state.LastPrice != nil {
+		e.setLastPrice(state.Symbol, *state.LastPrice)
+	}
+	return len(state.OpenOrders), nil
+}
+
+// orderIDCheckBatch bounds the IDs looked up per query by takenOrderIDs.
+const orderIDCheckBatch = 500
+
+// takenOrderIDs returns the IDs of orders that already exist here, in
+// ascending order within each batch.
+func takenOrderIDs(tx *sql.Tx, orders []models.Order) ([]int64, error) {
+	var taken []int64
+	for start := 0; start < len(orders); start += orderIDCheckBatch {
+		batch := orders[start:min(start+orderIDCheckBatch, len(orders))]
+		args := make([]interface{}, len(batch))
+		for i, o := range batch {
+			args[i] = o.ID
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+
+		rows, err := tx.Query(`SELECT id FROM orders WHERE id IN (`+placeholders+`) ORDER BY id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check order IDs: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan order ID: %w", err)
+			}
+			taken = append(taken, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating order IDs: %w", err)
+		}
+	}
+	return taken, nil
+}
+
+// validate checks the bundle is complete and internally consistent.
+func (s *SymbolState) validate(maxTier int) error {
+	if s.Version != SymbolStateVersion {
+		return fmt.Errorf("unsupported version %d (want %d)", s.Version, SymbolStateVersion)
+	}
+	if s.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if s.LastPrice != nil && !s.LastPrice.IsPositive() {
+		return fmt.Errorf("last_price must be positive")
+	}
+
+	seen := make(map[int64]bool, len(s.OpenOrders))
+	var bestBid, bestAsk *decimal.Decimal
+	for _, o := range s.OpenOrders {
+		switch {
+		case o.ID <= 0 || seen[o.ID]:
+			return fmt.Errorf("order %d: missing or duplicate ID", o.ID)
+		case o.Symbol != s.Symbol:
+			return fmt.Errorf("order %d: symbol %s does not match bundle symbol %s", o.ID, o.Symbol, s.Symbol)
+		case o.Side != models.OrderSideBuy && o.Side != models.OrderSideSell:
+			return fmt.Errorf("order %d: invalid side %q", o.ID, o.Side)
+		case o.Type != models.OrderTypeLimit || o.Price == nil || !o.Price.IsPositive():
+			return fmt.Errorf("order %d: only limit orders with a positive price can rest", o.ID)
+		case o.Status != models.OrderStatusOpen && o.Status != models.OrderStatusPartiallyFilled:
+			return fmt.Errorf("order %d: status %s cannot rest", o.ID, o.Status)
+		case !o.RemainingQuantity.IsPositive() || o.RemainingQuantity.GreaterThan(o.InitialQuantity):
+			return fmt.Errorf("order %d: remaining quantity must be positive and at most the initial quantity", o.ID)
+		case o.Tier < 0 || o.Tier > maxTier:
+			return fmt.Errorf("order %d: tier %d outside 0..%d", o.ID, o.Tier, maxTier)
+		}
+		seen[o.ID] = true
+
+		price := *o.Price
+		if o.Side == models.OrderSideBuy && (bestBid == nil || price.GreaterThan(*bestBid)) {
+			bestBid = &price
+		}
+		if o.Side == models.OrderSideSell && (bestAsk == nil || price.LessThan(*bestAsk)) {
+			bestAsk = &price
+		}
+	}
+	if bestBid != nil && bestAsk != nil && bestBid.GreaterThanOrEqual(*bestAsk) {
+		return fmt.Errorf("book is crossed: best bid %s >= best ask %s", bestBid, bestAsk)
+	}
+	return nil
+}
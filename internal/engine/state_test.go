@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_SymbolStateRoundTrip exports a symbol with partial fills and
+// tiered queues, imports the JSON bundle into a fresh engine and compares
+// the books, queue order and last price.
+func TestEngine_SymbolStateRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxPriorityTier = 1
+	src, _ := newFakeEngine(t, cfg)
+
+	_, _, err := src.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1.0))
+	require.NoError(t, err)
+	_, _, err = src.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 2.0))
+	require.NoError(t, err)
+	priority := limitRequest("BTCUSD", models.OrderSideSell, 50100, 0.5)
+	priority.Tier = 1
+	_, _, err = src.PlaceOrder(priority)
+	require.NoError(t, err)
+	_, _, err = src.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 49000, 1.5))
+	require.NoError(t, err)
+	_, _, err = src.PlaceOrder(marketRequest("BTCUSD", models.OrderSideBuy, 0.4))
+	require.NoError(t, err)
+
+	state, err := src.ExportSymbolState("BTCUSD")
+	require.NoError(t, err)
+	require.NotNil(t, state.LastPrice)
+	assert.True(t, decimal.NewFromInt(50000).Equal(*state.LastPrice))
+
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	var decoded SymbolState
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	dst, dstDB := newFakeEngine(t, cfg)
+	imported, err := dst.ImportSymbolState(&decoded)
+	require.NoError(t, err)
+	assert.Equal(t, len(state.OpenOrders), imported)
+	assert.Len(t, dstDB.ExecsMatching("INSERT INTO orders"), imported)
+
+	// Compare by string: JSON round-trips decimals to a different but equal
+	// internal representation.
+	levelStrings := func(e *Engine) []string {
+		bids, asks := e.GetOrderBookWithQuantities("BTCUSD", 10)
+		var out []string
+		for _, l := range append(bids, asks...) {
+			out = append(out, l.Price.String()+"x"+l.Quantity.String())
+		}
+		return out
+	}
+	require.Len(t, levelStrings(src), 3)
+	assert.Equal(t, levelStrings(src), levelStrings(dst))
+
+	queueIDs := func(e *Engine) []int64 {
+		var ids []int64
+		for _, o := range e.getOrderBook("BTCUSD").RestingOrders() {
+			ids = append(ids, o.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, queueIDs(src), queueIDs(dst), "queue priority must survive the round trip")
+
+	price, ok, err := dst.GetLastPrice("BTCUSD")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, decimal.NewFromInt(50000).Equal(price))
+
+	// A second import into a non-empty book is refused.
+	_, err = dst.ImportSymbolState(&decoded)
+	assert.ErrorIs(t, err, ErrSymbolNotEmpty)
+
+	// Invalid bundles are rejected before anything is written.
+	crossed := decoded
+	crossed.OpenOrders = append([]models.Order(nil), decoded.OpenOrders...)
+	high := decimal.NewFromInt(60000)
+	crossed.OpenOrders[0].Price = &high
+	fresh, freshDB := newFakeEngine(t, cfg)
+	_, err = fresh.ImportSymbolState(&crossed)
+	assert.ErrorIs(t, err, ErrInvalidSymbolState)
+	assert.Empty(t, freshDB.ExecsMatching("INSERT INTO orders"))
+}
+
+// TestEngine_ImportSymbolStateRejectsTakenIDs refuses a bundle whose order IDs
+// are already used on the target instance, before inserting anything.
+func TestEngine_ImportSymbolStateRejectsTakenIDs(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	price := decimal.NewFromInt(50000)
+	now := time.Now()
+	order := func(id int64) models.Order {
+		return models.Order{
+			ID: id, Symbol: "BTCUSD", Side: models.OrderSideSell, Type: models.OrderTypeLimit, Price: &price,
+			InitialQuantity: decimal.NewFromInt(1), RemainingQuantity: decimal.NewFromInt(1),
+			Status: models.OrderStatusOpen, CreatedAt: now, UpdatedAt: now,
+		}
+	}
+	state := &SymbolState{
+		Version:    SymbolStateVersion,
+		Symbol:     "BTCUSD",
+		OpenOrders: []models.Order{order(7), order(8)},
+	}
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "SELECT id FROM orders WHERE id IN") {
+			return nil, nil
+		}
+		return &fakeRows{Cols: []string{"id"}, Rows: [][]driver.Value{{int64(8)}}}, nil
+	}
+
+	_, err := eng.ImportSymbolState(state)
+	assert.ErrorIs(t, err, ErrOrderIDConflict)
+	assert.ErrorContains(t, err, "[8]")
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO orders"))
+	bids, asks := eng.getOrderBook("BTCUSD").GetOrderCount()
+	assert.Zero(t, bids+asks)
+
+	fdb.queryHook = nil
+	imported, err := eng.ImportSymbolState(state)
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported)
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// tradeFields lists the trades columns read through tradeTable.
+const tradeFields = "id, symbol, buy_order_id, sell_order_id, buy_global_order_id, sell_global_order_id, price, quantity, executed_at"
+
+// tradeShard returns the shard in [0, shards) holding symbol's trades.
+func tradeShard(symbol string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// tradeTable returns the table expression to read symbol's trades from:
+// trades, or with Config.TradeShards above 1 the symbol's trades_<shard>
+// combined with trades, which keeps history written before sharding was
+// enabled. The result is a derived table when sharded, so callers must
+// alias it. Every trades query is scoped to one symbol.
+func (e *Engine) tradeTable(symbol string) string {
+	if e.config.TradeShards <= 1 {
+		return "trades"
+	}
+	return fmt.Sprintf("(SELECT %s FROM trades_%d UNION ALL SELECT %s FROM trades)",
+		tradeFields, tradeShard(symbol, e.config.TradeShards), tradeFields)
+}
+
+// tradeTables lists every table trades are written to, in shard order.
+func (e *Engine) tradeTables() []string {
+	if e.config.TradeShards <= 1 {
+		return []string{"trades"}
+	}
+	tables := make([]string, e.config.TradeShards)
+	for i := range tables {
+		tables[i] = fmt.Sprintf("trades_%d", i)
+	}
+	return tables
+}
+
+// ensureTradeShards creates any missing trades_<n> table for the configured
+// Config.TradeShards, copying the columns and indexes of trades.
+func (e *Engine) ensureTradeShards() error {
+	if e.config.TradeShards <= 1 {
+		return nil
+	}
+	for _, table := range e.tradeTables() {
+		if _, err := e.db.Exec(`CREATE TABLE IF NOT EXISTS ` + table + ` LIKE trades`); err != nil {
+			return fmt.Errorf("failed to create trade shard table %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"testing"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_TradeShards checks the configured shard tables are created,
+// trades are written to the shard chosen by the symbol's hash and read from
+// it together with the unsharded table, and that the default keeps the
+// single trades table.
+func TestEngine_TradeShards(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TradeShards = 6
+	eng, fdb := newFakeEngine(t, cfg)
+
+	creates := fdb.ExecsMatching("CREATE TABLE IF NOT EXISTS")
+	require.Len(t, creates, 6)
+	assert.Contains(t, creates[5].Query, "trades_5 LIKE trades")
+
+	// FNV-1a modulo 6: BTCUSD -> 0, SOLUSD -> 5, ETHUSD -> 2.
+	for _, symbol := range []string{"BTCUSD", "SOLUSD"} {
+		_, _, err := eng.PlaceOrder(limitRequest(symbol, models.OrderSideSell, 100, 1))
+		require.NoError(t, err)
+		_, trades, err := eng.PlaceOrder(marketRequest(symbol, models.OrderSideBuy, 1))
+		require.NoError(t, err)
+		require.Len(t, trades, 1)
+	}
+
+	inserts := fdb.ExecsMatching("INSERT INTO trades_0 ")
+	require.Len(t, inserts, 1)
+	assert.Equal(t, "BTCUSD", inserts[0].Args[0])
+	inserts = fdb.ExecsMatching("INSERT INTO trades_5 ")
+	require.Len(t, inserts, 1)
+	assert.Equal(t, "SOLUSD", inserts[0].Args[0])
+	assert.Empty(t, fdb.ExecsMatching("INSERT INTO trades ("), "the unsharded table is not written")
+
+	_, err := eng.GetTrades("SOLUSD", 10)
+	require.NoError(t, err)
+	reads := fdb.QueriesMatching("FROM trades_5 UNION ALL")
+	require.Len(t, reads, 1)
+	assert.Contains(t, reads[0].Query, "FROM trades)", "history from before sharding is still read")
+	assert.Equal(t, "SOLUSD", reads[0].Args[0])
+
+	_, _, err = eng.GetLastPrice("ETHUSD")
+	require.NoError(t, err)
+	assert.Len(t, fdb.QueriesMatching("FROM trades_2 UNION ALL"), 1)
+
+	single, sfdb := newFakeEngine(t, DefaultConfig())
+	_, _, err = single.PlaceOrder(limitRequest("SOLUSD", models.OrderSideSell, 100, 1))
+	require.NoError(t, err)
+	_, _, err = single.PlaceOrder(marketRequest("SOLUSD", models.OrderSideBuy, 1))
+	require.NoError(t, err)
+	assert.Len(t, sfdb.ExecsMatching("INSERT INTO trades ("), 1)
+	assert.Empty(t, sfdb.ExecsMatching("CREATE TABLE"))
+}
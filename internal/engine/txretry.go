@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Server error numbers of transaction conflicts that succeed when retried.
+var retryableErrorNumbers = map[uint16]bool{
+	1205: true, // ER_LOCK_WAIT_TIMEOUT
+	1213: true, // ER_LOCK_DEADLOCK
+	9007: true, // TiDB write conflict
+}
+
+// isRetryableTxError reports whether err is a deadlock, lock wait timeout or
+// write conflict reported by MySQL or TiDB.
+func isRetryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && retryableErrorNumbers[mysqlErr.Number]
+}
+
+// retryTx runs attempt, which must roll back its own transaction on failure,
+// up to tx_retry.max_attempts times while it fails with a retryable error,
+// doubling the backoff after each failure.
+func (e *Engine) retryTx(attempt func() error) error {
+	backoff := e.config.TxRetry.Backoff.Duration
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || n >= e.config.TxRetry.MaxAttempts || !isRetryableTxError(err) {
+			return err
+		}
+		log.Printf("[WARN] Retrying transaction after attempt %d of %d: %v", n, e.config.TxRetry.MaxAttempts, err)
+		select {
+		case <-time.After(backoff):
+		case <-e.done:
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// rebuildOrderBook reloads symbol's book from its open orders in the DB,
+// undoing what a rolled-back attempt did to it in memory. Caller holds the
+// symbol lock.
+func (e *Engine) rebuildOrderBook(symbol string) error {
+	rows, err := e.db.Query(`
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE symbol = ? AND status IN ('open', 'partially_filled')
+		ORDER BY created_at ASC, id ASC
+	`, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to query open orders: %w", err)
+	}
+	defer rows.Close()
+
+	book := NewOrderBook(symbol)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return err
+		}
+		if order.Type == models.OrderTypeLimit && order.Price != nil {
+			book.AddOrder(order)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating orders: %w", err)
+	}
+	e.replaceOrderBook(symbol, book)
+	return nil
+}
+
+// replaceOrderBook gives symbol's book the orders of ob. The book keeps its
+// identity, so code holding it sees the new orders. Caller holds the symbol
+// lock.
+func (e *Engine) replaceOrderBook(symbol string, ob *OrderBook) {
+	e.getOrderBook(symbol).replaceContents(ob)
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"order-matching-engine/internal/models"
+)
+
+// deadlockOnce returns a deadlock error the first time it is called and nil
+// after that.
+func deadlockOnce() func() error {
+	failed := false
+	return func() error {
+		if failed {
+			return nil
+		}
+		failed = true
+		return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	}
+}
+
+// TestEngine_TxRetry retries a placement whose trade insert deadlocks after
+// the order has matched: the book is rebuilt from the DB in place, so the
+// match is applied once. It also retries a cancel whose commit deadlocks.
+func TestEngine_TxRetry(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TxRetry = TxRetryConfig{MaxAttempts: 3, Backoff: Duration{time.Millisecond}}
+	eng, fdb := newFakeEngine(t, cfg)
+
+	first, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	require.NoError(t, err)
+	resting, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50100, 1))
+	require.NoError(t, err)
+	book := eng.getOrderBook("BTCUSD")
+
+	cols := strings.Split(strings.Join(strings.Fields(orderColumns), ""), ",")
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "WHERE symbol = ? AND status IN ('open', 'partially_filled')") {
+			return nil, nil
+		}
+		return &fakeRows{Cols: cols, Rows: [][]driver.Value{
+			{first.ID, nil, nil, "BTCUSD", "sell", "limit", "50000", "1", "1", "open", int64(0), first.CreatedAt, first.UpdatedAt, nil, nil, nil},
+			{resting.ID, nil, nil, "BTCUSD", "sell", "limit", "50100", "1", "1", "open", int64(0), resting.CreatedAt, resting.UpdatedAt, nil, nil, nil},
+		}}, nil
+	}
+	fail := deadlockOnce()
+	fdb.execHook = func(query string, args []driver.Value) error {
+		if strings.Contains(query, "INSERT INTO trades") {
+			return fail()
+		}
+		return nil
+	}
+	order, trades, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50100, 1.5))
+	require.NoError(t, err)
+	assert.Len(t, trades, 2)
+	assert.Equal(t, models.OrderStatusFilled, order.Status)
+	assert.Len(t, fdb.ExecsMatching("INSERT INTO trades"), 2, "only the retried attempt's trades are written")
+
+	assert.Len(t, fdb.QueriesMatching("WHERE symbol = ? AND status IN"), 1, "the book is rebuilt once, after the failed attempt")
+
+	assert.Same(t, book, eng.getOrderBook("BTCUSD"), "the book keeps its identity")
+	_, asks := eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	require.Len(t, asks, 1, "the failed attempt must not consume the book twice")
+	assert.Equal(t, "50100", asks[0].Price.String())
+	assert.Equal(t, "0.5", asks[0].Quantity.String())
+
+	fdb.execHook = nil
+	fdb.commitHook = deadlockOnce()
+	fdb.queryHook = func(query string, args []driver.Value) (*fakeRows, error) {
+		if !strings.Contains(query, "WHERE id = ?") {
+			return nil, nil
+		}
+		created := time.Now()
+		return &fakeRows{
+			Cols: strings.Split(strings.Join(strings.Fields(orderColumns), ""), ","),
+			Rows: [][]driver.Value{{resting.ID, nil, nil, "BTCUSD", "sell", "limit", "50100", "1", "0.5", "partially_filled", int64(0), created, created, nil, nil, nil}},
+		}, nil
+	}
+	canceled, err := eng.CancelOrder(resting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCanceled, canceled.Status)
+	_, asks = eng.GetOrderBookWithQuantities("BTCUSD", 10)
+	assert.Empty(t, asks)
+}
+
+// TestEngine_TxRetryDisabled surfaces a deadlock without retrying.
+func TestEngine_TxRetryDisabled(t *testing.T) {
+	eng, fdb := newFakeEngine(t, DefaultConfig())
+
+	attempts := 0
+	fail := deadlockOnce()
+	fdb.commitHook = func() error {
+		attempts++
+		return fail()
+	}
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideSell, 50000, 1))
+	assert.True(t, isRetryableTxError(err))
+	assert.Equal(t, 1, attempts)
+}
+
+// BenchmarkPlaceOrder_TxRetry places a sell that joins the best ask level and
+// a buy that takes the level's head, on books of growing depth, with tx_retry
+// enabled. Attempts match on the live book, so the cost per placement should
+// not grow with the depth.
+func BenchmarkPlaceOrder_TxRetry(b *testing.B) {
+	for _, depth := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			cfg := DefaultConfig()
+			cfg.TxRetry = TxRetryConfig{MaxAttempts: 3, Backoff: Duration{time.Millisecond}}
+			eng, _ := newFakeEngine(b, cfg)
+
+			ob := eng.getOrderBook("BTCUSD")
+			created := time.Now()
+			for i := 0; i < depth; i++ {
+				bid := decimal.NewFromInt(int64(49000 - i))
+				ask := decimal.NewFromInt(int64(50000 + i))
+				for _, o := range []*models.Order{
+					{ID: int64(1e9 + 2*i), Symbol: "BTCUSD", Side: models.OrderSideBuy, Price: &bid},
+					{ID: int64(1e9 + 2*i + 1), Symbol: "BTCUSD", Side: models.OrderSideSell, Price: &ask},
+				} {
+					o.Type, o.Status = models.OrderTypeLimit, models.OrderStatusOpen
+					o.InitialQuantity, o.RemainingQuantity = decimal.NewFromInt(1), decimal.NewFromInt(1)
+					o.CreatedAt, o.UpdatedAt = created, created
+					ob.AddOrder(o)
+				}
+			}
+			sell := limitRequest("BTCUSD", models.OrderSideSell, 50000, 1)
+			buy := limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := eng.PlaceOrder(sell); err != nil {
+					b.Fatal(err)
+				}
+				if _, _, err := eng.PlaceOrder(buy); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
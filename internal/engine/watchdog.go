@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// symbolProgress tracks the placements and cancels in flight on one symbol
+// for the watchdog.
+type symbolProgress struct {
+	// inFlight counts operations waiting for or holding the symbol lock.
+	inFlight atomic.Int64
+	// last is when an operation last completed, or when the symbol went from
+	// idle to busy, in Unix nanoseconds.
+	last    atomic.Int64
+	stalled atomic.Bool
+}
+
+// symbolWatchdog detects symbols whose operations stop completing.
+type symbolWatchdog struct {
+	mutex    sync.Mutex
+	symbols  map[string]*symbolProgress
+	stalls   atomic.Uint64 // stalls detected since start
+	stalledN atomic.Int64  // symbols currently stalled
+}
+
+func newSymbolWatchdog() *symbolWatchdog {
+	return &symbolWatchdog{symbols: make(map[string]*symbolProgress)}
+}
+
+// progress returns symbol's tracker, creating it on first use.
+func (w *symbolWatchdog) progress(symbol string) *symbolProgress {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	p, ok := w.symbols[symbol]
+	if !ok {
+		p = &symbolProgress{}
+		w.symbols[symbol] = p
+	}
+	return p
+}
+
+// noWork is returned by trackSymbolWork when the watchdog is disabled.
+func noWork() {}
+
+// trackSymbolWork records that an operation on symbol has started and
+// returns the function to call once it has finished, whatever the outcome.
+// Call it before taking the symbol lock so time spent waiting counts.
+func (e *Engine) trackSymbolWork(symbol string) func() {
+	if e.watchdog == nil {
+		return noWork
+	}
+	p := e.watchdog.progress(symbol)
+	if p.inFlight.Add(1) == 1 {
+		p.last.Store(time.Now().UnixNano())
+	}
+	return func() {
+		p.last.Store(time.Now().UnixNano())
+		p.inFlight.Add(-1)
+	}
+}
+
+// runWatchdog checks for stalled symbols every half StallTimeout until the
+// engine is closed.
+func (e *Engine) runWatchdog() {
+	ticker := time.NewTicker(e.config.Watchdog.StallTimeout.Duration / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case now := <-ticker.C:
+			e.checkStalls(now)
+		}
+	}
+}
+
+// checkStalls flags every symbol with operations in flight and none
+// completed for StallTimeout, and returns the symbols newly flagged. A
+// flagged symbol is logged once and cleared when an operation completes or
+// nothing is left in flight. With ForceRelease, each newly stalled symbol
+// gets a fresh lock.
+func (e *Engine) checkStalls(now time.Time) []string {
+	w := e.watchdog
+	timeout := e.config.Watchdog.StallTimeout.Duration
+
+	w.mutex.Lock()
+	symbols := make([]string, 0, len(w.symbols))
+	for symbol := range w.symbols {
+		symbols = append(symbols, symbol)
+	}
+	w.mutex.Unlock()
+	sort.Strings(symbols)
+
+	var stalled []string
+	for _, symbol := range symbols {
+		p := w.progress(symbol)
+		inFlight := p.inFlight.Load()
+		idle := now.Sub(time.Unix(0, p.last.Load()))
+		isStalled := inFlight > 0 && idle >= timeout
+
+		if isStalled == p.stalled.Load() {
+			continue
+		}
+		p.stalled.Store(isStalled)
+		if !isStalled {
+			w.stalledN.Add(-1)
+			log.Printf("[INFO] Watchdog: symbol %s is making progress again", symbol)
+			continue
+		}
+		w.stalledN.Add(1)
+		w.stalls.Add(1)
+		stalled = append(stalled, symbol)
+		log.Printf("[ERROR] Watchdog: symbol %s has made no progress for %s with %d operations in flight",
+			symbol, idle.Round(time.Millisecond), inFlight)
+		if e.config.Watchdog.ForceRelease {
+			e.replaceSymbolMutex(symbol)
+			log.Printf("[WARN] Watchdog: replaced the lock of symbol %s; operations already waiting stay blocked", symbol)
+		}
+	}
+	return stalled
+}
+
+// replaceSymbolMutex installs a fresh lock for symbol, so operations that
+// start afterwards no longer queue behind a stuck holder. The stuck holder
+// and its waiters keep the old lock and may resume later, concurrently with
+// new operations, so this trades safety for availability.
+func (e *Engine) replaceSymbolMutex(symbol string) {
+	e.globalMutex.Lock()
+	e.symbolMutexes[symbol] = &sync.Mutex{}
+	e.globalMutex.Unlock()
+}
+
+// writeWatchdogMetrics writes the watchdog gauges and counters.
+func (e *Engine) writeWatchdogMetrics(w io.Writer) error {
+	_, err := fmt.Fprintf(w,
+		"# HELP engine_stalled_symbols Symbols the watchdog currently considers stalled.\n"+
+			"# TYPE engine_stalled_symbols gauge\n"+
+			"engine_stalled_symbols %d\n"+
+			"# HELP engine_watchdog_stalls_total Symbol stalls detected by the watchdog.\n"+
+			"# TYPE engine_watchdog_stalls_total counter\n"+
+			"engine_watchdog_stalls_total %d\n",
+		e.watchdog.stalledN.Load(), e.watchdog.stalls.Load())
+	return err
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"order-matching-engine/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEngine_WatchdogDetectsStall holds a symbol's lock, as a deadlock
+// would, while an order waits on it, and expects the watchdog to flag only
+// that symbol and to clear the flag once the order completes.
+func TestEngine_WatchdogDetectsStall(t *testing.T) {
+	cfg := DefaultConfig()
+	// Long enough that the background ticker never fires during the test.
+	cfg.Watchdog.StallTimeout = Duration{time.Hour}
+	eng, _ := newFakeEngine(t, cfg)
+
+	_, _, err := eng.PlaceOrder(limitRequest("ETHUSD", models.OrderSideBuy, 3000, 1))
+	require.NoError(t, err)
+
+	stuck := eng.getSymbolMutex("BTCUSD")
+	stuck.Lock()
+	placed := make(chan error, 1)
+	go func() {
+		_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1))
+		placed <- err
+	}()
+	require.Eventually(t, func() bool {
+		return eng.watchdog.progress("BTCUSD").inFlight.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Empty(t, eng.checkStalls(time.Now()))
+	assert.Equal(t, []string{"BTCUSD"}, eng.checkStalls(time.Now().Add(time.Hour)))
+	// Reported once per stall.
+	assert.Empty(t, eng.checkStalls(time.Now().Add(2*time.Hour)))
+
+	var buf bytes.Buffer
+	require.NoError(t, eng.WriteMetrics(&buf))
+	assert.Contains(t, buf.String(), "engine_stalled_symbols 1\n")
+	assert.Contains(t, buf.String(), "engine_watchdog_stalls_total 1\n")
+
+	stuck.Unlock()
+	require.NoError(t, <-placed)
+	eng.checkStalls(time.Now().Add(time.Hour))
+	assert.False(t, eng.watchdog.progress("BTCUSD").stalled.Load())
+	assert.Equal(t, int64(0), eng.watchdog.stalledN.Load())
+}
+
+// TestEngine_WatchdogForceRelease lets new orders through a stalled symbol
+// once its lock has been replaced.
+func TestEngine_WatchdogForceRelease(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Watchdog.StallTimeout = Duration{time.Hour}
+	cfg.Watchdog.ForceRelease = true
+	eng, _ := newFakeEngine(t, cfg)
+
+	stuck := eng.getSymbolMutex("BTCUSD")
+	stuck.Lock()
+	defer stuck.Unlock()
+	// Simulate an operation stuck while holding the lock.
+	done := eng.trackSymbolWork("BTCUSD")
+	defer done()
+
+	assert.Equal(t, []string{"BTCUSD"}, eng.checkStalls(time.Now().Add(time.Hour)))
+	_, _, err := eng.PlaceOrder(limitRequest("BTCUSD", models.OrderSideBuy, 50000, 1))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AmountsBaseUnits is the value of the amounts query parameter that selects
+// integer base units for prices and quantities.
+const AmountsBaseUnits = "base_units"
+
+// BaseUnitScale is the number of decimal places one integer base unit
+// stands for: an amount A is sent as A * 10^scale, so with a quantity scale
+// of 8 one unit is 0.00000001 (a satoshi for BTC).
+type BaseUnitScale struct {
+	Price    int32 `json:"price"`
+	Quantity int32 `json:"quantity"`
+}
+
+// ToBaseUnits returns d as an integer count of 10^-scale units. The
+// conversion is exact: it fails rather than round when d has more than scale
+// decimal places.
+func ToBaseUnits(d decimal.Decimal, scale int32) (json.Number, error) {
+	shifted := d.Shift(scale)
+	if !shifted.IsInteger() {
+		return "", fmt.Errorf("%s has more than %d decimal places", d, scale)
+	}
+	return json.Number(shifted.BigInt().String()), nil
+}
+
+// FromBaseUnits returns the decimal amount of n units of 10^-scale.
+func FromBaseUnits(n json.Number, scale int32) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(n.String())
+	if err != nil || !d.IsInteger() {
+		return decimal.Zero, fmt.Errorf("invalid base unit amount %q", n)
+	}
+	return d.Shift(-scale), nil
+}
+
+// BaseUnitTrade is a Trade with its price and quantity in base units
+type BaseUnitTrade struct {
+	ID          int64       `json:"id"`
+	Symbol      string      `json:"symbol"`
+	BuyOrderID  int64       `json:"buy_order_id"`
+	SellOrderID int64       `json:"sell_order_id"`
+	Price       json.Number `json:"price"`
+	Quantity    json.Number `json:"quantity"`
+	ExecutedAt  time.Time   `json:"executed_at"`
+	// Client order IDs as on Trade.
+	BuyClientOrderID  *string `json:"buy_client_order_id,omitempty"`
+	SellClientOrderID *string `json:"sell_client_order_id,omitempty"`
+}
+
+// BaseUnitOrderBookLevel is an OrderBookLevel with its price and quantity in base units
+type BaseUnitOrderBookLevel struct {
+	Price      json.Number `json:"price"`
+	Quantity   json.Number `json:"quantity"`
+	OrderCount int         `json:"order_count,omitempty"`
+	// OldestOrderAge is as on OrderBookLevel, in seconds.
+	OldestOrderAge *float64 `json:"oldest_order_age,omitempty"`
+}
+
+// BaseUnitTradeResponse is TradeResponse with amounts in base units
+type BaseUnitTradeResponse struct {
+	Scale  BaseUnitScale   `json:"scale"`
+	Trades []BaseUnitTrade `json:"trades"`
+	Meta   *SymbolMeta     `json:"meta,omitempty"`
+}
+
+// BaseUnitOrderBookResponse is OrderBookResponse with amounts in base units
+type BaseUnitOrderBookResponse struct {
+	Symbol string                   `json:"symbol"`
+	Scale  BaseUnitScale            `json:"scale"`
+	Bids   []BaseUnitOrderBookLevel `json:"bids"`
+	Asks   []BaseUnitOrderBookLevel `json:"asks"`
+	Meta   *SymbolMeta              `json:"meta,omitempty"`
+}
+
+// TradesInBaseUnits converts trades to base units at scale.
+func TradesInBaseUnits(trades []Trade, scale BaseUnitScale) ([]BaseUnitTrade, error) {
+	out := make([]BaseUnitTrade, len(trades))
+	for i, t := range trades {
+		price, err := ToBaseUnits(t.Price, scale.Price)
+		if err != nil {
+			return nil, fmt.Errorf("trade %d price: %w", t.ID, err)
+		}
+		quantity, err := ToBaseUnits(t.Quantity, scale.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("trade %d quantity: %w", t.ID, err)
+		}
+		out[i] = BaseUnitTrade{
+			ID:          t.ID,
+			Symbol:      t.Symbol,
+			BuyOrderID:  t.BuyOrderID,
+			SellOrderID: t.SellOrderID,
+			Price:       price,
+			Quantity:    quantity,
+			ExecutedAt:  t.ExecutedAt,
+
+			BuyClientOrderID:  t.BuyClientOrderID,
+			SellClientOrderID: t.SellClientOrderID,
+		}
+	}
+	return out, nil
+}
+
+// LevelsInBaseUnits converts book levels to base units at scale.
+func LevelsInBaseUnits(levels []OrderBookLevel, scale BaseUnitScale) ([]BaseUnitOrderBookLevel, error) {
+	out := make([]BaseUnitOrderBookLevel, len(levels))
+	for i, l := range levels {
+		price, err := ToBaseUnits(l.Price, scale.Price)
+		if err != nil {
+			return nil, fmt.Errorf("level price: %w", err)
+		}
+		quantity, err := ToBaseUnits(l.Quantity, scale.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("level %s quantity: %w", l.Price, err)
+		}
+		out[i] = BaseUnitOrderBookLevel{Price: price, Quantity: quantity, OrderCount: l.OrderCount, OldestOrderAge: l.OldestOrderAge}
+	}
+	return out, nil
+}
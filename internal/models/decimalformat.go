@@ -0,0 +1,193 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MaxDecimalPlaces bounds the decimal_places a response may ask for.
+const MaxDecimalPlaces = 18
+
+var (
+	decimalType   = reflect.TypeOf(decimal.Decimal{})
+	numberType    = reflect.TypeOf(json.Number(""))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// FormatDecimal formats d with exactly places decimal places, padding with
+// trailing zeros. A value with more significant decimal places than that is
+// never rounded: it is returned at its full precision instead.
+func FormatDecimal(d decimal.Decimal, places int32) string {
+	if !d.Equal(d.Truncate(places)) {
+		return d.String()
+	}
+	return d.StringFixed(places)
+}
+
+// MarshalFixedDecimals encodes v like json.Marshal, except that every
+// decimal.Decimal in it is formatted with FormatDecimal. It honors json
+// struct tags (names, "-" and omitempty) and json.Marshaler
+// implementations, which covers the response types in this package.
+func MarshalFixedDecimals(v any, places int32) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeFixed(&buf, reflect.ValueOf(v), places); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeFixed(buf *bytes.Buffer, v reflect.Value, places int32) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	switch t := v.Type(); {
+	case t == decimalType:
+		return writeLeaf(buf, FormatDecimal(v.Interface().(decimal.Decimal), places))
+	case t == numberType:
+		return writeLeaf(buf, v.Interface())
+	case t.Implements(marshalerType) && t.Kind() != reflect.Pointer:
+		return writeLeaf(buf, v.Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeFixed(buf, v.Elem(), places)
+
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		if err := writeFields(buf, v, places, &first); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return writeLeaf(buf, v.Interface())
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeFixed(buf, v.Index(i), places); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeLeaf(buf, k.String()); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeFixed(buf, v.MapIndex(k), places); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	default:
+		return writeLeaf(buf, v.Interface())
+	}
+}
+
+// writeFields writes the JSON members of struct v, inlining untagged
+// embedded structs as encoding/json does.
+func writeFields(buf *bytes.Buffer, v reflect.Value, places int32, first *bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if err := writeFields(buf, fv, places, first); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) {
+			continue
+		}
+
+		if !*first {
+			buf.WriteByte(',')
+		}
+		*first = false
+		if err := writeLeaf(buf, name); err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+		if err := writeFixed(buf, fv, places); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmptyValue matches encoding/json's omitempty rule.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// writeLeaf appends the standard JSON encoding of x.
+func writeLeaf(buf *bytes.Buffer, x any) error {
+	b, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMarshalFixedDecimals pads decimals to the requested places, keeps
+// values with more significant places at full precision, and otherwise
+// encodes like encoding/json.
+func TestMarshalFixedDecimals(t *testing.T) {
+	price := decimal.RequireFromString("50000.123456789")
+	resp := CreateOrderResponse{
+		OrderID: 7,
+		Status:  "partially_filled",
+		Trades: []Trade{{
+			ID:          1,
+			Symbol:      "BTCUSD",
+			BuyOrderID:  7,
+			SellOrderID: 3,
+			Price:       price,
+			Quantity:    decimal.RequireFromString("1.5"),
+			ExecutedAt:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}},
+		Message: "Order processed successfully",
+	}
+
+	got, err := MarshalFixedDecimals(resp, 8)
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(got, &decoded))
+	trade := decoded["trades"].([]any)[0].(map[string]any)
+	assert.Equal(t, "1.50000000", trade["quantity"])
+	assert.Equal(t, "50000.123456789", trade["price"], "must not round below significant digits")
+
+	// Apart from the padded quantity, the document matches encoding/json.
+	plain, err := json.Marshal(resp)
+	require.NoError(t, err)
+	var want map[string]any
+	require.NoError(t, json.Unmarshal(plain, &want))
+	want["trades"].([]any)[0].(map[string]any)["quantity"] = "1.50000000"
+	assert.Equal(t, want, decoded)
+
+	assert.Equal(t, "2", FormatDecimal(decimal.RequireFromString("2.000"), 0))
+	assert.Equal(t, "0.1230", FormatDecimal(decimal.RequireFromString("0.123"), 4))
+}
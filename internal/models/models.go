@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -36,6 +39,7 @@ const (
 type Order struct {
 	ID                int64            `json:"id" db:"id"`
 	ClientOrderID     *string          `json:"client_order_id,omitempty" db:"client_order_id"`
+	AccountID         string           `json:"account_id,omitempty" db:"account_id"`
 	Symbol            string           `json:"symbol" db:"symbol"`
 	Side              OrderSide        `json:"side" db:"side"`
 	Type              OrderType        `json:"type" db:"type"`
@@ -43,8 +47,20 @@ type Order struct {
 	InitialQuantity   decimal.Decimal  `json:"initial_quantity" db:"initial_quantity"`
 	RemainingQuantity decimal.Decimal  `json:"remaining_quantity" db:"remaining_quantity"`
 	Status            OrderStatus      `json:"status" db:"status"`
+	Tier              int              `json:"tier" db:"tier"`
 	CreatedAt         time.Time        `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time        `json:"updated_at" db:"updated_at"`
+	// ReferencePrice is the best opposing price when a market order was
+	// submitted, and Slippage how far its average fill price moved from it
+	// as a fraction of it, positive when worse for the order. Both are nil
+	// for limit orders and for market orders that met an empty book.
+	ReferencePrice *decimal.Decimal `json:"reference_price,omitempty" db:"reference_price"`
+	Slippage       *decimal.Decimal `json:"slippage,omitempty" db:"slippage"`
+	// IDNamespace is the order ID namespace the order was placed under, and
+	// GlobalID its composite "<namespace>-<id>" ID, unique across engine
+	// instances. Both are empty for orders placed without a namespace.
+	IDNamespace string `json:"-" db:"id_namespace"`
+	GlobalID    string `json:"global_id,omitempty" db:"-"`
 }
 
 // Trade represents a completed trade between two orders
@@ -56,30 +72,183 @@ type Trade struct {
 	Price       decimal.Decimal `json:"price" db:"price"`
 	Quantity    decimal.Decimal `json:"quantity" db:"quantity"`
 	ExecutedAt  time.Time       `json:"executed_at" db:"executed_at"`
+	// BuyGlobalOrderID and SellGlobalOrderID are the orders' composite IDs,
+	// omitted for orders placed without an order ID namespace.
+	BuyGlobalOrderID  string `json:"buy_global_order_id,omitempty" db:"buy_global_order_id"`
+	SellGlobalOrderID string `json:"sell_global_order_id,omitempty" db:"sell_global_order_id"`
+	// BuyClientOrderID and SellClientOrderID are the orders' client_order_id,
+	// filled in only on request and omitted when the order has none.
+	BuyClientOrderID  *string `json:"buy_client_order_id,omitempty" db:"-"`
+	SellClientOrderID *string `json:"sell_client_order_id,omitempty" db:"-"`
 }
 
 // CreateOrderRequest represents the JSON payload for creating a new order
 type CreateOrderRequest struct {
 	ClientOrderID *string          `json:"client_order_id,omitempty"`
+	AccountID     string           `json:"account_id,omitempty"`
 	Symbol        string           `json:"symbol" binding:"required"`
 	Side          OrderSide        `json:"side" binding:"required"`
 	Type          OrderType        `json:"type" binding:"required"`
 	Price         *decimal.Decimal `json:"price,omitempty"`
 	Quantity      decimal.Decimal  `json:"quantity" binding:"required"`
+	Tier          int              `json:"tier,omitempty"`
+	// IncludeBook asks for the top BookDepth levels before and after the order
+	// is applied to be returned with the placement.
+	IncludeBook bool `json:"include_book,omitempty"`
+	BookDepth   int  `json:"book_depth,omitempty"`
+	// ValidUntil, or SentAt plus MaxLatencyMs, bound how late the order may
+	// reach the engine before it is rejected as expired.
+	ValidUntil   *time.Time `json:"valid_until,omitempty"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	MaxLatencyMs int64      `json:"max_latency_ms,omitempty"`
+	// MarketRemainder overrides the symbol's handling of a market order's
+	// unfilled remainder: MarketRemainderCancel or MarketRemainderRest.
+	MarketRemainder string `json:"market_remainder,omitempty"`
+	// Trace records each matching decision and returns it with the placement.
+	Trace bool `json:"trace,omitempty"`
+	// TimeInForce says how long a limit order's unfilled remainder stays on
+	// the book. Empty means the symbol's default, else TimeInForceGTC.
+	TimeInForce TimeInForce `json:"time_in_force,omitempty"`
+}
+
+// TimeInForce is how long a limit order's unfilled remainder stays on the book
+type TimeInForce string
+
+const (
+	// TimeInForceGTC rests the remainder until it fills or is canceled.
+	TimeInForceGTC TimeInForce = "GTC"
+	// TimeInForceIOC cancels the remainder once matching stops.
+	TimeInForceIOC TimeInForce = "IOC"
+)
+
+// Valid reports whether tif is a known time in force; empty is not.
+func (tif TimeInForce) Valid() bool {
+	return tif == TimeInForceGTC || tif == TimeInForceIOC
+}
+
+// Values for self-trade prevention, which stops an account's orders from
+// trading with each other.
+const (
+	// SelfTradeCancelResting cancels the account's resting order and lets
+	// the incoming order keep matching.
+	SelfTradeCancelResting = "cancel_resting"
+	// SelfTradeCancelIncoming cancels the rest of the incoming order.
+	SelfTradeCancelIncoming = "cancel_incoming"
+)
+
+// MaxAccountIDLength is the width of the orders.account_id column.
+const MaxAccountIDLength = 64
+
+// Values for CreateOrderRequest.MarketRemainder.
+const (
+	// MarketRemainderCancel cancels the unfilled remainder of a market order.
+	MarketRemainderCancel = "cancel"
+	// MarketRemainderRest converts the remainder to a limit order at the
+	// order's last fill price and rests it on the book.
+	MarketRemainderRest = "rest"
+)
+
+// Validate performs basic request validation for creating orders.
+func (req *CreateOrderRequest) Validate() error {
+	if req.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if len(req.AccountID) > MaxAccountIDLength {
+		return fmt.Errorf("account_id must be at most %d characters", MaxAccountIDLength)
+	}
+	if req.Side != OrderSideBuy && req.Side != OrderSideSell {
+		return fmt.Errorf("side must be 'buy' or 'sell'")
+	}
+	if req.Type != OrderTypeLimit && req.Type != OrderTypeMarket {
+		return fmt.Errorf("type must be 'limit' or 'market'")
+	}
+	if req.Quantity.IsZero() || req.Quantity.IsNegative() {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if req.Type == OrderTypeLimit {
+		if req.Price == nil || req.Price.IsZero() || req.Price.IsNegative() {
+			return fmt.Errorf("price is required for limit orders and must be positive")
+		}
+	}
+	if req.BookDepth < 0 {
+		return fmt.Errorf("book_depth must not be negative")
+	}
+	switch req.MarketRemainder {
+	case "", MarketRemainderCancel, MarketRemainderRest:
+	default:
+		return fmt.Errorf("market_remainder must be '%s' or '%s'", MarketRemainderCancel, MarketRemainderRest)
+	}
+	if req.MarketRemainder != "" && req.Type != OrderTypeMarket {
+		return fmt.Errorf("market_remainder applies only to market orders")
+	}
+	if req.TimeInForce != "" && !req.TimeInForce.Valid() {
+		return fmt.Errorf("time_in_force must be '%s' or '%s'", TimeInForceGTC, TimeInForceIOC)
+	}
+	if req.MaxLatencyMs < 0 {
+		return fmt.Errorf("max_latency_ms must not be negative")
+	}
+	if req.MaxLatencyMs > 0 && req.SentAt == nil {
+		return fmt.Errorf("sent_at is required with max_latency_ms")
+	}
+	return nil
 }
 
 // CreateOrderResponse represents the response after creating an order
 type CreateOrderResponse struct {
-	OrderID int64   `json:"order_id"`
-	Status  string  `json:"status"`
-	Trades  []Trade `json:"trades,omitempty"`
-	Message string  `json:"message"`
+	OrderID int64 `json:"order_id"`
+	// GlobalOrderID is the order's Order.GlobalID.
+	GlobalOrderID string  `json:"global_order_id,omitempty"`
+	Status        string  `json:"status"`
+	Trades        []Trade `json:"trades,omitempty"`
+	Message       string  `json:"message"`
+	// BookBefore and BookAfter are set only when include_book was requested.
+	BookBefore *BookSnapshot `json:"book_before,omitempty"`
+	BookAfter  *BookSnapshot `json:"book_after,omitempty"`
+	// Trace is set only when trace was requested.
+	Trace []MatchTraceStep `json:"trace,omitempty"`
+	// QueuePosition (1 = head) and QuantityAhead locate the resting
+	// remainder in its price level's queue just after it rested. Both are
+	// omitted when nothing rested.
+	QueuePosition int              `json:"queue_position,omitempty"`
+	QuantityAhead *decimal.Decimal `json:"quantity_ahead,omitempty"`
+}
+
+// Matching trace actions reported in MatchTraceStep.Action
+const (
+	TraceCandidate = "candidate" // best resting order considered
+	TraceSkip      = "skip"      // candidate passed over without trading
+	TraceTrade     = "trade"     // trade executed against the candidate
+	TraceStop      = "stop"      // matching ended; Reason says why
+	TraceRest      = "rest"      // remainder added to the book
+	TraceCancel    = "cancel"    // unfilled remainder canceled
+)
+
+// MatchTraceStep is one recorded decision of the matcher
+type MatchTraceStep struct {
+	Step           int              `json:"step"`
+	Action         string           `json:"action"`
+	RestingOrderID int64            `json:"resting_order_id,omitempty"`
+	Price          *decimal.Decimal `json:"price,omitempty"`
+	Quantity       *decimal.Decimal `json:"quantity,omitempty"`
+	Reason         string           `json:"reason,omitempty"`
+}
+
+// BookSnapshot is the aggregated top of a symbol's book at a point in time
+type BookSnapshot struct {
+	Bids []OrderBookLevel `json:"bids"`
+	Asks []OrderBookLevel `json:"asks"`
 }
 
 // OrderBookLevel represents a single price level in the order book
 type OrderBookLevel struct {
 	Price    decimal.Decimal `json:"price"`
 	Quantity decimal.Decimal `json:"quantity"`
+	// OrderCount is the number of resting orders at the level; it is set
+	// only when counts were requested.
+	OrderCount int `json:"order_count,omitempty"`
+	// OldestOrderAge is how long, in seconds, the level's oldest resting
+	// order has been in the book; it is set only when requested.
+	OldestOrderAge *float64 `json:"oldest_order_age,omitempty"`
 }
 
 // OrderBookResponse represents the aggregated order book response
@@ -87,9 +256,276 @@ type OrderBookResponse struct {
 	Symbol string           `json:"symbol"`
 	Bids   []OrderBookLevel `json:"bids"`
 	Asks   []OrderBookLevel `json:"asks"`
+	// Meta is set only when requested with include=meta.
+	Meta *SymbolMeta `json:"meta,omitempty"`
+}
+
+// SymbolMeta describes how a symbol's amounts are denominated and displayed
+type SymbolMeta struct {
+	Symbol            string `json:"symbol"`
+	BaseAsset         string `json:"base_asset,omitempty"`
+	QuoteAsset        string `json:"quote_asset,omitempty"`
+	DisplayCurrency   string `json:"display_currency,omitempty"`
+	PricePrecision    *int32 `json:"price_precision,omitempty"`
+	QuantityPrecision *int32 `json:"quantity_precision,omitempty"`
+}
+
+// BookLevelChange sets the total quantity of one price level; zero removes it
+type BookLevelChange struct {
+	Side     OrderSide       `json:"side"`
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// BookDelta is one numbered change to a symbol's book
+type BookDelta struct {
+	Sequence uint64            `json:"sequence"`
+	Changes  []BookLevelChange `json:"changes"`
+}
+
+// OrderBookDeltaResponse represents the book changes since a sequence, or the
+// full book when Snapshot is set
+type OrderBookDeltaResponse struct {
+	Symbol   string           `json:"symbol"`
+	Sequence uint64           `json:"sequence"`
+	Snapshot bool             `json:"snapshot"`
+	Deltas   []BookDelta      `json:"deltas,omitempty"`
+	Bids     []OrderBookLevel `json:"bids,omitempty"`
+	Asks     []OrderBookLevel `json:"asks,omitempty"`
+}
+
+// LiquidityScoreResponse represents a symbol's liquidity score and its
+// components, all on a 0-100 scale
+type LiquidityScoreResponse struct {
+	Symbol       string  `json:"symbol"`
+	Score        float64 `json:"score"`
+	SpreadScore  float64 `json:"spread_score"`
+	DepthScore   float64 `json:"depth_score"`
+	BalanceScore float64 `json:"balance_score"`
+	// Spread is (ask-bid)/mid; nil unless both sides have orders.
+	Spread   *decimal.Decimal `json:"spread,omitempty"`
+	BidDepth decimal.Decimal  `json:"bid_depth"`
+	AskDepth decimal.Decimal  `json:"ask_depth"`
 }
 
 // TradeResponse represents the response for trade queries
 type TradeResponse struct {
 	Trades []Trade `json:"trades"`
+	// Meta is set only when requested with include=meta.
+	Meta *SymbolMeta `json:"meta,omitempty"`
+}
+
+// CanceledOrdersResponse represents the response for recent cancel queries
+type CanceledOrdersResponse struct {
+	Symbol string  `json:"symbol"`
+	Orders []Order `json:"orders"`
+}
+
+// CancelableResponse reports whether an order can currently be canceled
+type CancelableResponse struct {
+	OrderID    int64  `json:"order_id"`
+	Cancelable bool   `json:"cancelable"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// StaleCancelResponse represents the result of canceling orders created before a cutoff
+type StaleCancelResponse struct {
+	Symbol   string    `json:"symbol,omitempty"`
+	Before   time.Time `json:"before"`
+	Canceled int       `json:"canceled"`
+}
+
+// PositionResponse represents an account's net position in a symbol
+type PositionResponse struct {
+	AccountID string          `json:"account_id"`
+	Symbol    string          `json:"symbol"`
+	Position  decimal.Decimal `json:"position"`
+}
+
+// VersionResponse describes the running build and its enabled features
+type VersionResponse struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit"`
+	BuildTime string   `json:"build_time"`
+	GoVersion string   `json:"go_version"`
+	Features  []string `json:"features"`
+}
+
+// SelfTradeStatsResponse counts an account's self-trade prevention cancels
+type SelfTradeStatsResponse struct {
+	AccountID        string `json:"account_id"`
+	IncomingCanceled uint64 `json:"incoming_canceled"`
+	RestingCanceled  uint64 `json:"resting_canceled"`
+}
+
+// ImportStateResponse represents the response after importing a symbol state bundle
+type ImportStateResponse struct {
+	Symbol         string `json:"symbol"`
+	ImportedOrders int    `json:"imported_orders"`
+}
+
+// ReviewResponse lists the symbols halted for manual review
+type ReviewResponse struct {
+	Symbols []string `json:"symbols"`
+}
+
+// OrderRef is an order ID as given by a client: numeric, or composite
+// "<namespace>-<id>". In JSON it is a number or a string; numeric refs are
+// written back as numbers.
+type OrderRef string
+
+// UnmarshalJSON accepts an integer or a string.
+func (r *OrderRef) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*r = OrderRef(s)
+		return nil
+	}
+	var id int64
+	if err := json.Unmarshal(data, &id); err != nil {
+		return fmt.Errorf("order ID must be an integer or a string, got %s", data)
+	}
+	*r = OrderRef(strconv.FormatInt(id, 10))
+	return nil
+}
+
+// MarshalJSON writes numeric refs as numbers and composite ones as strings.
+func (r OrderRef) MarshalJSON() ([]byte, error) {
+	if _, err := strconv.ParseInt(string(r), 10, 64); err == nil {
+		return []byte(r), nil
+	}
+	return json.Marshal(string(r))
+}
+
+// OrderStatusRequest represents the JSON payload for a bulk order status lookup
+type OrderStatusRequest struct {
+	OrderIDs []OrderRef `json:"order_ids"`
+}
+
+// OrderStatusEntry is the current status and remaining quantity of a single order
+type OrderStatusEntry struct {
+	OrderID           int64           `json:"order_id"`
+	GlobalOrderID     string          `json:"global_order_id,omitempty"`
+	Status            OrderStatus     `json:"status"`
+	RemainingQuantity decimal.Decimal `json:"remaining_quantity"`
+}
+
+// OrderStatusResponse represents the response for a bulk order status lookup.
+// NotFound echoes the requested IDs that matched no order.
+type OrderStatusResponse struct {
+	Orders   []OrderStatusEntry `json:"orders"`
+	NotFound []OrderRef         `json:"not_found"`
+}
+
+// QueuePositionSnapshot is an order's place in its price level's FIFO queue at a point in time
+type QueuePositionSnapshot struct {
+	Timestamp     time.Time       `json:"timestamp"`
+	Event         string          `json:"event"`
+	EventOrderID  int64           `json:"event_order_id"`
+	Position      int             `json:"position"` // 1 = head of the queue
+	QuantityAhead decimal.Decimal `json:"quantity_ahead"`
+}
+
+// FillEstimate is a rough estimate of how long a resting order will take to
+// fill if trading at its price continues at the recent rate
+type FillEstimate struct {
+	OrderID           int64           `json:"order_id"`
+	IsEstimate        bool            `json:"is_estimate"` // always true
+	QueuePosition     int             `json:"queue_position"`
+	QuantityAhead     decimal.Decimal `json:"quantity_ahead"`
+	RemainingQuantity decimal.Decimal `json:"remaining_quantity"`
+	RecentVolume      decimal.Decimal `json:"recent_volume"`
+	WindowSeconds     float64         `json:"window_seconds"`
+	// Nil when there was no recent volume at the price to extrapolate from.
+	SecondsToFirstFill *float64 `json:"seconds_to_first_fill"`
+	SecondsToFill      *float64 `json:"seconds_to_fill"`
+	Note               string   `json:"note,omitempty"`
+}
+
+// QueueHistoryResponse represents the response for an order's queue position history
+type QueueHistoryResponse struct {
+	OrderID   int64                   `json:"order_id"`
+	Snapshots []QueuePositionSnapshot `json:"snapshots"`
+}
+
+// SimulationAction is the kind of step in a what-if simulation
+type SimulationAction string
+
+const (
+	SimulationActionPlace  SimulationAction = "place"
+	SimulationActionCancel SimulationAction = "cancel"
+)
+
+// SimulationOperation is a single place or cancel step of a what-if simulation
+type SimulationOperation struct {
+	Action  SimulationAction    `json:"action"`
+	Order   *CreateOrderRequest `json:"order,omitempty"`    // for place
+	OrderID int64               `json:"order_id,omitempty"` // for cancel
+}
+
+// SimulationRequest represents the JSON payload for simulating a sequence of operations
+type SimulationRequest struct {
+	Symbol     string                `json:"symbol"`
+	Operations []SimulationOperation `json:"operations"`
+}
+
+// SimulationOperationResult is the outcome of one simulated operation
+type SimulationOperationResult struct {
+	Action  SimulationAction `json:"action"`
+	OrderID int64            `json:"order_id,omitempty"`
+	Status  OrderStatus      `json:"status,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// SimulationResponse represents the trades and final book produced by a simulation
+type SimulationResponse struct {
+	Symbol  string                      `json:"symbol"`
+	Results []SimulationOperationResult `json:"results"`
+	Trades  []Trade                     `json:"trades"`
+	Bids    []OrderBookLevel            `json:"bids"`
+	Asks    []OrderBookLevel            `json:"asks"`
+}
+
+// EventType identifies an engine event published on the event stream
+type EventType string
+
+const (
+	// EventTrade is a committed trade.
+	EventTrade EventType = "trade"
+	// EventFillProvisional is a fill reported before its transaction commits.
+	EventFillProvisional EventType = "fill_provisional"
+	// EventFillConfirmed follows a provisional fill whose transaction committed.
+	EventFillConfirmed EventType = "fill_confirmed"
+	// EventFillRetracted follows a provisional fill whose transaction failed;
+	// the fill never happened.
+	EventFillRetracted EventType = "fill_retracted"
+
+	// The order events below are delivered only to account streams.
+
+	// EventOrderPlaced is a committed placement of one of the account's
+	// orders, carrying the order as it stood after matching.
+	EventOrderPlaced EventType = "order_placed"
+	// EventOrderFilled is a committed trade against one of the account's
+	// orders, carrying the trade and that order.
+	EventOrderFilled EventType = "order_filled"
+	// EventOrderCanceled is a committed cancel of one of the account's orders.
+	EventOrderCanceled EventType = "order_canceled"
+)
+
+// Event is a single message on the engine event stream
+type Event struct {
+	Type   EventType `json:"type"`
+	Symbol string    `json:"symbol"`
+	// MatchID ties provisional fills to their confirmation or retraction.
+	// All fills of one placement share a MatchID.
+	MatchID     uint64 `json:"match_id,omitempty"`
+	Provisional bool   `json:"provisional"`
+	Trade       *Trade `json:"trade,omitempty"`
+	// AccountID and Order are set on account stream events.
+	AccountID string    `json:"account_id,omitempty"`
+	Order     *Order    `json:"order,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrderRefJSON accepts numeric and composite order IDs and writes
+// numeric ones back as numbers.
+func TestOrderRefJSON(t *testing.T) {
+	var req OrderStatusRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"order_ids": [42, "nyc_1-43", "44"]}`), &req))
+	assert.Equal(t, []OrderRef{"42", "nyc_1-43", "44"}, req.OrderIDs)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"order_ids": [1.5]}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"order_ids": [true]}`), &req))
+
+	out, err := json.Marshal(OrderStatusResponse{Orders: []OrderStatusEntry{}, NotFound: []OrderRef{"42", "nyc_1-43"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"orders": [], "not_found": [42, "nyc_1-43"]}`, string(out))
+}
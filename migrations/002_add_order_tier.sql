@@ -0,0 +1,5 @@
+-- migrations/002_add_order_tier.sql
+-- Priority tier for queue jumping: higher tiers rest ahead of lower tiers at
+-- the same price; FIFO still applies within a tier. 0 is the default tier.
+ALTER TABLE orders
+  ADD COLUMN tier TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER status;
@@ -0,0 +1,5 @@
+-- migrations/003_add_orders_symbol_status_updated_index.sql
+-- Serves "most recently canceled orders for a symbol" without scanning every
+-- order of that status.
+ALTER TABLE orders
+  ADD INDEX idx_symbol_status_updated (symbol, status, updated_at);
@@ -0,0 +1,6 @@
+-- migrations/004_add_trades_unique_execution.sql
+-- Makes trade inserts idempotent: a retried write of the same execution hits
+-- this key and is skipped (INSERT ... ON DUPLICATE KEY UPDATE in the engine).
+-- Fails if duplicate trades already exist; remove them before applying.
+ALTER TABLE trades
+  ADD UNIQUE KEY uq_trade_execution (buy_order_id, sell_order_id, executed_at, quantity);
@@ -0,0 +1,6 @@
+-- migrations/005_add_orders_account.sql
+-- Optional owning account of an order. Positions are derived by joining
+-- trades to the account_id of their buy and sell orders.
+ALTER TABLE orders
+  ADD COLUMN account_id VARCHAR(64) NULL AFTER client_order_id,
+  ADD INDEX idx_account_symbol (account_id, symbol);
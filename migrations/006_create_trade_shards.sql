@@ -0,0 +1,7 @@
+-- migrations/006_create_trade_shards.sql
+-- Intentionally empty. With trade_shards set in the engine config, the engine
+-- creates trades_0 .. trades_<N-1> for the configured count at startup
+-- (CREATE TABLE IF NOT EXISTS ... LIKE trades), so its DB user needs the
+-- CREATE privilege. LIKE copies the columns, indexes and uq_trade_execution
+-- but not the foreign keys to orders. Trades already in the trades table
+-- stay there and are still read alongside the symbol's shard.
@@ -0,0 +1,8 @@
+-- migrations/007_add_orders_slippage.sql
+-- Realized slippage of market orders: the best opposing price when the order
+-- was submitted, and how far the average fill price moved from it as a
+-- fraction of that price (positive when worse for the order). Both stay NULL
+-- for limit orders and for market orders that met an empty book.
+ALTER TABLE orders
+  ADD COLUMN reference_price DECIMAL(30,10) NULL AFTER tier,
+  ADD COLUMN slippage DECIMAL(20,8) NULL AFTER reference_price;
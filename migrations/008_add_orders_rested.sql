@@ -0,0 +1,8 @@
+-- migrations/008_add_orders_rested.sql
+-- Whether a limit order ever rested in the book. Placement clears it for
+-- limit orders that filled or were canceled (IOC remainders, self-trade
+-- stops) before resting, so queue history can leave them out without
+-- comparing second-precision timestamps. Rows placed before this migration
+-- keep the default.
+ALTER TABLE orders
+  ADD COLUMN rested BOOLEAN NOT NULL DEFAULT TRUE AFTER tier;
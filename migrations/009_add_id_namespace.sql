@@ -0,0 +1,13 @@
+-- migrations/009_add_id_namespace.sql
+-- The order_id_namespace each order was placed under, and the composite IDs
+-- of both orders of each trade, so composite IDs keep resolving after the
+-- configured namespace changes or data from several instances is merged.
+-- All stay NULL for orders placed without a namespace. With trade_shards,
+-- shard tables created after this migration copy the trade columns; apply
+-- the trades statement to any trades_<n> table that already exists.
+ALTER TABLE orders
+  ADD COLUMN id_namespace VARCHAR(32) NULL AFTER id;
+
+ALTER TABLE trades
+  ADD COLUMN buy_global_order_id VARCHAR(64) NULL AFTER sell_order_id,
+  ADD COLUMN sell_global_order_id VARCHAR(64) NULL AFTER buy_global_order_id;
@@ -0,0 +1,20 @@
+-- migrations/010_create_book_events.sql
+-- The book event log used when event_log is enabled. book_events holds each
+-- change to a resting order, numbered per symbol; order_state is the order's
+-- JSON after the change, or NULL once it left the book. book_snapshots holds
+-- each symbol's resting orders as of last_seq; events up to last_seq are
+-- deleted in the same transaction that writes the snapshot.
+CREATE TABLE IF NOT EXISTS book_events (
+  symbol VARCHAR(64) NOT NULL,
+  seq BIGINT NOT NULL,
+  order_id BIGINT UNSIGNED NOT NULL,
+  order_state LONGTEXT NULL,
+  PRIMARY KEY (symbol, seq)
+);
+
+CREATE TABLE IF NOT EXISTS book_snapshots (
+  symbol VARCHAR(64) NOT NULL PRIMARY KEY,
+  last_seq BIGINT NOT NULL,
+  orders LONGTEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);